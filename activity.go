@@ -0,0 +1,86 @@
+package godbm
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Activity is a backend of the current database as reported by pg_stat_activity.
+type Activity struct {
+	Pid             int
+	User            string
+	ApplicationName string        // see SetApplicationName.
+	ClientAddr      string        // empty for unix socket connections.
+	State           string        // such as "active", "idle" or "idle in transaction".
+	WaitEventType   string        // such as "Lock" or "IO", empty if not waiting.
+	WaitEvent       string        // the specific event waited on, empty if not waiting.
+	BackendStart    time.Time     // when the connection was opened.
+	XactStart       time.Time     // when the current transaction began, zero if none.
+	QueryStart      time.Time     // when the current, or for idle backends the last, query began.
+	Duration        time.Duration // how long the current query has run, zero unless active.
+	Query           string        // the current or last query.
+}
+
+// ActiveQueries returns the client backends connected to the current database other than the
+// one running this query, longest running first. Seeing other users' queries requires
+// superuser or the pg_read_all_stats role, otherwise their Query is hidden by postgres.
+func (store *SqlStore) ActiveQueries(ctx context.Context) ([]Activity, error) {
+	rows, err := store.QueryContext(ctx, `select pid, coalesce(usename, ''), application_name, coalesce(host(client_addr), ''),
+		coalesce(state, ''), coalesce(wait_event_type, ''), coalesce(wait_event, ''), backend_start, xact_start, query_start,
+		case when state = 'active' then extract(epoch from clock_timestamp() - query_start) else 0 end, query
+		from pg_stat_activity
+		where datname = current_database() and backend_type = 'client backend' and pid <> pg_backend_pid()
+		order by query_start nulls last`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var activity []Activity
+	for rows.Next() {
+		var a Activity
+		var xactStart, queryStart sql.NullTime
+		var seconds float64
+		err := rows.Scan(&a.Pid, &a.User, &a.ApplicationName, &a.ClientAddr, &a.State, &a.WaitEventType, &a.WaitEvent,
+			&a.BackendStart, &xactStart, &queryStart, &seconds, &a.Query)
+		if err != nil {
+			return nil, err
+		}
+		a.XactStart, a.QueryStart = xactStart.Time, queryStart.Time
+		a.Duration = time.Duration(seconds * float64(time.Second))
+		activity = append(activity, a)
+	}
+	return activity, rows.Err()
+}
+
+// CancelQuery cancels the query running on the backend pid with pg_cancel_backend, leaving its
+// connection open, and reports whether the signal was sent. Cancelling another user's query
+// requires superuser or the pg_signal_backend role.
+func (store *SqlStore) CancelQuery(ctx context.Context, pid int) (bool, error) {
+	return store.signalBackend(ctx, "select pg_cancel_backend($1)", pid)
+}
+
+// TerminateBackend closes the connection of the backend pid with pg_terminate_backend, rolling
+// back its transaction, and reports whether the signal was sent. The same permissions as
+// CancelQuery apply.
+func (store *SqlStore) TerminateBackend(ctx context.Context, pid int) (bool, error) {
+	return store.signalBackend(ctx, "select pg_terminate_backend($1)", pid)
+}
+
+// signalBackend runs query, one of the pg_*_backend functions, for pid.
+func (store *SqlStore) signalBackend(ctx context.Context, query string, pid int) (sent bool, err error) {
+	rows, err := store.QueryContext(ctx, query, pid)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		err = rows.Scan(&sent)
+	}
+	if err == nil {
+		err = rows.Err()
+	}
+	return sent, err
+}
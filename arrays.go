@@ -0,0 +1,84 @@
+package godbm
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"github.com/lib/pq"
+	"reflect"
+)
+
+// Array wraps a slice, or a pointer to one, so it can be passed as a postgres array parameter
+// or scanned from an array column:
+//
+//	var tags []string
+//	err := row.Scan(godbm.Array(&tags))
+//
+// Slice arguments to the store's query methods are converted automatically, Array is only
+// needed for scanning or when calling database/sql directly.
+func Array(a interface{}) interface {
+	driver.Valuer
+	sql.Scanner
+} {
+	return pq.Array(a)
+}
+
+// convertArgs prepares arguments the drivers can't send themselves. Arguments marked for
+// encryption are encrypted, types registered with RegisterType use their valuer, math/big
+// numbers are sent as exact numeric text, and structs and maps are encoded as json for json
+// and jsonb parameters. With lib/pq slices, other than []byte and types implementing
+// driver.Valuer, are wrapped with Array so that []int64 or []string can be passed for
+// = ANY($1) without importing lib/pq, other drivers such as pgx encode slices natively.
+func (store *SqlStore) convertArgs(args []interface{}) ([]interface{}, error) {
+	pqArrays := store.isPQ()
+
+	var converted []interface{}
+	for i, arg := range args {
+		var value interface{}
+		switch valuer := store.valuerFor(arg); {
+		case isEncryptedArg(arg):
+			v, err := store.encrypt(arg.(encryptedArg))
+			if err != nil {
+				return nil, err
+			}
+			value = v
+		case valuer != nil:
+			v, err := valuer(arg)
+			if err != nil {
+				return nil, err
+			}
+			value = v
+		case pqArrays && isArrayArg(arg):
+			value = pq.Array(arg)
+		case isDecimalArg(arg):
+			value = decimalArg(arg)
+		case isJSONArg(arg):
+			v, err := jsonArg(arg)
+			if err != nil {
+				return nil, err
+			}
+			value = v
+		default:
+			continue
+		}
+		if converted == nil {
+			converted = append([]interface{}(nil), args...)
+		}
+		converted[i] = value
+	}
+	if converted == nil {
+		return args, nil
+	}
+	return converted, nil
+}
+
+// isArrayArg reports whether arg is a slice which should be sent as an array.
+func isArrayArg(arg interface{}) bool {
+	if arg == nil {
+		return false
+	}
+	if _, ok := arg.(driver.Valuer); ok {
+		return false
+	}
+	t := reflect.TypeOf(arg)
+	return t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8
+}
@@ -0,0 +1,48 @@
+package godbm
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestConvertArgs(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	args := []interface{}{[]int64{1, 2}, []string{"a"}, []byte("raw"), json.RawMessage(`{}`), 3, nil, []int{4}}
+	converted, err := dbm.convertArgs(args)
+	if err != nil {
+		t.Fatalf("error converting args: %v\n", err)
+	}
+
+	if _, ok := converted[0].(interface{ Scan(interface{}) error }); !ok {
+		t.Fatalf("expected []int64 to be wrapped, got %T\n", converted[0])
+	}
+	if _, ok := converted[6].(interface{ Scan(interface{}) error }); !ok {
+		t.Fatalf("expected []int to be wrapped, got %T\n", converted[6])
+	}
+	if _, ok := converted[2].([]byte); !ok {
+		t.Fatalf("expected []byte to be left alone, got %T\n", converted[2])
+	}
+	if _, ok := converted[3].(json.RawMessage); !ok {
+		t.Fatalf("expected json.RawMessage to be left alone, got %T\n", converted[3])
+	}
+	if _, ok := args[0].([]int64); !ok {
+		t.Fatalf("expected the caller's args to be left alone\n")
+	}
+
+	plain := []interface{}{1, "a"}
+	if converted, _ := dbm.convertArgs(plain); &converted[0] != &plain[0] {
+		t.Fatalf("expected args without slices to be returned as is\n")
+	}
+
+	dbm.SetDriver(&dsnDriver{})
+	if converted, _ := dbm.convertArgs(args); &converted[0] != &args[0] {
+		t.Fatalf("expected args to be left alone for other drivers\n")
+	}
+}
+
+func TestArrayScan(t *testing.T) {
+	var tags []string
+	if err := Array(&tags).Scan([]byte(`{a,"b c"}`)); err != nil || len(tags) != 2 || tags[1] != "b c" {
+		t.Fatalf("unexpected scan: %v %v\n", tags, err)
+	}
+}
@@ -0,0 +1,107 @@
+package godbm
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+// AuditRecord describes a successful ExecPrepared call.
+type AuditRecord struct {
+	Key          string        // the statement key.
+	Actor        string        // who made the call, see WithActor.
+	Time         time.Time     // when the call finished.
+	RowsAffected int64         // rows affected, -1 if the driver doesn't report it.
+	Args         []interface{} // the arguments after redaction, see SetRedactor.
+}
+
+// AuditSink records audit records, such as to a table or an external log.
+type AuditSink interface {
+	Audit(ctx context.Context, rec AuditRecord) error
+}
+
+// AuditOptions configures auditing of ExecPrepared calls.
+type AuditOptions struct {
+	Sink          AuditSink // where records are written.
+	InTransaction bool      // write the record in the same transaction as the statement, failing the call if it can't be written.
+}
+
+// SetAudit enables recording every successful ExecPrepared call to opts.Sink. With
+// InTransaction calls made outside of a transaction run in their own so the statement and its
+// record commit together, and the context passed to the sink carries the transaction. Without
+// it failures to write records are logged and the call succeeds. Passing nil disables auditing.
+// Should be called before the store is in use.
+func (store *SqlStore) SetAudit(opts *AuditOptions) {
+	store.auditOpts = opts
+}
+
+type actorKey struct{}
+
+// WithActor returns a context identifying the user or service making calls, which is recorded
+// by auditing.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set with WithActor, if any.
+func ActorFromContext(ctx context.Context) (string, bool) {
+	actor, ok := ctx.Value(actorKey{}).(string)
+	return actor, ok
+}
+
+// needsAuditTx reports whether an exec with ctx must run in its own transaction to be audited.
+func (store *SqlStore) needsAuditTx(ctx context.Context) bool {
+	if store.auditOpts == nil || !store.auditOpts.InTransaction {
+		return false
+	}
+	_, inTx := TxFromContext(ctx)
+	return !inTx
+}
+
+// audit records a successful exec of st.
+func (store *SqlStore) audit(ctx context.Context, st *statement, args []interface{}, result sql.Result) error {
+	opts := store.auditOpts
+	if opts == nil || opts.Sink == nil || st.key == "" {
+		return nil
+	}
+
+	rec := AuditRecord{Key: st.key, Time: time.Now(), RowsAffected: -1, Args: store.redact(st, args)}
+	rec.Actor, _ = ActorFromContext(ctx)
+	if n, err := result.RowsAffected(); err == nil {
+		rec.RowsAffected = n
+	}
+
+	err := opts.Sink.Audit(ctx, rec)
+	if err != nil && !opts.InTransaction {
+		store.logEvent(ctx, "godbm: audit", err, slog.String("key", st.key))
+		return nil
+	}
+	return err
+}
+
+// TableAuditSink writes audit records to a table with the columns:
+//
+//	create table audit_log (stmt_key text, actor text, at timestamptz, rows_affected bigint)
+//
+// Records are written in the transaction carried by the context, if any.
+type TableAuditSink struct {
+	store  *SqlStore
+	insert string
+}
+
+// NewTableAuditSink creates a sink writing to table, which may be schema qualified.
+func NewTableAuditSink(store *SqlStore, table string) *TableAuditSink {
+	insert := "insert into " + QuoteQualified(table) + " (stmt_key, actor, at, rows_affected) values ($1, $2, $3, $4)"
+	return &TableAuditSink{store: store, insert: insert}
+}
+
+// Audit implements AuditSink.
+func (s *TableAuditSink) Audit(ctx context.Context, rec AuditRecord) error {
+	var c conn = s.store.db
+	if tx, ok := TxFromContext(ctx); ok {
+		c = tx.Tx
+	}
+	_, err := c.ExecContext(ctx, s.insert, rec.Key, rec.Actor, rec.Time, rec.RowsAffected)
+	return err
+}
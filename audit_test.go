@@ -0,0 +1,50 @@
+package godbm
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+// sinkFunc adapts a function to an AuditSink.
+type sinkFunc func(ctx context.Context, rec AuditRecord) error
+
+func (f sinkFunc) Audit(ctx context.Context, rec AuditRecord) error {
+	return f(ctx, rec)
+}
+
+func TestAudit(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	st := &statement{key: "set_password", query: "update users set password = $1 where id = $2"}
+	Sensitive(1)(&st.opts)
+
+	var recs []AuditRecord
+	failure := errors.New("sink down")
+	var sinkErr error
+	dbm.SetAudit(&AuditOptions{Sink: sinkFunc(func(ctx context.Context, rec AuditRecord) error {
+		recs = append(recs, rec)
+		return sinkErr
+	})})
+
+	ctx := WithActor(context.Background(), "alice")
+	if err := dbm.audit(ctx, st, []interface{}{"hunter2", 7}, driver.RowsAffected(1)); err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 1 || recs[0].Actor != "alice" || recs[0].RowsAffected != 1 || recs[0].Args[0] != RedactedValue {
+		t.Fatalf("unexpected audit record: %+v\n", recs)
+	}
+
+	sinkErr = failure
+	if err := dbm.audit(ctx, st, nil, driver.RowsAffected(1)); err != nil {
+		t.Fatalf("expected sink errors to be logged outside of transaction mode, got %v\n", err)
+	}
+
+	dbm.SetAudit(&AuditOptions{Sink: dbm.auditOpts.Sink, InTransaction: true})
+	if err := dbm.audit(ctx, st, nil, driver.RowsAffected(1)); err != failure {
+		t.Fatalf("expected sink errors to fail the call in transaction mode, got %v\n", err)
+	}
+	if !dbm.needsAuditTx(ctx) {
+		t.Fatalf("expected calls outside a transaction to need one\n")
+	}
+}
@@ -0,0 +1,50 @@
+package godbm
+
+// StatementConflictError is returned by GetOrPrepare when the key is already registered with
+// different sql.
+type StatementConflictError struct {
+	StmtKey string // the statement key
+}
+
+func (e *StatementConflictError) Error() string {
+	return "godbm: error " + e.StmtKey + " is already registered with different sql"
+}
+
+// GetOrPrepare registers query under key if it isn't registered yet. Concurrent calls for the
+// same key share a single prepare, so statements discovered at runtime can be registered on
+// first use without racing. Returns a *StatementConflictError if key is registered with
+// different sql, the options are only applied when the statement is first registered.
+func (store *SqlStore) GetOrPrepare(key, query string, opts ...StmtOption) error {
+	if !store.Connected {
+		return &ConnectionError{}
+	}
+
+	if st, err := store.lookup(key); err == nil {
+		return st.sameQuery(query)
+	}
+
+	_, err, _ := store.preparing.do(key, func() (interface{}, error) {
+		// the statement may have been registered between our lookup and now.
+		if _, err := store.lookup(key); err == nil {
+			return nil, nil
+		}
+		return nil, store.PrepareAddWithOptions(key, query, opts...)
+	})
+	if err != nil {
+		return err
+	}
+
+	st, err := store.lookup(key)
+	if err != nil {
+		return err
+	}
+	return st.sameQuery(query)
+}
+
+// sameQuery returns a StatementConflictError if st wasn't registered with query.
+func (st *statement) sameQuery(query string) error {
+	if st.query != query {
+		return &StatementConflictError{StmtKey: st.key}
+	}
+	return nil
+}
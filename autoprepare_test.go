@@ -0,0 +1,83 @@
+package godbm
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// prepareDriver counts the statements prepared on its connections, which can't run them.
+type prepareDriver struct {
+	prepares atomic.Int32
+}
+
+func (d *prepareDriver) Open(dsn string) (driver.Conn, error) { return prepareConn{d}, nil }
+
+type prepareConn struct{ d *prepareDriver }
+
+func (c prepareConn) Prepare(query string) (driver.Stmt, error) {
+	c.d.prepares.Add(1)
+	time.Sleep(10 * time.Millisecond)
+	return prepareStmt{}, nil
+}
+func (c prepareConn) Close() error              { return nil }
+func (c prepareConn) Begin() (driver.Tx, error) { return nil, driver.ErrSkip }
+
+type prepareStmt struct{}
+
+func (prepareStmt) Close() error  { return nil }
+func (prepareStmt) NumInput() int { return -1 }
+func (prepareStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (prepareStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func TestGetOrPrepare(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	drv := &prepareDriver{}
+	sql.Register("godbm-prepare", drv)
+	db, err := sql.Open("godbm-prepare", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	dbm.db = db
+	dbm.Connected = true
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- dbm.GetOrPrepare("get", "select val1 from test where val3 = $1")
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("error preparing statement: %v\n", err)
+		}
+	}
+	if n := drv.prepares.Load(); n != 1 {
+		t.Fatalf("expected concurrent callers to share a single prepare got %d\n", n)
+	}
+
+	if err := dbm.GetOrPrepare("get", "select val1 from test where val3 = $1"); err != nil {
+		t.Fatalf("expected the same sql to be accepted got %v\n", err)
+	}
+	var conflict *StatementConflictError
+	if err := dbm.GetOrPrepare("get", "select val2 from test where val3 = $1"); !errors.As(err, &conflict) || conflict.StmtKey != "get" {
+		t.Fatalf("expected a StatementConflictError got %v\n", err)
+	}
+	if n := drv.prepares.Load(); n != 1 {
+		t.Fatalf("expected no further prepares got %d\n", n)
+	}
+}
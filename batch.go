@@ -0,0 +1,92 @@
+package godbm
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+)
+
+// BatchError is returned by Batch.Exec when one of the queued statements fails. Nothing in the
+// batch is committed.
+type BatchError struct {
+	Index int    // position of the failed statement in the batch.
+	Key   string // key of the failed statement.
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return "godbm: error batch statement " + strconv.Itoa(e.Index) + " (" + e.Key + "): " + e.Err.Error()
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// Batch queues executions of registered statements to run together, see SqlStore.Batch. A
+// Batch is not safe for concurrent use.
+type Batch struct {
+	store *SqlStore
+	items []batchItem
+}
+
+// batchItem is a queued execution.
+type batchItem struct {
+	key  string
+	args []interface{}
+}
+
+// Batch returns an empty batch. Queued statements are executed in order inside a single
+// transaction, or a savepoint if the context passed to Exec carries one, so they commit
+// together and pay for one commit rather than one each. database/sql doesn't expose protocol
+// level pipelining, so each statement still waits for the previous one to return.
+//
+//	results, err := store.Batch().
+//		Queue("insert_order", orderID, userID).
+//		Queue("insert_line", orderID, sku, qty).
+//		Exec(ctx)
+func (store *SqlStore) Batch() *Batch {
+	return &Batch{store: store}
+}
+
+// Queue adds an execution of the statement registered under key with args to the batch.
+func (b *Batch) Queue(key string, args ...interface{}) *Batch {
+	b.items = append(b.items, batchItem{key: key, args: args})
+	return b
+}
+
+// Len returns the number of queued executions.
+func (b *Batch) Len() int {
+	return len(b.items)
+}
+
+// Exec executes the queued statements and returns their results in order. If any fails the
+// transaction is rolled back and a *BatchError is returned. Unknown keys are reported before
+// anything is executed. The batch is emptied once executed.
+func (b *Batch) Exec(ctx context.Context) ([]sql.Result, error) {
+	items := b.items
+	b.items = nil
+	if len(items) == 0 {
+		return nil, nil
+	}
+	for i, item := range items {
+		if _, err := b.store.lookup(item.key); err != nil {
+			return nil, &BatchError{Index: i, Key: item.key, Err: err}
+		}
+	}
+
+	results := make([]sql.Result, len(items))
+	err := b.store.WithTransaction(ctx, func(ctx context.Context, tx *Tx) error {
+		for i, item := range items {
+			result, err := b.store.ExecPreparedContext(ctx, item.key, item.args...)
+			if err != nil {
+				return &BatchError{Index: i, Key: item.key, Err: err}
+			}
+			results[i] = result
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,30 @@
+package godbm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestBatchUnknownKey(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.queries = map[string]*statement{"insert": {key: "insert"}}
+
+	b := dbm.Batch().Queue("insert", 1).Queue("missing", 2)
+	if b.Len() != 2 {
+		t.Fatalf("expected 2 queued statements got %d\n", b.Len())
+	}
+
+	_, err := b.Exec(context.Background())
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || batchErr.Index != 1 || batchErr.Key != "missing" {
+		t.Fatalf("expected a BatchError for the unknown key got %v\n", err)
+	}
+	var unknown *UnknownStmtError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected the BatchError to wrap UnknownStmtError\n")
+	}
+	if b.Len() != 0 {
+		t.Fatalf("expected the batch to be emptied\n")
+	}
+}
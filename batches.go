@@ -0,0 +1,72 @@
+package godbm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// BatchProgress reports the progress of DeleteInBatches after each batch.
+type BatchProgress struct {
+	Batches      int   // batches executed so far.
+	RowsAffected int64 // rows affected by the last batch.
+	Total        int64 // rows affected by every batch so far.
+}
+
+// DeleteInBatches repeatedly executes the delete or update registered under key until it
+// affects no rows, sleeping for sleepBetween between batches so replication and vacuum keep
+// up. The statement must limit itself to the batch size, which is passed as the argument after
+// args, for example:
+//
+//	delete from events where id in (select id from events where created_at < $1 limit $2)
+//
+// Each batch commits on its own, so locks are short lived and a cancelled run keeps the work
+// done. progress, which may be nil, is called after every batch. Returns the total number of
+// rows affected.
+func (store *SqlStore) DeleteInBatches(ctx context.Context, key string, batchSize int, sleepBetween time.Duration, progress func(p BatchProgress), args ...interface{}) (int64, error) {
+	if batchSize <= 0 {
+		return 0, errors.New("godbm: error batch size must be positive")
+	}
+	if _, inTx := TxFromContext(ctx); inTx {
+		return 0, errors.New("godbm: error DeleteInBatches can't run inside a transaction")
+	}
+	data := append(args[:len(args):len(args)], batchSize)
+	return store.inBatches(ctx, sleepBetween, progress, func() (sql.Result, error) {
+		return store.ExecPreparedContext(ctx, key, data...)
+	})
+}
+
+// inBatches calls exec until it affects no rows, sleeping between calls and reporting progress.
+// In a dry run exec is only called once.
+func (store *SqlStore) inBatches(ctx context.Context, sleepBetween time.Duration, progress func(p BatchProgress), exec func() (sql.Result, error)) (int64, error) {
+	var p BatchProgress
+	for {
+		result, err := exec()
+		if err != nil {
+			return p.Total, err
+		}
+		if p.RowsAffected, err = result.RowsAffected(); err != nil {
+			return p.Total, err
+		}
+		p.Batches++
+		p.Total += p.RowsAffected
+		if progress != nil {
+			progress(p)
+		}
+		// a batch run as a dry run is rolled back, so the next would affect the same rows.
+		if p.RowsAffected == 0 || store.dryRun != DryRunOff {
+			return p.Total, nil
+		}
+
+		if sleepBetween > 0 {
+			timer := time.NewTimer(sleepBetween)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return p.Total, ctx.Err()
+			}
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package godbm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDeleteInBatchesArgs(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	if _, err := dbm.DeleteInBatches(context.Background(), "purge", 0, 0, nil); err == nil {
+		t.Fatalf("expected an error for a zero batch size\n")
+	}
+	if _, err := dbm.DeleteInBatches(withTx(context.Background(), &Tx{}), "purge", 10, 0, nil); err == nil {
+		t.Fatalf("expected an error inside a transaction\n")
+	}
+}
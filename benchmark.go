@@ -0,0 +1,140 @@
+package godbm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// BenchmarkResult holds the latencies and throughput measured by BenchmarkStatement.
+type BenchmarkResult struct {
+	Key        string
+	Calls      int
+	Elapsed    time.Duration
+	Throughput float64 // calls per second.
+	Min        time.Duration
+	Mean       time.Duration
+	P50        time.Duration
+	P90        time.Duration
+	P95        time.Duration
+	P99        time.Duration
+	Max        time.Duration
+}
+
+func (r BenchmarkResult) String() string {
+	return fmt.Sprintf("%s: %d calls in %v (%.1f/s) min %v mean %v p50 %v p90 %v p95 %v p99 %v max %v",
+		r.Key, r.Calls, r.Elapsed.Round(time.Millisecond), r.Throughput, r.Min, r.Mean, r.P50, r.P90, r.P95, r.P99, r.Max)
+}
+
+// BenchmarkStatement calls the statement registered under key back to back for duration and
+// reports its latencies and throughput as seen by a single caller, including reading every
+// row. args is called with the call number to provide the arguments of each call and may be
+// nil if the statement takes none. For statements registered with PrepareAddIn the first
+// argument is the slice of list values, as passed to QueryPreparedIn. The statement is really
+// executed, so benchmark writes against a disposable database. Stops at the first call which
+// fails.
+func (store *SqlStore) BenchmarkStatement(ctx context.Context, key string, args func(i int) []interface{}, duration time.Duration) (*BenchmarkResult, error) {
+	if !store.HasStatement(key) {
+		return nil, &UnknownStmtError{StmtKey: key}
+	}
+
+	var latencies []time.Duration
+	start := time.Now()
+	for i := 0; time.Since(start) < duration; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var data []interface{}
+		if args != nil {
+			data = args(i)
+		}
+
+		called := time.Now()
+		if err := store.benchmarkCall(ctx, key, data); err != nil {
+			return nil, err
+		}
+		latencies = append(latencies, time.Since(called))
+	}
+	return benchmarkResult(key, latencies, time.Since(start)), nil
+}
+
+// BenchmarkStatements runs BenchmarkStatement for duration on every registered statement in
+// key order, calling each with its sample args. Stops at the first statement which fails.
+func (store *SqlStore) BenchmarkStatements(ctx context.Context, samples map[string][]interface{}, duration time.Duration) ([]BenchmarkResult, error) {
+	store.RLock()
+	keys := make([]string, 0, len(store.queries))
+	for key := range store.queries {
+		keys = append(keys, key)
+	}
+	store.RUnlock()
+	sort.Strings(keys)
+
+	results := make([]BenchmarkResult, 0, len(keys))
+	for _, key := range keys {
+		sample := samples[key]
+		result, err := store.BenchmarkStatement(ctx, key, func(int) []interface{} { return sample }, duration)
+		if err != nil {
+			return results, &StatementError{Key: key, Err: err}
+		}
+		results = append(results, *result)
+	}
+	return results, nil
+}
+
+// benchmarkCall queries the statement and reads every row.
+func (store *SqlStore) benchmarkCall(ctx context.Context, key string, data []interface{}) error {
+	st, err := store.lookup(key)
+	if err != nil {
+		return err
+	}
+	var rows *sql.Rows
+	if st.in != nil {
+		if len(data) == 0 {
+			return ErrNeedsInList
+		}
+		rows, err = store.QueryPreparedIn(ctx, key, data[0], data[1:]...)
+	} else {
+		rows, err = store.QueryPreparedContext(ctx, key, data...)
+	}
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+	}
+	return rows.Err()
+}
+
+// benchmarkResult summarizes the latencies of the calls made in elapsed.
+func benchmarkResult(key string, latencies []time.Duration, elapsed time.Duration) *BenchmarkResult {
+	result := &BenchmarkResult{Key: key, Calls: len(latencies), Elapsed: elapsed}
+	if len(latencies) == 0 {
+		return result
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+	result.Throughput = float64(len(latencies)) / elapsed.Seconds()
+	result.Min = latencies[0]
+	result.Mean = total / time.Duration(len(latencies))
+	result.P50 = percentile(latencies, 50)
+	result.P90 = percentile(latencies, 90)
+	result.P95 = percentile(latencies, 95)
+	result.P99 = percentile(latencies, 99)
+	result.Max = latencies[len(latencies)-1]
+	return result
+}
+
+// percentile returns the nearest rank percentile p of the sorted latencies.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
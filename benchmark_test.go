@@ -0,0 +1,23 @@
+package godbm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBenchmarkResult(t *testing.T) {
+	var latencies []time.Duration
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	result := benchmarkResult("get", latencies, 2*time.Second)
+	if result.Calls != 100 || result.Throughput != 50 || result.Min != time.Millisecond || result.Max != 100*time.Millisecond {
+		t.Fatalf("unexpected result: %v\n", result)
+	}
+	if result.P50 != 50*time.Millisecond || result.P99 != 99*time.Millisecond || result.Mean != 50500*time.Microsecond {
+		t.Fatalf("unexpected percentiles: %v\n", result)
+	}
+	if empty := benchmarkResult("get", nil, time.Second); empty.Calls != 0 || empty.P99 != 0 {
+		t.Fatalf("unexpected empty result: %v\n", empty)
+	}
+}
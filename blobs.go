@@ -0,0 +1,254 @@
+package godbm
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrBlobNotFound is returned by Blobs.Open when no complete blob is stored under an id.
+var ErrBlobNotFound = errors.New("godbm: error blob not found")
+
+// ErrBlobChanged is returned by BlobReader.Read when a chunk is missing because the blob was
+// replaced or deleted while it was being read.
+var ErrBlobChanged = errors.New("godbm: error blob was replaced or deleted while being read")
+
+// defaultBlobChunk is the chunk size used when Blobs is given none.
+const defaultBlobChunk = 1 << 20
+
+// Blobs stores files as bytea chunks, one row each in <table>_chunks, described by a manifest
+// row in table. Blobs are written and read a chunk per statement so memory stays flat
+// whatever their size, see Create and Open. For objects too large for that, or needing random
+// access writes, see CreateLargeObject.
+type Blobs struct {
+	store     *SqlStore
+	table     string
+	chunks    string
+	chunkSize int
+}
+
+// Blobs returns the blob store kept in table, which may be schema qualified, using chunks of
+// chunkSize bytes, or a megabyte if chunkSize is zero. See CreateTables.
+func (store *SqlStore) Blobs(table string, chunkSize int) *Blobs {
+	if chunkSize <= 0 {
+		chunkSize = defaultBlobChunk
+	}
+	return &Blobs{store: store, table: table, chunks: table + "_chunks", chunkSize: chunkSize}
+}
+
+// CreateTables creates the manifest and chunk tables if they don't exist.
+func (b *Blobs) CreateTables(ctx context.Context) error {
+	manifest, err := Format("create table if not exists %I (id text primary key, size bigint not null default 0, "+
+		"chunks int not null default 0, complete bool not null default false, created timestamptz not null default now())", b.table)
+	if err != nil {
+		return err
+	}
+	chunks, err := Format("create table if not exists %I (blob_id text not null references %I (id) on delete cascade, "+
+		"seq int not null, data bytea not null, primary key (blob_id, seq))", b.chunks, b.table)
+	if err != nil {
+		return err
+	}
+	for _, query := range []string{manifest, chunks} {
+		if _, err := b.store.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create starts writing the blob id, replacing any blob already stored under it. Each chunk is
+// inserted as soon as it fills under a pending manifest row, and the blob only replaces the one
+// stored under id when the writer is closed, until then Open returns the old blob. A writer
+// which is never closed leaves its pending row and chunks behind. Writing the same id from two
+// writers at once isn't supported.
+func (b *Blobs) Create(ctx context.Context, id string) (*BlobWriter, error) {
+	pending := make([]byte, 16)
+	if _, err := rand.Read(pending); err != nil {
+		return nil, err
+	}
+	w := &BlobWriter{blobs: b, ctx: ctx, id: id, pending: "godbm-pending-" + hex.EncodeToString(pending),
+		buf: make([]byte, 0, b.chunkSize)}
+
+	query, err := Format("insert into %I (id) values ($1)", b.table)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := b.store.ExecContext(ctx, query, w.pending); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Open opens the complete blob id for reading.
+func (b *Blobs) Open(ctx context.Context, id string) (*BlobReader, error) {
+	query, err := Format("select size, chunks, created from %I where id = $1 and complete", b.table)
+	if err != nil {
+		return nil, err
+	}
+	r := &BlobReader{blobs: b, ctx: ctx, id: id}
+	err = b.store.scanRow(ctx, query, []interface{}{id}, &r.Size, &r.chunks, &r.created)
+	if err == sql.ErrNoRows {
+		return nil, ErrBlobNotFound
+	}
+	return r, err
+}
+
+// Delete removes the blob id along with its chunks, it isn't an error if there is none.
+func (b *Blobs) Delete(ctx context.Context, id string) error {
+	query, err := Format("delete from %I where id = $1", b.table)
+	if err != nil {
+		return err
+	}
+	_, err = b.store.ExecContext(ctx, query, id)
+	return err
+}
+
+// BlobWriter writes a blob a chunk at a time, see Blobs.Create.
+type BlobWriter struct {
+	blobs   *Blobs
+	ctx     context.Context
+	id      string
+	pending string // id of the manifest row the chunks are written under until Close.
+	buf     []byte
+	seq     int
+	size    int64
+	closed  bool
+}
+
+// Write implements io.Writer, inserting each chunk as it fills.
+func (w *BlobWriter) Write(p []byte) (n int, err error) {
+	if w.closed {
+		return 0, errors.New("godbm: error write to closed blob")
+	}
+	for len(p) > 0 {
+		free := w.blobs.chunkSize - len(w.buf)
+		if free > len(p) {
+			free = len(p)
+		}
+		w.buf = append(w.buf, p[:free]...)
+		n += free
+		p = p[free:]
+		if len(w.buf) == w.blobs.chunkSize {
+			if err := w.flush(); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Close implements io.Closer, writing the last chunk and then, in one transaction, replacing
+// any blob stored under the id with the one written.
+func (w *BlobWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if len(w.buf) > 0 {
+		if err := w.flush(); err != nil {
+			return err
+		}
+	}
+
+	// the chunks are moved rather than the manifest renamed as they reference it.
+	type step struct {
+		format string
+		table  string
+		args   []interface{}
+	}
+	steps := []step{
+		{"delete from %I where id = $1", w.blobs.table, []interface{}{w.id}},
+		{"insert into %I (id, size, chunks, complete) values ($1, $2, $3, true)", w.blobs.table, []interface{}{w.id, w.size, w.seq}},
+		{"update %I set blob_id = $1 where blob_id = $2", w.blobs.chunks, []interface{}{w.id, w.pending}},
+		{"delete from %I where id = $1", w.blobs.table, []interface{}{w.pending}},
+	}
+	return w.blobs.store.WithTransaction(w.ctx, func(ctx context.Context, tx *Tx) error {
+		for _, step := range steps {
+			query, err := Format(step.format, step.table)
+			if err != nil {
+				return err
+			}
+			if _, err := w.blobs.store.ExecContext(ctx, query, step.args...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// flush inserts the buffered chunk.
+func (w *BlobWriter) flush() error {
+	query, err := Format("insert into %I (blob_id, seq, data) values ($1, $2, $3)", w.blobs.chunks)
+	if err != nil {
+		return err
+	}
+	if _, err := w.blobs.store.ExecContext(w.ctx, query, w.pending, w.seq, w.buf); err != nil {
+		return err
+	}
+	w.seq++
+	w.size += int64(len(w.buf))
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// BlobReader reads a blob a chunk at a time, see Blobs.Open.
+type BlobReader struct {
+	Size    int64 // size of the blob in bytes.
+	blobs   *Blobs
+	ctx     context.Context
+	id      string
+	created time.Time // when the blob was stored, to tell it from one replacing it.
+	chunks  int
+	seq     int
+	buf     []byte
+}
+
+// Read implements io.Reader, fetching the next chunk once the last is used up.
+func (r *BlobReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.seq >= r.chunks {
+			return 0, io.EOF
+		}
+		query, err := Format("select c.data from %I c join %I m on m.id = c.blob_id where c.blob_id = $1 and c.seq = $2 and m.created = $3",
+			r.blobs.chunks, r.blobs.table)
+		if err != nil {
+			return 0, err
+		}
+		err = r.blobs.store.scanRow(r.ctx, query, []interface{}{r.id, r.seq, r.created}, &r.buf)
+		if err == sql.ErrNoRows {
+			return 0, ErrBlobChanged
+		}
+		if err != nil {
+			return 0, err
+		}
+		r.seq++
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// scanRow runs the ad-hoc query and scans its first row into dest, returning sql.ErrNoRows if
+// there are none.
+func (store *SqlStore) scanRow(ctx context.Context, query string, args []interface{}, dest ...interface{}) error {
+	rows, err := store.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return err
+	}
+	return rows.Close()
+}
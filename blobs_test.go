@@ -0,0 +1,16 @@
+package godbm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBlobWriterChunks(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	w := &BlobWriter{blobs: dbm.Blobs("files", 4), ctx: context.Background(), id: "a", buf: make([]byte, 0, 4)}
+	// the first full chunk fails to insert without a connection, leaving it buffered.
+	n, err := w.Write([]byte("abcdef"))
+	if err == nil || n != 4 || string(w.buf) != "abcd" || w.seq != 0 {
+		t.Fatalf("unexpected write: %d %q %v\n", n, w.buf, err)
+	}
+}
@@ -0,0 +1,181 @@
+package godbm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of calling the database while the circuit breaker is open.
+var ErrCircuitOpen = errors.New("godbm: error circuit breaker is open")
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+const (
+	BreakerClosed   BreakerState = iota // calls flow to the database normally.
+	BreakerOpen                         // calls fail fast with ErrCircuitOpen.
+	BreakerHalfOpen                     // a limited number of probe calls are let through.
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// MarshalText implements encoding.TextMarshaler so the state reads well in JSON stats.
+func (s BreakerState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// CircuitBreaker stops calls from reaching the database after FailureThreshold consecutive
+// connection failures. After OpenDuration it lets HalfOpenProbes calls through, closing again
+// once they all succeed or re-opening on the first failure. Configure the exported fields and
+// pass it to SetCircuitBreaker, a CircuitBreaker must not be copied after first use.
+type CircuitBreaker struct {
+	FailureThreshold int                  // consecutive failures before opening, defaults to 5.
+	OpenDuration     time.Duration        // how long to stay open before probing, defaults to 10 seconds.
+	HalfOpenProbes   int                  // probes allowed, and successes required, while half-open. defaults to 1.
+	IsFailure        func(err error) bool // classifies errors as failures, defaults to IsConnectionError.
+
+	mu        sync.Mutex
+	state     BreakerState
+	failures  int       // consecutive failures while closed
+	openedAt  time.Time // when we last transitioned to open
+	probes    int       // in-flight probes while half-open
+	successes int       // successful probes while half-open
+}
+
+// SetCircuitBreaker sets the circuit breaker guarding calls to the database. Passing nil
+// disables it. This should be called before the store is in use.
+func (store *SqlStore) SetCircuitBreaker(cb *CircuitBreaker) {
+	store.breaker = cb
+}
+
+// BreakerState returns the current state of the store's circuit breaker, or BreakerClosed
+// if no breaker is set.
+func (store *SqlStore) BreakerState() BreakerState {
+	if store.breaker == nil {
+		return BreakerClosed
+	}
+	return store.breaker.State()
+}
+
+// State returns the current state of the breaker.
+func (cb *CircuitBreaker) State() BreakerState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state == BreakerOpen && time.Since(cb.openedAt) >= cb.openDuration() {
+		return BreakerHalfOpen
+	}
+	return cb.state
+}
+
+// do calls fn if the breaker allows it and records the outcome.
+func (cb *CircuitBreaker) do(ctx context.Context, fn func(ctx context.Context) error) error {
+	probe, err := cb.allow()
+	if err != nil {
+		return err
+	}
+	err = fn(ctx)
+	cb.record(probe, err)
+	return err
+}
+
+// allow returns ErrCircuitOpen if the call should not proceed, and whether the call is a probe.
+func (cb *CircuitBreaker) allow() (probe bool, err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case BreakerOpen:
+		if time.Since(cb.openedAt) < cb.openDuration() {
+			return false, ErrCircuitOpen
+		}
+		cb.state = BreakerHalfOpen
+		cb.probes = 0
+		cb.successes = 0
+		fallthrough
+	case BreakerHalfOpen:
+		if cb.probes >= cb.halfOpenProbes() {
+			return false, ErrCircuitOpen
+		}
+		cb.probes++
+		return true, nil
+	}
+	return false, nil
+}
+
+// record updates the breaker state with the result of a call.
+func (cb *CircuitBreaker) record(probe bool, err error) {
+	isFailure := cb.IsFailure
+	if isFailure == nil {
+		isFailure = IsConnectionError
+	}
+	failed := err != nil && isFailure(err)
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if probe {
+		if cb.state != BreakerHalfOpen {
+			return
+		}
+		cb.probes--
+		if failed {
+			cb.trip()
+			return
+		}
+		cb.successes++
+		if cb.successes >= cb.halfOpenProbes() {
+			cb.state = BreakerClosed
+			cb.failures = 0
+		}
+		return
+	}
+
+	if cb.state != BreakerClosed {
+		return
+	}
+	if !failed {
+		cb.failures = 0
+		return
+	}
+	cb.failures++
+	threshold := cb.FailureThreshold
+	if threshold <= 0 {
+		threshold = 5
+	}
+	if cb.failures >= threshold {
+		cb.trip()
+	}
+}
+
+// trip opens the breaker, must be called with mu held.
+func (cb *CircuitBreaker) trip() {
+	cb.state = BreakerOpen
+	cb.openedAt = time.Now()
+	cb.failures = 0
+}
+
+func (cb *CircuitBreaker) openDuration() time.Duration {
+	if cb.OpenDuration <= 0 {
+		return 10 * time.Second
+	}
+	return cb.OpenDuration
+}
+
+func (cb *CircuitBreaker) halfOpenProbes() int {
+	if cb.HalfOpenProbes <= 0 {
+		return 1
+	}
+	return cb.HalfOpenProbes
+}
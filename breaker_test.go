@@ -0,0 +1,36 @@
+package godbm
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	cb := &CircuitBreaker{FailureThreshold: 2, OpenDuration: 20 * time.Millisecond}
+	fail := func(ctx context.Context) error { return driver.ErrBadConn }
+	succeed := func(ctx context.Context) error { return nil }
+
+	cb.do(context.Background(), fail)
+	cb.do(context.Background(), fail)
+	if cb.State() != BreakerOpen {
+		t.Fatalf("expected breaker to be open, got %s\n", cb.State())
+	}
+
+	if err := cb.do(context.Background(), succeed); err != ErrCircuitOpen {
+		t.Fatalf("expected ErrCircuitOpen, got %v\n", err)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if cb.State() != BreakerHalfOpen {
+		t.Fatalf("expected breaker to be half-open, got %s\n", cb.State())
+	}
+
+	if err := cb.do(context.Background(), succeed); err != nil {
+		t.Fatalf("expected probe to succeed, got %v\n", err)
+	}
+	if cb.State() != BreakerClosed {
+		t.Fatalf("expected breaker to be closed, got %s\n", cb.State())
+	}
+}
@@ -0,0 +1,311 @@
+package godbm
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SelectBuilder builds a parameterized select statement, for filter UIs and other queries whose
+// shape depends on user input. It isn't an ORM, the result is plain sql and arguments for
+// Query, QueryPrepared or PrepareStatement. Table and column names are quoted, conditions
+// passed to Where are written by the caller and use ? for parameters, ?? for a literal ?.
+//
+//	query, args := godbm.Select("orders").Columns("id", "total").WhereEq("status", status).
+//		Where("created_at > ?", since).OrderBy("created_at", true).Limit(50).Build()
+type SelectBuilder struct {
+	table   string
+	columns []string
+	where   conditions
+	order   []string
+	limit   int
+	offset  int
+	lock    RowLock
+}
+
+// Select starts a select from table, which may be schema qualified.
+func Select(table string) *SelectBuilder {
+	return &SelectBuilder{table: table}
+}
+
+// Columns sets the columns selected, all columns if none are given.
+func (b *SelectBuilder) Columns(columns ...string) *SelectBuilder {
+	b.columns = append(b.columns, columns...)
+	return b
+}
+
+// Where adds a condition written in sql with ? parameters, conditions are joined with and.
+func (b *SelectBuilder) Where(cond string, args ...interface{}) *SelectBuilder {
+	b.where.add(cond, args)
+	return b
+}
+
+// WhereEq adds a column = value condition.
+func (b *SelectBuilder) WhereEq(column string, value interface{}) *SelectBuilder {
+	b.where.add(QuoteQualified(column)+" = ?", []interface{}{value})
+	return b
+}
+
+// WhereIn adds a column in (values) condition, which matches nothing if values is empty.
+func (b *SelectBuilder) WhereIn(column string, values ...interface{}) *SelectBuilder {
+	b.where.addIn(column, values)
+	return b
+}
+
+// OrderBy adds a column to order by, descending if desc is set.
+func (b *SelectBuilder) OrderBy(column string, desc bool) *SelectBuilder {
+	order := QuoteQualified(column)
+	if desc {
+		order += " desc"
+	}
+	b.order = append(b.order, order)
+	return b
+}
+
+// Limit limits the number of rows returned, zero for no limit.
+func (b *SelectBuilder) Limit(n int) *SelectBuilder {
+	b.limit = n
+	return b
+}
+
+// Offset skips the first n rows.
+func (b *SelectBuilder) Offset(n int) *SelectBuilder {
+	b.offset = n
+	return b
+}
+
+// Lock adds a locking clause such as FOR UPDATE SKIP LOCKED.
+func (b *SelectBuilder) Lock(lock RowLock) *SelectBuilder {
+	b.lock = lock
+	return b
+}
+
+// Build returns the sql and its arguments.
+func (b *SelectBuilder) Build() (string, []interface{}) {
+	columns := "*"
+	if len(b.columns) > 0 {
+		columns = quoteList(b.columns)
+	}
+
+	var args []interface{}
+	query := "select " + columns + " from " + QuoteQualified(b.table) + b.where.build(&args)
+	if len(b.order) > 0 {
+		query += " order by " + strings.Join(b.order, ", ")
+	}
+	if b.limit > 0 {
+		args = append(args, b.limit)
+		query += " limit $" + strconv.Itoa(len(args))
+	}
+	if b.offset > 0 {
+		args = append(args, b.offset)
+		query += " offset $" + strconv.Itoa(len(args))
+	}
+	if clause := b.lock.String(); clause != "" {
+		query += " " + clause
+	}
+	return query, args
+}
+
+// InsertBuilder builds a parameterized insert of a single row.
+type InsertBuilder struct {
+	table     string
+	columns   []string
+	values    []interface{}
+	returning []string
+}
+
+// Insert starts an insert into table, which may be schema qualified.
+func Insert(table string) *InsertBuilder {
+	return &InsertBuilder{table: table}
+}
+
+// Set sets the value of column in the inserted row.
+func (b *InsertBuilder) Set(column string, value interface{}) *InsertBuilder {
+	b.columns = append(b.columns, column)
+	b.values = append(b.values, value)
+	return b
+}
+
+// Returning sets the columns returned from the inserted row.
+func (b *InsertBuilder) Returning(columns ...string) *InsertBuilder {
+	b.returning = append(b.returning, columns...)
+	return b
+}
+
+// Build returns the sql and its arguments. Without any columns set the row is inserted with
+// the column defaults.
+func (b *InsertBuilder) Build() (string, []interface{}) {
+	if len(b.columns) == 0 {
+		return "insert into " + QuoteQualified(b.table) + " default values" + returning(b.returning), nil
+	}
+	params := make([]string, len(b.values))
+	for i := range b.values {
+		params[i] = "$" + strconv.Itoa(i+1)
+	}
+	query := "insert into " + QuoteQualified(b.table) + " (" + quoteList(b.columns) + ") values (" + strings.Join(params, ", ") + ")"
+	return query + returning(b.returning), append([]interface{}(nil), b.values...)
+}
+
+// UpdateBuilder builds a parameterized update.
+type UpdateBuilder struct {
+	table     string
+	columns   []string
+	values    []interface{}
+	where     conditions
+	returning []string
+}
+
+// Update starts an update of table, which may be schema qualified.
+func Update(table string) *UpdateBuilder {
+	return &UpdateBuilder{table: table}
+}
+
+// Set sets column to value.
+func (b *UpdateBuilder) Set(column string, value interface{}) *UpdateBuilder {
+	b.columns = append(b.columns, column)
+	b.values = append(b.values, value)
+	return b
+}
+
+// Where adds a condition written in sql with ? parameters, conditions are joined with and.
+func (b *UpdateBuilder) Where(cond string, args ...interface{}) *UpdateBuilder {
+	b.where.add(cond, args)
+	return b
+}
+
+// WhereEq adds a column = value condition.
+func (b *UpdateBuilder) WhereEq(column string, value interface{}) *UpdateBuilder {
+	b.where.add(QuoteQualified(column)+" = ?", []interface{}{value})
+	return b
+}
+
+// Returning sets the columns returned from the updated rows.
+func (b *UpdateBuilder) Returning(columns ...string) *UpdateBuilder {
+	b.returning = append(b.returning, columns...)
+	return b
+}
+
+// Build returns the sql and its arguments.
+func (b *UpdateBuilder) Build() (string, []interface{}) {
+	args := append([]interface{}(nil), b.values...)
+	sets := make([]string, len(b.columns))
+	for i, column := range b.columns {
+		sets[i] = QuoteQualified(column) + " = $" + strconv.Itoa(i+1)
+	}
+	query := "update " + QuoteQualified(b.table) + " set " + strings.Join(sets, ", ") + b.where.build(&args)
+	return query + returning(b.returning), args
+}
+
+// DeleteBuilder builds a parameterized delete.
+type DeleteBuilder struct {
+	table     string
+	where     conditions
+	returning []string
+}
+
+// Delete starts a delete from table, which may be schema qualified.
+func Delete(table string) *DeleteBuilder {
+	return &DeleteBuilder{table: table}
+}
+
+// Where adds a condition written in sql with ? parameters, conditions are joined with and.
+func (b *DeleteBuilder) Where(cond string, args ...interface{}) *DeleteBuilder {
+	b.where.add(cond, args)
+	return b
+}
+
+// WhereEq adds a column = value condition.
+func (b *DeleteBuilder) WhereEq(column string, value interface{}) *DeleteBuilder {
+	b.where.add(QuoteQualified(column)+" = ?", []interface{}{value})
+	return b
+}
+
+// Returning sets the columns returned from the deleted rows.
+func (b *DeleteBuilder) Returning(columns ...string) *DeleteBuilder {
+	b.returning = append(b.returning, columns...)
+	return b
+}
+
+// Build returns the sql and its arguments.
+func (b *DeleteBuilder) Build() (string, []interface{}) {
+	var args []interface{}
+	query := "delete from " + QuoteQualified(b.table) + b.where.build(&args)
+	return query + returning(b.returning), args
+}
+
+// conditions are where conditions joined with and.
+type conditions struct {
+	conds []string
+	args  [][]interface{}
+}
+
+// add adds a condition with ? parameters.
+func (c *conditions) add(cond string, args []interface{}) {
+	c.conds = append(c.conds, cond)
+	c.args = append(c.args, args)
+}
+
+// addIn adds a column in (values) condition.
+func (c *conditions) addIn(column string, values []interface{}) {
+	if len(values) == 0 {
+		c.add("false", nil)
+		return
+	}
+	c.add(QuoteQualified(column)+" in ("+strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")+")", values)
+}
+
+// build returns the where clause, numbering its parameters after those already in args and
+// appending its arguments to args.
+func (c *conditions) build(args *[]interface{}) string {
+	if len(c.conds) == 0 {
+		return ""
+	}
+	conds := make([]string, len(c.conds))
+	for i, cond := range c.conds {
+		conds[i] = "(" + numberParams(cond, len(*args)) + ")"
+		*args = append(*args, c.args[i]...)
+	}
+	return " where " + strings.Join(conds, " and ")
+}
+
+// numberParams replaces the ? parameters in cond with $n starting after offset, leaving string
+// literals and quoted identifiers alone. ?? becomes a literal ?.
+func numberParams(cond string, offset int) string {
+	var b strings.Builder
+	for i := 0; i < len(cond); i++ {
+		switch c := cond[i]; {
+		case c == '\'' || c == '"':
+			end := skipQuoted(cond, i, c)
+			if end >= len(cond) {
+				end = len(cond) - 1
+			}
+			b.WriteString(cond[i : end+1])
+			i = end
+		case c == '?' && i+1 < len(cond) && cond[i+1] == '?':
+			b.WriteByte('?')
+			i++
+		case c == '?':
+			offset++
+			b.WriteString("$" + strconv.Itoa(offset))
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// quoteList quotes and joins column names.
+func quoteList(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = QuoteQualified(column)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// returning returns the returning clause for columns, if any.
+func returning(columns []string) string {
+	if len(columns) == 0 {
+		return ""
+	}
+	return " returning " + quoteList(columns)
+}
@@ -0,0 +1,43 @@
+package godbm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectBuilder(t *testing.T) {
+	query, args := Select("shop.orders").Columns("id", "total").
+		WhereEq("status", "paid").
+		Where("created_at > ? and note <> '?' and tags ?? ?", "2020-01-01", "gift").
+		WhereIn("region", "eu", "us").
+		OrderBy("created_at", true).Limit(50).Offset(100).Build()
+
+	want := `select "id", "total" from "shop"."orders" where ("status" = $1) and (created_at > $2 and note <> '?' and tags ? $3) and ("region" in ($4, $5)) order by "created_at" desc limit $6 offset $7`
+	if query != want {
+		t.Fatalf("unexpected sql:\n%s\n%s\n", query, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"paid", "2020-01-01", "gift", "eu", "us", 50, 100}) {
+		t.Fatalf("unexpected args: %v\n", args)
+	}
+
+	if query, _ := Select("t").WhereIn("id").Build(); query != `select * from "t" where (false)` {
+		t.Fatalf("expected an empty in to match nothing, got: %s\n", query)
+	}
+}
+
+func TestWriteBuilders(t *testing.T) {
+	query, args := Insert("users").Set("email", "a@example.com").Set("name", "a").Returning("id").Build()
+	if query != `insert into "users" ("email", "name") values ($1, $2) returning "id"` || len(args) != 2 {
+		t.Fatalf("unexpected insert: %s %v\n", query, args)
+	}
+
+	query, args = Update("users").Set("name", "b").WhereEq("id", 7).Build()
+	if query != `update "users" set "name" = $1 where ("id" = $2)` || !reflect.DeepEqual(args, []interface{}{"b", 7}) {
+		t.Fatalf("unexpected update: %s %v\n", query, args)
+	}
+
+	query, args = Delete("users").Where("last_seen < now() - ?::interval", "1 year").Build()
+	if query != `delete from "users" where (last_seen < now() - $1::interval)` || len(args) != 1 {
+		t.Fatalf("unexpected delete: %s %v\n", query, args)
+	}
+}
@@ -0,0 +1,229 @@
+// Package cdc streams row changes out of postgres through a logical replication slot, for
+// services which want change events without running a separate CDC pipeline.
+//
+// Changes are read with the logical decoding SQL functions using the wal2json output plugin,
+// which must be installed on the server, and the connecting user needs the REPLICATION
+// attribute. The replication protocol, and with it the pgoutput plugin, isn't available
+// through lib/pq, so the slot is polled rather than streamed.
+package cdc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/wirepair/godbm"
+	"strings"
+	"time"
+)
+
+// Op is the kind of change an Event describes.
+type Op string
+
+const (
+	Insert Op = "I"
+	Update Op = "U"
+	Delete Op = "D"
+)
+
+// Event is a single row change.
+type Event struct {
+	LSN      string                 // position of the change in the WAL.
+	Op       Op                     // insert, update or delete.
+	Schema   string                 // schema of the changed table.
+	Table    string                 // name of the changed table.
+	Columns  map[string]interface{} // new row values for inserts and updates.
+	Identity map[string]interface{} // replica identity, usually the primary key, of the old row for updates and deletes.
+}
+
+// Options configures a Consumer.
+type Options struct {
+	Slot         string        // replication slot name, required.
+	Tables       []string      // tables to capture as schema.table, "*" wildcards allowed, all tables if empty.
+	BatchSize    int           // changes read per poll, whole transactions are always read, defaults to 1000.
+	PollInterval time.Duration // wait between polls which find no changes, defaults to a second.
+}
+
+// Consumer reads the changes of a replication slot. Changes are only acknowledged, and so
+// removed from the slot, once the handler passed to Run has returned nil for them, so after
+// a crash or restart delivery resumes from the last acknowledged transaction and handlers
+// should tolerate seeing a transaction twice.
+type Consumer struct {
+	store *godbm.SqlStore
+	opts  Options
+}
+
+// New creates a Consumer reading the slot in opts through store.
+func New(store *godbm.SqlStore, opts Options) (*Consumer, error) {
+	if opts.Slot == "" {
+		return nil, errors.New("godbm: error cdc consumer needs a slot name")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	return &Consumer{store: store, opts: opts}, nil
+}
+
+// CreateSlot creates the replication slot with the wal2json plugin unless it already exists.
+// The slot retains WAL until changes are acknowledged, so drop slots which are no longer read
+// with DropSlot or the server's disk will fill.
+func (c *Consumer) CreateSlot(ctx context.Context) error {
+	_, err := c.store.ExecContext(ctx, "select pg_create_logical_replication_slot($1, 'wal2json') "+
+		"where not exists (select 1 from pg_replication_slots where slot_name = $1)", c.opts.Slot)
+	return err
+}
+
+// DropSlot drops the replication slot if it exists.
+func (c *Consumer) DropSlot(ctx context.Context) error {
+	_, err := c.store.ExecContext(ctx, "select pg_drop_replication_slot(slot_name) from pg_replication_slots where slot_name = $1", c.opts.Slot)
+	return err
+}
+
+// ConfirmedLSN returns the position up to which changes have been acknowledged, where reading
+// restarts.
+func (c *Consumer) ConfirmedLSN(ctx context.Context) (lsn string, err error) {
+	rows, err := c.store.QueryContext(ctx, "select coalesce(confirmed_flush_lsn::text, '') from pg_replication_slots where slot_name = $1", c.opts.Slot)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		return "", errors.New("godbm: error replication slot " + c.opts.Slot + " does not exist")
+	}
+	err = rows.Scan(&lsn)
+	return lsn, err
+}
+
+// Run reads changes until ctx is done or fn returns an error, passing fn the changes of one
+// or more whole transactions at a time. Changes are acknowledged after fn returns nil.
+func (c *Consumer) Run(ctx context.Context, fn func(ctx context.Context, events []Event) error) error {
+	for {
+		events, last, err := c.Poll(ctx)
+		if err != nil {
+			return err
+		}
+		if last == "" {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.opts.PollInterval):
+			}
+			continue
+		}
+		if len(events) > 0 {
+			if err := fn(ctx, events); err != nil {
+				return err
+			}
+		}
+		if err := c.Ack(ctx, last); err != nil {
+			return err
+		}
+	}
+}
+
+// Poll returns the pending changes without acknowledging them, along with the position to pass
+// to Ack once they are handled. last is empty if there were no pending transactions, events
+// may be empty even when it isn't if the transactions changed no captured tables.
+func (c *Consumer) Poll(ctx context.Context) (events []Event, last string, err error) {
+	opts := []interface{}{c.opts.Slot, c.opts.BatchSize, "format-version", "2", "include-transaction", "true"}
+	query := "select lsn::text, data from pg_logical_slot_peek_changes($1, null, $2, $3, $4, $5, $6"
+	if len(c.opts.Tables) > 0 {
+		opts = append(opts, "add-tables", addTables(c.opts.Tables))
+		query += ", $7, $8"
+	}
+
+	rows, err := c.store.QueryContext(ctx, query+")", opts...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var lsn string
+		var data []byte
+		if err := rows.Scan(&lsn, &data); err != nil {
+			return nil, "", err
+		}
+		last = lsn
+		event, ok, err := parseChange(lsn, data)
+		if err != nil {
+			return nil, "", err
+		}
+		if ok {
+			events = append(events, event)
+		}
+	}
+	return events, last, rows.Err()
+}
+
+// Ack acknowledges every change up to and including the transaction ending at lsn, as returned
+// by Poll, removing them from the slot.
+func (c *Consumer) Ack(ctx context.Context, lsn string) error {
+	// consuming changes up to lsn is how the SQL interface confirms them.
+	_, err := c.store.ExecContext(ctx, "select count(*) from pg_logical_slot_get_changes($1, $2::pg_lsn, null, 'format-version', '2')", c.opts.Slot, lsn)
+	return err
+}
+
+// addTables formats tables for wal2json's add-tables option, which requires a schema.
+func addTables(tables []string) string {
+	formatted := make([]string, len(tables))
+	for i, table := range tables {
+		if !strings.Contains(table, ".") {
+			table = "*." + table
+		}
+		formatted[i] = strings.ReplaceAll(strings.ReplaceAll(table, `\`, `\\`), ",", `\,`)
+	}
+	return strings.Join(formatted, ",")
+}
+
+// change is a wal2json format version 2 row.
+type change struct {
+	Action   string   `json:"action"`
+	Schema   string   `json:"schema"`
+	Table    string   `json:"table"`
+	Columns  []column `json:"columns"`
+	Identity []column `json:"identity"`
+}
+
+// column is a column value in a wal2json change.
+type column struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+// parseChange decodes a wal2json row, ok is false for rows which aren't row changes such as
+// transaction begin and commit. Numbers are decoded as json.Number so no precision is lost.
+func parseChange(lsn string, data []byte) (event Event, ok bool, err error) {
+	var ch change
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&ch); err != nil {
+		return event, false, err
+	}
+
+	switch op := Op(ch.Action); op {
+	case Insert, Update, Delete:
+		event = Event{LSN: lsn, Op: op, Schema: ch.Schema, Table: ch.Table, Columns: columnMap(ch.Columns), Identity: columnMap(ch.Identity)}
+		return event, true, nil
+	}
+	return event, false, nil
+}
+
+// columnMap maps column names to values, nil if there are no columns.
+func columnMap(columns []column) map[string]interface{} {
+	if len(columns) == 0 {
+		return nil
+	}
+	values := make(map[string]interface{}, len(columns))
+	for _, c := range columns {
+		values[c.Name] = c.Value
+	}
+	return values
+}
@@ -0,0 +1,33 @@
+package cdc
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseChange(t *testing.T) {
+	data := `{"action":"U","schema":"public","table":"users","columns":[{"name":"id","type":"bigint","value":9007199254740993},{"name":"name","type":"text","value":"bob"}],"identity":[{"name":"id","type":"bigint","value":9007199254740993}]}`
+	event, ok, err := parseChange("0/16B3748", []byte(data))
+	if err != nil || !ok {
+		t.Fatalf("error parsing change: %v %v\n", ok, err)
+	}
+	if event.Op != Update || event.Table != "users" || event.Columns["name"] != "bob" || event.Identity["id"] != json.Number("9007199254740993") {
+		t.Fatalf("unexpected event: %+v\n", event)
+	}
+
+	if _, ok, err := parseChange("0/16B3750", []byte(`{"action":"C"}`)); ok || err != nil {
+		t.Fatalf("expected commits to be skipped: %v %v\n", ok, err)
+	}
+}
+
+func TestAddTables(t *testing.T) {
+	if tables := addTables([]string{"users", "audit.events"}); tables != "*.users,audit.events" {
+		t.Fatalf("unexpected add-tables: %s\n", tables)
+	}
+}
+
+func TestNewRequiresSlot(t *testing.T) {
+	if _, err := New(nil, Options{}); err == nil {
+		t.Fatalf("expected an error without a slot name\n")
+	}
+}
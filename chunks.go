@@ -0,0 +1,68 @@
+package godbm
+
+import (
+	"context"
+	"errors"
+)
+
+// Chunk is a batch of rows read by ScanTableInChunks.
+type Chunk struct {
+	Rows    []map[string]interface{} // the rows, as returned by QueryPreparedMaps.
+	LastKey interface{}              // key of the last row, save it to resume with ScanTableInChunksFrom.
+	Number  int                      // 1 based number of the chunk within this scan.
+}
+
+// ScanTableInChunks reads every row of table in order of keyColumn, chunkSize rows at a time,
+// calling fn with each chunk. Chunks are selected by key range rather than offset, so each
+// one is an index range scan however far into the table it is. keyColumn must be unique, such
+// as the primary key. The scan stops at the first error returned by fn, which is returned.
+// Rows are read without a transaction, so rows changed during the scan may or may not be seen.
+func (store *SqlStore) ScanTableInChunks(ctx context.Context, table, keyColumn string, chunkSize int, fn func(ctx context.Context, chunk Chunk) error) error {
+	return store.scanChunks(ctx, table, keyColumn, nil, chunkSize, fn)
+}
+
+// ScanTableInChunksFrom is the same as ScanTableInChunks but resumes after the row whose key
+// is after, typically the LastKey of the last chunk processed by an earlier scan.
+func (store *SqlStore) ScanTableInChunksFrom(ctx context.Context, table, keyColumn string, after interface{}, chunkSize int, fn func(ctx context.Context, chunk Chunk) error) error {
+	if after == nil {
+		return errors.New("godbm: error ScanTableInChunksFrom requires a key to resume after")
+	}
+	return store.scanChunks(ctx, table, keyColumn, after, chunkSize, fn)
+}
+
+// scanChunks implements ScanTableInChunks, starting after the key after unless it is nil.
+func (store *SqlStore) scanChunks(ctx context.Context, table, keyColumn string, after interface{}, chunkSize int, fn func(ctx context.Context, chunk Chunk) error) error {
+	if chunkSize <= 0 {
+		return errors.New("godbm: error chunk size must be positive")
+	}
+	for number := 1; ; number++ {
+		b := Select(table).OrderBy(keyColumn, false).Limit(chunkSize)
+		if after != nil {
+			b.Where(QuoteQualified(keyColumn)+" > ?", after)
+		}
+		query, args := b.Build()
+		rows, err := store.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		results, err := store.collectMaps(rows)
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			return nil
+		}
+
+		last, ok := results[len(results)-1][keyColumn]
+		if !ok {
+			return errors.New("godbm: error key column " + keyColumn + " is not in the table's rows")
+		}
+		if err := fn(ctx, Chunk{Rows: results, LastKey: last, Number: number}); err != nil {
+			return err
+		}
+		if len(results) < chunkSize {
+			return nil
+		}
+		after = last
+	}
+}
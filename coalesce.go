@@ -0,0 +1,49 @@
+package godbm
+
+import "context"
+
+// Coalesced makes concurrent QueryPreparedMaps calls of the statement with the same arguments
+// share a single query, each caller getting its own copy of the rows. Combined with Cached
+// this stops a burst of misses from all reaching the database. The shared query runs with the
+// context of the first caller, so its cancellation fails the others too. Calls made inside a
+// transaction always run their own query. QueryPrepared isn't coalesced, as the *sql.Rows it
+// returns are read from the connection and can't be shared between callers.
+func Coalesced() StmtOption {
+	return func(o *stmtOptions) {
+		o.coalesced = true
+	}
+}
+
+// coalescedMaps runs query for st and args unless an identical call is in flight, in which
+// case it waits for that call's results. Every caller gets its own copy, the rows read by
+// query are never handed out so callers may modify theirs while others are still copying.
+func (store *SqlStore) coalescedMaps(ctx context.Context, st *statement, args []interface{}, query func() ([]map[string]interface{}, error)) ([]map[string]interface{}, error) {
+	if _, inTx := TxFromContext(ctx); inTx || !st.opts.coalesced {
+		return query()
+	}
+
+	val, err, shared := store.reads.do(store.resultKey(st.key, args), func() (interface{}, error) {
+		return query()
+	})
+	if err != nil {
+		return nil, err
+	}
+	if shared {
+		store.counters.coalesced.Add(1)
+	}
+	results, _ := val.([]map[string]interface{})
+	return copyMaps(results), nil
+}
+
+// copyMaps returns a copy of rows, so callers sharing a result can modify their own.
+func copyMaps(rows []map[string]interface{}) []map[string]interface{} {
+	copied := make([]map[string]interface{}, len(rows))
+	for i, row := range rows {
+		c := make(map[string]interface{}, len(row))
+		for k, v := range row {
+			c[k] = v
+		}
+		copied[i] = c
+	}
+	return copied
+}
@@ -0,0 +1,54 @@
+package godbm
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCoalescedMaps(t *testing.T) {
+	ctx := context.Background()
+	dbm := New(username, password, dbname, host, "disable", "")
+	st := &statement{key: "report"}
+	Coalesced()(&st.opts)
+
+	const callers = 5
+	release := make(chan struct{})
+	calls := 0
+	original := []map[string]interface{}{{"n": 1}}
+	query := func() ([]map[string]interface{}, error) {
+		calls++
+		<-release
+		return original, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([][]map[string]interface{}, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = dbm.coalescedMaps(ctx, st, []interface{}{1}, query)
+		}(i)
+	}
+	// give every caller time to join the flight before letting the query finish.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 || dbm.Stats().Coalesced != callers-1 {
+		t.Fatalf("expected a single query got %d\n", calls)
+	}
+	for i := range results {
+		results[i][0]["n"] = i + 2
+	}
+	if original[0]["n"] != 1 {
+		t.Fatalf("expected no caller to be handed the rows read by the query\n")
+	}
+	for i := range results {
+		if results[i][0]["n"] != i+2 {
+			t.Fatalf("expected each caller to get its own copy: %v\n", results[i])
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package godbm
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// QueryComments configures sqlcommenter style comments appended to the sql sent to the server,
+// such as /*route='%2Forders',service='billing'*/, so pg_stat_activity and the server logs can
+// be correlated back to application endpoints.
+type QueryComments struct {
+	Tags        map[string]string                           // tags added to every statement, such as the service name.
+	FromContext func(ctx context.Context) map[string]string // optional, tags taken from each call's context, such as a traceparent.
+}
+
+// SetQueryComments sets the comments appended to executed sql. Tags are added when statements
+// are prepared. As prepared statements are only sent once, per call tags from WithQueryTags
+// and FromContext are only added to statements which are not prepared, see SetNoPrepare.
+// Queries which already contain a comment are left unchanged. Must be called before Connect.
+func (store *SqlStore) SetQueryComments(c *QueryComments) {
+	store.comments = c
+}
+
+type queryTagsKey struct{}
+
+// WithQueryTags returns a context carrying tags for the query comments of calls made with it.
+// Tags are merged with any the context already carries.
+func WithQueryTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := make(map[string]string, len(tags))
+	for k, v := range queryTags(ctx) {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, queryTagsKey{}, merged)
+}
+
+// queryTags returns the tags set with WithQueryTags, if any.
+func queryTags(ctx context.Context) map[string]string {
+	tags, _ := ctx.Value(queryTagsKey{}).(map[string]string)
+	return tags
+}
+
+// comment returns query with the comment for the store's tags and those of ctx appended.
+func (store *SqlStore) comment(ctx context.Context, query string) string {
+	if store.comments == nil {
+		return query
+	}
+	tags := make(map[string]string)
+	for k, v := range queryTags(ctx) {
+		tags[k] = v
+	}
+	if store.comments.FromContext != nil {
+		for k, v := range store.comments.FromContext(ctx) {
+			tags[k] = v
+		}
+	}
+	return store.commentWith(query, tags)
+}
+
+// commentWith returns query with the comment for the store's tags and the extra tags appended.
+func (store *SqlStore) commentWith(query string, extra map[string]string) string {
+	if store.comments == nil || strings.Contains(query, "/*") {
+		return query
+	}
+
+	tags := make(map[string]string, len(store.comments.Tags)+len(extra))
+	for k, v := range store.comments.Tags {
+		tags[k] = v
+	}
+	for k, v := range extra {
+		tags[k] = v
+	}
+	if len(tags) == 0 {
+		return query
+	}
+	return query + " " + formatComment(tags)
+}
+
+// formatComment formats tags as a sqlcommenter comment, with url encoded keys and values in
+// key order.
+func formatComment(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = commentEscape(k) + "='" + commentEscape(tags[k]) + "'"
+	}
+	return "/*" + strings.Join(pairs, ",") + "*/"
+}
+
+// commentEscape url encodes s, which also removes any quotes or comment terminators.
+func commentEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
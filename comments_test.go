@@ -0,0 +1,34 @@
+package godbm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestQueryComments(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	if q := dbm.comment(context.Background(), "select 1"); q != "select 1" {
+		t.Fatalf("expected no comment without QueryComments, got: %s\n", q)
+	}
+
+	dbm.SetQueryComments(&QueryComments{
+		Tags: map[string]string{"service": "billing"},
+		FromContext: func(ctx context.Context) map[string]string {
+			return map[string]string{"traceparent": "00-abc-01"}
+		},
+	})
+
+	if q := dbm.commentWith("select 1", nil); q != "select 1 /*service='billing'*/" {
+		t.Fatalf("unexpected prepare time comment: %s\n", q)
+	}
+
+	ctx := WithQueryTags(context.Background(), map[string]string{"route": "/orders/{id}", "evil": "'*/ drop"})
+	want := "select 1 /*evil='%27%2A%2F%20drop',route='%2Forders%2F%7Bid%7D',service='billing',traceparent='00-abc-01'*/"
+	if q := dbm.comment(ctx, "select 1"); q != want {
+		t.Fatalf("unexpected comment:\n%s\n%s\n", q, want)
+	}
+
+	if q := dbm.comment(ctx, "select /* hint */ 1"); q != "select /* hint */ 1" {
+		t.Fatalf("expected queries with comments to be left alone, got: %s\n", q)
+	}
+}
@@ -0,0 +1,261 @@
+package godbm
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeLayouts are the text formats postgres uses for date, timestamp and timestamptz values.
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00:00",
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999-07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// Composite wraps a pointer to a struct so a postgres composite (row) value can be scanned into
+// it or passed as a parameter. The struct's mapped fields, see fieldsOf, are matched to the
+// composite's attributes in order. NULL attributes are handled as in ScanStruct.
+//
+//	var addr Address
+//	err := row.Scan(godbm.Composite(&addr))
+func Composite(v interface{}) interface {
+	driver.Valuer
+	sql.Scanner
+} {
+	return &composite{v}
+}
+
+type composite struct {
+	v interface{}
+}
+
+// Scan implements sql.Scanner.
+func (c *composite) Scan(src interface{}) error {
+	rv, err := structValue(c.v)
+	if err != nil {
+		return err
+	}
+	text, ok := asText(src)
+	if !ok {
+		return fmt.Errorf("godbm: error cannot scan %T into a composite", src)
+	}
+	if src == nil {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+
+	attrs, err := parseElements(text, '(', ')')
+	if err != nil {
+		return err
+	}
+	fields := fieldsOf(rv.Type())
+	if len(attrs) != len(fields) {
+		return fmt.Errorf("godbm: error composite has %d attributes but %s has %d fields", len(attrs), rv.Type(), len(fields))
+	}
+	for i, f := range fields {
+		if err := assignText(rv.FieldByIndex(f.index), attrs[i]); err != nil {
+			return fmt.Errorf("godbm: error scanning composite attribute %s: %w", f.column, err)
+		}
+	}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (c *composite) Value() (driver.Value, error) {
+	rv, err := structValue(c.v)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	b.WriteByte('(')
+	for i, f := range fieldsOf(rv.Type()) {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		text, null, err := formatText(rv.FieldByIndex(f.index))
+		if err != nil {
+			return nil, err
+		}
+		if !null {
+			b.WriteString(quoteElement(text))
+		}
+	}
+	b.WriteByte(')')
+	return b.String(), nil
+}
+
+// asText returns the text of a column value, src is nil for NULL.
+func asText(src interface{}) (string, bool) {
+	switch v := src.(type) {
+	case nil:
+		return "", true
+	case []byte:
+		return string(v), true
+	case string:
+		return v, true
+	}
+	return "", false
+}
+
+// parseElements splits the text form of a composite or range, such as (1,"a b",) or [1,5),
+// into its elements. The first and last characters are not checked beyond their position, as
+// range bounds may be either [ or (. Empty unquoted elements are NULL and returned as nil.
+func parseElements(s string, open, close byte) ([]*string, error) {
+	if len(s) < 2 || (open != 0 && s[0] != open) || (close != 0 && s[len(s)-1] != close) {
+		return nil, fmt.Errorf("godbm: error invalid row value %q", s)
+	}
+	s = s[1 : len(s)-1]
+
+	var elems []*string
+	var b strings.Builder
+	quoted, inQuotes := false, false
+	for i := 0; i <= len(s); i++ {
+		if i == len(s) || (s[i] == ',' && !inQuotes) {
+			if inQuotes {
+				return nil, errors.New("godbm: error unterminated quote in row value")
+			}
+			if b.Len() == 0 && !quoted {
+				elems = append(elems, nil)
+			} else {
+				elem := b.String()
+				elems = append(elems, &elem)
+			}
+			b.Reset()
+			quoted = false
+			continue
+		}
+
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			i++
+			b.WriteByte(s[i])
+		case c == '"' && inQuotes && i+1 < len(s) && s[i+1] == '"':
+			i++
+			b.WriteByte('"')
+		case c == '"':
+			inQuotes = !inQuotes
+			quoted = true
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return elems, nil
+}
+
+// quoteElement quotes text for use inside a composite or range literal.
+func quoteElement(text string) string {
+	if text != "" && !strings.ContainsAny(text, "\"\\(),[] \t\n") {
+		return text
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text) + `"`
+}
+
+// assignText sets dest from the text form of a postgres value, text is nil for NULL which sets
+// pointers to nil and other values to their zero value.
+func assignText(dest reflect.Value, text *string) error {
+	if scanner, ok := dest.Addr().Interface().(sql.Scanner); ok {
+		if text == nil {
+			return scanner.Scan(nil)
+		}
+		return scanner.Scan(*text)
+	}
+	if text == nil {
+		dest.Set(reflect.Zero(dest.Type()))
+		return nil
+	}
+	if dest.Kind() == reflect.Ptr {
+		v := reflect.New(dest.Type().Elem())
+		if err := assignText(v.Elem(), text); err != nil {
+			return err
+		}
+		dest.Set(v)
+		return nil
+	}
+
+	s := *text
+	if dest.Type() == reflect.TypeOf(time.Time{}) {
+		t, err := parseTime(s)
+		if err != nil {
+			return err
+		}
+		dest.Set(reflect.ValueOf(t))
+		return nil
+	}
+	switch dest.Kind() {
+	case reflect.String:
+		dest.SetString(s)
+	case reflect.Bool:
+		dest.SetBool(s == "t" || s == "true")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, dest.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dest.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, dest.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dest.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, dest.Type().Bits())
+		if err != nil {
+			return err
+		}
+		dest.SetFloat(f)
+	default:
+		return fmt.Errorf("godbm: error cannot convert text into %s", dest.Type())
+	}
+	return nil
+}
+
+// parseTime parses a postgres date, timestamp or timestamptz.
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("godbm: error invalid time %q", s)
+}
+
+// formatText returns the text form of v for a composite or range literal, null is set for nil
+// pointers and values whose driver.Valuer returns nil.
+func formatText(v reflect.Value) (text string, null bool, err error) {
+	var value interface{} = v.Interface()
+	if valuer, ok := value.(driver.Valuer); ok {
+		if value, err = valuer.Value(); err != nil {
+			return "", false, err
+		}
+	} else if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", true, nil
+		}
+		return formatText(v.Elem())
+	}
+
+	switch x := value.(type) {
+	case nil:
+		return "", true, nil
+	case time.Time:
+		return x.Format("2006-01-02 15:04:05.999999999-07:00"), false, nil
+	case []byte:
+		return string(x), false, nil
+	case bool:
+		if x {
+			return "t", false, nil
+		}
+		return "f", false, nil
+	}
+	return fmt.Sprint(value), false, nil
+}
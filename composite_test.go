@@ -0,0 +1,62 @@
+package godbm
+
+import (
+	"testing"
+	"time"
+)
+
+type address struct {
+	Street string  `db:"street"`
+	Number int     `db:"number"`
+	Unit   *string `db:"unit"`
+	Active bool    `db:"active"`
+}
+
+func TestComposite(t *testing.T) {
+	var addr address
+	if err := Composite(&addr).Scan([]byte(`("1 Main ""St""",42,,t)`)); err != nil {
+		t.Fatalf("error scanning composite: %v\n", err)
+	}
+	if addr.Street != `1 Main "St"` || addr.Number != 42 || addr.Unit != nil || !addr.Active {
+		t.Fatalf("unexpected composite: %+v\n", addr)
+	}
+
+	v, err := Composite(&addr).Value()
+	if err != nil || v != `("1 Main \"St\"",42,,t)` {
+		t.Fatalf("unexpected composite value: %v %v\n", v, err)
+	}
+	if err := Composite(&addr).Scan("(a,1)"); err == nil {
+		t.Fatalf("expected an error for a short composite\n")
+	}
+}
+
+func TestRange(t *testing.T) {
+	var ints Range[int64]
+	if err := ints.Scan([]byte("[1,5)")); err != nil || ints.Lower != 1 || ints.Upper != 5 || !ints.LowerInc || ints.UpperInc {
+		t.Fatalf("unexpected range: %+v %v\n", ints, err)
+	}
+	if err := ints.Scan("(,10]"); err != nil || !ints.LowerInf || ints.Upper != 10 || !ints.UpperInc {
+		t.Fatalf("unexpected unbounded range: %+v %v\n", ints, err)
+	}
+	if err := ints.Scan("empty"); err != nil || !ints.Empty {
+		t.Fatalf("unexpected empty range: %+v %v\n", ints, err)
+	}
+	if err := ints.Scan(nil); err != nil || ints.Valid {
+		t.Fatalf("unexpected NULL range: %+v %v\n", ints, err)
+	}
+
+	var booked Range[time.Time]
+	if err := booked.Scan(`["2024-01-01 10:00:00+00","2024-01-01 11:30:00+00")`); err != nil {
+		t.Fatalf("error scanning tstzrange: %v\n", err)
+	}
+	if booked.Upper.Sub(booked.Lower) != 90*time.Minute {
+		t.Fatalf("unexpected tstzrange: %+v\n", booked)
+	}
+	if v, _ := booked.Value(); v != `["2024-01-01 10:00:00+00:00","2024-01-01 11:30:00+00:00")` {
+		t.Fatalf("unexpected tstzrange value: %v\n", v)
+	}
+
+	if v, _ := (Range[int]{Lower: 1, LowerInc: true, UpperInf: true, Valid: true}).Value(); v != "[1,)" {
+		t.Fatalf("unexpected range value: %v\n", v)
+	}
+}
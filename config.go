@@ -0,0 +1,122 @@
+package godbm
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+// Config holds the store configuration in a form which can be decoded from a config file. It
+// carries json, yaml and toml tags, LoadConfig reads json, decode yaml or toml with the library
+// of your choice and pass the result to NewFromConfig.
+type Config struct {
+	Host               string   `json:"host" yaml:"host" toml:"host"`                                                 // comma separated host or host:port entries
+	Replicas           []string `json:"replicas" yaml:"replicas" toml:"replicas"`                                     // read replica host or host:port entries
+	User               string   `json:"user" yaml:"user" toml:"user"`                                                 // database username
+	Password           string   `json:"password" yaml:"password" toml:"password"`                                     // database password
+	Database           string   `json:"database" yaml:"database" toml:"database"`                                     // database name to connect to
+	SSLMode            string   `json:"sslmode" yaml:"sslmode" toml:"sslmode"`                                        // one of: require, verify-full, verify-ca, disable
+	SSLCert            string   `json:"sslcert" yaml:"sslcert" toml:"sslcert"`                                        // client certificate file
+	SSLKey             string   `json:"sslkey" yaml:"sslkey" toml:"sslkey"`                                           // client key file
+	SSLRootCert        string   `json:"sslrootcert" yaml:"sslrootcert" toml:"sslrootcert"`                            // root certificate file
+	ApplicationName    string   `json:"application_name" yaml:"application_name" toml:"application_name"`             // reported in pg_stat_activity
+	TargetSessionAttrs string   `json:"target_session_attrs" yaml:"target_session_attrs" toml:"target_session_attrs"` // see SetTargetSessionAttrs
+	Options            string   `json:"options" yaml:"options" toml:"options"`                                        // additional connection string options
+
+	SessionParams map[string]string `json:"session_params" yaml:"session_params" toml:"session_params"` // run-time parameters such as search_path, see SetSessionParams
+
+	MaxOpenConns    int      `json:"max_open_conns" yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int      `json:"max_idle_conns" yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime Duration `json:"conn_max_lifetime" yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+	ConnMaxIdleTime Duration `json:"conn_max_idle_time" yaml:"conn_max_idle_time" toml:"conn_max_idle_time"`
+
+	StatementTimeout         Duration `json:"statement_timeout" yaml:"statement_timeout" toml:"statement_timeout"`
+	LockTimeout              Duration `json:"lock_timeout" yaml:"lock_timeout" toml:"lock_timeout"`
+	IdleInTransactionTimeout Duration `json:"idle_in_transaction_timeout" yaml:"idle_in_transaction_timeout" toml:"idle_in_transaction_timeout"`
+
+	StatementDirs []string `json:"statement_dirs" yaml:"statement_dirs" toml:"statement_dirs"` // directories of .sql files prepared on Connect, see PrepareFS
+}
+
+// Duration is a time.Duration which is decoded from and encoded to strings such as "30s".
+type Duration time.Duration
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// LoadConfig reads a json Config from the file at path.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	if !strings.HasSuffix(path, ".json") {
+		return cfg, errors.New("godbm: error LoadConfig only reads json, decode " + path + " into a Config and use NewFromConfig")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	err = json.Unmarshal(data, &cfg)
+	return cfg, err
+}
+
+// NewFromConfig creates a new *SqlStore from cfg. The statement directories are prepared when
+// the store connects.
+func NewFromConfig(cfg Config) *SqlStore {
+	var opts []string
+	for _, o := range [][2]string{
+		{"sslcert", cfg.SSLCert},
+		{"sslkey", cfg.SSLKey},
+		{"sslrootcert", cfg.SSLRootCert},
+	} {
+		if o[1] != "" {
+			opts = append(opts, o[0]+"="+dsnValue(o[1]))
+		}
+	}
+	if cfg.Options != "" {
+		opts = append(opts, cfg.Options)
+	}
+
+	store := NewWithReplicas(cfg.User, cfg.Password, cfg.Database, cfg.Host, cfg.Replicas, cfg.SSLMode, strings.Join(opts, " "))
+	store.SetSessionParams(cfg.SessionParams)
+	if cfg.ApplicationName != "" {
+		store.SetApplicationName(cfg.ApplicationName)
+	}
+	if cfg.TargetSessionAttrs != "" {
+		store.SetTargetSessionAttrs(cfg.TargetSessionAttrs)
+	}
+	store.SetPoolOptions(PoolOptions{
+		MaxOpenConns:    cfg.MaxOpenConns,
+		MaxIdleConns:    cfg.MaxIdleConns,
+		ConnMaxLifetime: time.Duration(cfg.ConnMaxLifetime),
+		ConnMaxIdleTime: time.Duration(cfg.ConnMaxIdleTime),
+	})
+	store.SetTimeouts(Timeouts{
+		Statement:         time.Duration(cfg.StatementTimeout),
+		Lock:              time.Duration(cfg.LockTimeout),
+		IdleInTransaction: time.Duration(cfg.IdleInTransactionTimeout),
+	})
+	store.statementDirs = cfg.StatementDirs
+	return store
+}
+
+// prepareStatementDirs prepares the .sql files in the configured statement directories.
+func (store *SqlStore) prepareStatementDirs() error {
+	for _, dir := range store.statementDirs {
+		if err := store.PrepareFS(os.DirFS(dir)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package godbm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+	data := `{"host": "db1,db2", "user": "app", "database": "orders", "sslmode": "verify-full",
+		"sslrootcert": "/etc/ssl/ca.pem", "max_open_conns": 20, "conn_max_lifetime": "30m",
+		"statement_timeout": "5s", "statement_dirs": ["sql"]}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("error loading config: %v\n", err)
+	}
+
+	dbm := NewFromConfig(cfg)
+	if dbm.pool.MaxOpenConns != 20 || dbm.pool.ConnMaxLifetime != 30*time.Minute {
+		t.Fatalf("unexpected pool options: %+v\n", dbm.pool)
+	}
+	if dbm.timeouts.Statement != 5*time.Second {
+		t.Fatalf("unexpected timeouts: %+v\n", dbm.timeouts)
+	}
+	if len(dbm.statementDirs) != 1 || dbm.statementDirs[0] != "sql" {
+		t.Fatalf("unexpected statement dirs: %v\n", dbm.statementDirs)
+	}
+	if dsn := dbm.dsn(); !strings.Contains(dsn, "sslrootcert=/etc/ssl/ca.pem") || !strings.Contains(dsn, "dbname=orders") {
+		t.Fatalf("unexpected dsn: %s\n", dsn)
+	}
+}
+
+func TestLoadConfigBadDuration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+	if err := os.WriteFile(path, []byte(`{"lock_timeout": "soon"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Fatalf("expected an error for an invalid duration\n")
+	}
+}
+
+func TestLoadConfigNotJSON(t *testing.T) {
+	// the file doesn't exist, so only the extension check can explain the error.
+	_, err := LoadConfig(filepath.Join(t.TempDir(), "db.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "only reads json") {
+		t.Fatalf("expected the extension to be checked before reading got %v\n", err)
+	}
+}
@@ -0,0 +1,165 @@
+package godbm
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"sync/atomic"
+)
+
+// Target session attributes accepted by SetTargetSessionAttrs.
+const (
+	TargetReadWrite = "read-write" // only connect to a host which is not in recovery.
+	TargetAny       = "any"        // connect to the first host which accepts the connection.
+)
+
+// errHostInRecovery is returned when no read-write host could be found.
+var errHostInRecovery = errors.New("godbm: error host is in recovery")
+
+// connector opens connections for one of the store's pools. It tries each of its hosts in turn,
+// starting with the one which last accepted a connection.
+type connector struct {
+	store *SqlStore
+	hosts []string
+	last  atomic.Int32 // index of the host which last accepted a connection
+}
+
+// newConnector creates a connector for a comma separated list of hosts.
+func (store *SqlStore) newConnector(hosts string) *connector {
+	c := &connector{store: store}
+	for _, host := range strings.Split(hosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			c.hosts = append(c.hosts, host)
+		}
+	}
+	return c
+}
+
+// SetTargetSessionAttrs sets which of several comma separated hosts passed to New are acceptable,
+// similar to libpq's target_session_attrs. Defaults to TargetReadWrite so that after a failover
+// new connections go to the promoted standby. Must be called before Connect.
+func (store *SqlStore) SetTargetSessionAttrs(attrs string) {
+	store.targetSession = attrs
+}
+
+// Connect implements driver.Connector.
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	if len(c.hosts) == 0 {
+		return nil, errors.New("godbm: error no hosts to connect to")
+	}
+
+	var err error
+	start := int(c.last.Load())
+	for i := 0; i < len(c.hosts); i++ {
+		idx := (start + i) % len(c.hosts)
+		var conn driver.Conn
+		if conn, err = c.dial(ctx, c.hosts[idx]); err != nil {
+			c.store.onConnectionError(c.hosts[idx], err)
+			continue
+		}
+
+		if len(c.hosts) > 1 && c.store.targetSession != TargetAny {
+			var recovering bool
+			if recovering, err = inRecovery(ctx, conn); err != nil || recovering {
+				conn.Close()
+				if err == nil {
+					err = errHostInRecovery
+				}
+				continue
+			}
+		}
+
+		if err = c.store.onConnect(ctx, c.hosts[idx], conn); err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		if int(c.last.Swap(int32(idx))) != idx {
+			c.store.logEvent(ctx, "godbm: connected to host", nil, slog.String("host", c.hosts[idx]))
+		}
+		return conn, nil
+	}
+	return nil, err
+}
+
+// Driver implements driver.Connector.
+func (c *connector) Driver() driver.Driver {
+	return c.store.sqlDriver()
+}
+
+// dial opens a single connection to host.
+func (c *connector) dial(ctx context.Context, host string) (driver.Conn, error) {
+	user, password, err := c.store.credentialsFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if password, err = c.store.passwordFor(ctx, host, user, password); err != nil {
+		return nil, err
+	}
+	dsn := c.store.dsnWith(host, user, password)
+	if c.store.customDial() {
+		return c.store.openPQ(ctx, dsn)
+	}
+	drv := c.store.sqlDriver()
+	if driverCtx, ok := drv.(driver.DriverContext); ok {
+		conn, err := driverCtx.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return conn.Connect(ctx)
+	}
+	return drv.Open(dsn)
+}
+
+// inRecovery reports whether the server the connection is to is a standby.
+func inRecovery(ctx context.Context, conn driver.Conn) (bool, error) {
+	queryer, ok := conn.(driver.QueryerContext)
+	if !ok {
+		return false, errors.New("godbm: error driver does not support queries")
+	}
+
+	rows, err := queryer.QueryContext(ctx, "select pg_is_in_recovery()", nil)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	dest := make([]driver.Value, 1)
+	if err := rows.Next(dest); err != nil {
+		return false, err
+	}
+	recovering, _ := dest[0].(bool)
+	return recovering, nil
+}
+
+// hostPort splits a host entry into host and port, the port is empty if not given.
+func hostPort(entry string) (host, port string) {
+	if h, p, err := net.SplitHostPort(entry); err == nil {
+		return h, p
+	}
+	return entry, ""
+}
+
+// isReadOnlyError reports whether err was caused by writing to a standby, which happens when
+// pooled connections still point at a demoted primary.
+func isReadOnlyError(err error) bool {
+	return SQLState(err) == "25006" // read_only_sql_transaction
+}
+
+// reresolve drops idle connections so new ones are opened against the current primary.
+func (store *SqlStore) reresolve() {
+	store.logEvent(context.Background(), "godbm: primary is read only, re-resolving hosts", nil)
+	store.db.SetMaxIdleConns(-1)
+	store.db.SetMaxIdleConns(store.idleConns())
+}
+
+// idleConns returns the maximum number of idle connections for the pool.
+func (store *SqlStore) idleConns() int {
+	if store.pool.MaxIdleConns == 0 {
+		return 2 // the database/sql default
+	}
+	return store.pool.MaxIdleConns
+}
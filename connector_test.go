@@ -0,0 +1,22 @@
+package godbm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConnectorHosts(t *testing.T) {
+	dbm := New(username, password, dbname, "db1, db2:5433,,[::1]:5434", "disable", "")
+	c := dbm.newConnector(dbm.host)
+	if len(c.hosts) != 3 || c.hosts[1] != "db2:5433" {
+		t.Fatalf("unexpected hosts: %v\n", c.hosts)
+	}
+
+	if dsn := dbm.dsnFor(c.hosts[1]); !strings.Contains(dsn, "host=db2 ") || !strings.Contains(dsn, "port=5433") {
+		t.Fatalf("expected host and port to be split, got: %s\n", dsn)
+	}
+
+	if dsn := dbm.dsnFor(c.hosts[2]); !strings.Contains(dsn, "host=::1 ") || !strings.Contains(dsn, "port=5434") {
+		t.Fatalf("expected ipv6 host and port to be split, got: %s\n", dsn)
+	}
+}
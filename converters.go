@@ -0,0 +1,92 @@
+package godbm
+
+import (
+	"database/sql/driver"
+	"reflect"
+)
+
+// ValuerFunc converts a value of a registered type into a value the driver can send.
+type ValuerFunc func(v interface{}) (driver.Value, error)
+
+// ScannerFunc scans a column value, nil for NULL, into dest which is a pointer to a value of the
+// registered type.
+type ScannerFunc func(dest interface{}, src interface{}) error
+
+// typeConverters maps registered types to their functions.
+type typeConverters map[reflect.Type]typeConverter
+
+// typeConverter holds the functions registered for a type.
+type typeConverter struct {
+	valuer  ValuerFunc
+	scanner ScannerFunc
+}
+
+// RegisterType teaches the store how to bind and scan t, so domain types such as enums and ids
+// don't each need to implement driver.Valuer and sql.Scanner. Arguments of type t are converted
+// with valuer and pointers to t passed to ForEachRow scans, CountPrepared style helpers and
+// QueryPreparedStructs fields are scanned with scanner. Either function may be nil. Should be
+// called before the store is in use.
+//
+//	store.RegisterType(reflect.TypeOf(Status(0)),
+//		func(v interface{}) (driver.Value, error) { return v.(Status).String(), nil },
+//		func(dest, src interface{}) error { return dest.(*Status).Parse(src) })
+func (store *SqlStore) RegisterType(t reflect.Type, valuer ValuerFunc, scanner ScannerFunc) {
+	if store.converters == nil {
+		store.converters = make(typeConverters)
+	}
+	store.converters[t] = typeConverter{valuer: valuer, scanner: scanner}
+}
+
+// valuerFor returns the registered valuer for the type of arg, if any.
+func (store *SqlStore) valuerFor(arg interface{}) ValuerFunc {
+	if store.converters == nil || arg == nil {
+		return nil
+	}
+	return store.converters[reflect.TypeOf(arg)].valuer
+}
+
+// scannerFor returns the registered scanner for values of type t, if any.
+func (store *SqlStore) scannerFor(t reflect.Type) ScannerFunc {
+	if store.converters == nil {
+		return nil
+	}
+	return store.converters[t].scanner
+}
+
+// scanDest replaces pointers to registered types in dest with scanners calling the registered
+// ScannerFunc.
+func (store *SqlStore) scanDest(dest []interface{}) []interface{} {
+	if store.converters == nil {
+		return dest
+	}
+	var wrapped []interface{}
+	for i, d := range dest {
+		t := reflect.TypeOf(d)
+		if t == nil || t.Kind() != reflect.Ptr {
+			continue
+		}
+		scanner := store.scannerFor(t.Elem())
+		if scanner == nil {
+			continue
+		}
+		if wrapped == nil {
+			wrapped = append([]interface{}(nil), dest...)
+		}
+		wrapped[i] = &funcScanner{scanner: scanner, dest: d}
+	}
+	if wrapped == nil {
+		return dest
+	}
+	return wrapped
+}
+
+// funcScanner adapts a ScannerFunc to sql.Scanner.
+type funcScanner struct {
+	scanner ScannerFunc
+	dest    interface{}
+}
+
+// Scan implements sql.Scanner.
+func (s *funcScanner) Scan(src interface{}) error {
+	return s.scanner(s.dest, src)
+}
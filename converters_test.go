@@ -0,0 +1,91 @@
+package godbm
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type status int
+
+const (
+	statusActive status = iota + 1
+	statusBanned
+)
+
+func registerStatus(dbm *SqlStore) {
+	names := map[status]string{statusActive: "active", statusBanned: "banned"}
+	dbm.RegisterType(reflect.TypeOf(status(0)),
+		func(v interface{}) (driver.Value, error) {
+			return names[v.(status)], nil
+		},
+		func(dest, src interface{}) error {
+			text, _ := asText(src)
+			for s, name := range names {
+				if name == text {
+					*dest.(*status) = s
+					return nil
+				}
+			}
+			return errors.New("unknown status " + text)
+		})
+}
+
+func TestRegisterTypeArgs(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	registerStatus(dbm)
+
+	converted, err := dbm.convertArgs([]interface{}{statusBanned, 1})
+	if err != nil || converted[0] != "banned" || converted[1] != 1 {
+		t.Fatalf("unexpected args: %v %v\n", converted, err)
+	}
+
+	var s status
+	var n int
+	dest := dbm.scanDest([]interface{}{&s, &n})
+	if err := dest[0].(sql.Scanner).Scan([]byte("active")); err != nil || s != statusActive {
+		t.Fatalf("unexpected scan: %v %v\n", s, err)
+	}
+	if dest[1] != &n {
+		t.Fatalf("expected unregistered types to be left alone\n")
+	}
+}
+
+func TestRegisterTypeStructs(t *testing.T) {
+	sql.Register("godbm-status", &rowsDriver{
+		columns: []string{"id", "status"},
+		values:  [][]driver.Value{{int64(1), []byte("banned")}},
+	})
+	db, err := sql.Open("godbm-status", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	dbm := New(username, password, dbname, host, "disable", "")
+	registerStatus(dbm)
+
+	type account struct {
+		ID     int64  `db:"id"`
+		Status status `db:"status"`
+	}
+	rows, err := db.Query("select")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	s, err := newStructScanner(reflect.TypeOf(account{}), []string{"id", "status"}, dbm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var a account
+	if !rows.Next() {
+		t.Fatalf("expected a row\n")
+	}
+	if err := s.scan(rows, reflect.ValueOf(&a).Elem()); err != nil || a.Status != statusBanned {
+		t.Fatalf("unexpected account: %+v %v\n", a, err)
+	}
+}
@@ -0,0 +1,67 @@
+package godbm
+
+import "context"
+
+// CredentialProvider supplies the username and password for new connections, allowing
+// credentials rotated by Vault, AWS Secrets Manager or similar to be picked up without
+// restarting. Implementations should cache credentials as GetCredentials is called for every
+// connection the pools open.
+type CredentialProvider interface {
+	GetCredentials(ctx context.Context) (user, password string, err error)
+}
+
+// CredentialFunc adapts a function to a CredentialProvider.
+type CredentialFunc func(ctx context.Context) (user, password string, err error)
+
+// GetCredentials implements CredentialProvider.
+func (f CredentialFunc) GetCredentials(ctx context.Context) (user, password string, err error) {
+	return f(ctx)
+}
+
+// SetCredentialProvider sets a provider consulted for the username and password every time a
+// connection is opened, replacing those passed to New. Existing connections are unaffected,
+// use PoolOptions.ConnMaxLifetime to have them replaced once credentials rotate. Must be called
+// before Connect.
+func (store *SqlStore) SetCredentialProvider(p CredentialProvider) {
+	store.credentials = p
+}
+
+// credentialsFor returns the username and password to open a new connection with.
+func (store *SqlStore) credentialsFor(ctx context.Context) (user, password string, err error) {
+	if store.credentials == nil {
+		return store.username, store.password, nil
+	}
+	return store.credentials.GetCredentials(ctx)
+}
+
+// AuthTokenGenerator generates a short lived password for user on the given host and port,
+// such as an RDS IAM authentication token. The port is 5432 if the host entry didn't give one.
+type AuthTokenGenerator func(ctx context.Context, host, port, user string) (string, error)
+
+// SetAuthTokenGenerator sets a generator called for the password every time a connection is
+// opened, for IAM style authentication where tokens expire after a few minutes. With
+// aws-sdk-go-v2:
+//
+//	store.SetAuthTokenGenerator(func(ctx context.Context, host, port, user string) (string, error) {
+//		return auth.BuildAuthToken(ctx, host+":"+port, region, user, cfg.Credentials)
+//	})
+//
+// Tokens are only checked when connecting, so existing connections keep working after they
+// expire. RDS requires an sslmode of require or stricter for IAM authentication. Must be
+// called before Connect.
+func (store *SqlStore) SetAuthTokenGenerator(gen AuthTokenGenerator) {
+	store.authToken = gen
+}
+
+// passwordFor returns the password to connect as user to the host entry, generating a token
+// if an AuthTokenGenerator is set.
+func (store *SqlStore) passwordFor(ctx context.Context, entry, user, password string) (string, error) {
+	if store.authToken == nil {
+		return password, nil
+	}
+	host, port := hostPort(entry)
+	if port == "" {
+		port = "5432"
+	}
+	return store.authToken(ctx, host, port, user)
+}
@@ -0,0 +1,62 @@
+package godbm
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// dsnDriver records the connection strings it is asked to open.
+type dsnDriver struct {
+	dsns []string
+}
+
+func (d *dsnDriver) Open(dsn string) (driver.Conn, error) {
+	d.dsns = append(d.dsns, dsn)
+	return nil, errors.New("not connecting")
+}
+
+func TestCredentialProvider(t *testing.T) {
+	drv := &dsnDriver{}
+	dbm := New("static", "static", dbname, host, "disable", "")
+	dbm.SetDriver(drv)
+
+	rotation := 0
+	dbm.SetCredentialProvider(CredentialFunc(func(ctx context.Context) (string, string, error) {
+		rotation++
+		return "app", "secret" + strings.Repeat("!", rotation), nil
+	}))
+
+	c := dbm.newConnector(dbm.host)
+	c.Connect(context.Background())
+	c.Connect(context.Background())
+
+	if len(drv.dsns) != 2 || !strings.Contains(drv.dsns[0], "user=app password=secret! ") || !strings.Contains(drv.dsns[1], "password=secret!! ") {
+		t.Fatalf("expected credentials to be fetched per connection, got: %v\n", drv.dsns)
+	}
+
+	fail := errors.New("vault unavailable")
+	dbm.SetCredentialProvider(CredentialFunc(func(ctx context.Context) (string, string, error) {
+		return "", "", fail
+	}))
+	if _, err := c.Connect(context.Background()); err != fail {
+		t.Fatalf("expected the provider error, got: %v\n", err)
+	}
+}
+
+func TestAuthTokenGenerator(t *testing.T) {
+	drv := &dsnDriver{}
+	dbm := New("iam_user", "", dbname, "db1,db2:5433", "require", "")
+	dbm.SetDriver(drv)
+	dbm.SetTargetSessionAttrs(TargetAny)
+	dbm.SetAuthTokenGenerator(func(ctx context.Context, host, port, user string) (string, error) {
+		return "token-" + host + "-" + port + "-" + user, nil
+	})
+
+	dbm.newConnector(dbm.host).Connect(context.Background())
+	if len(drv.dsns) != 2 || !strings.Contains(drv.dsns[0], "password=token-db1-5432-iam_user ") || !strings.Contains(drv.dsns[1], "password=token-db2-5433-iam_user ") {
+		t.Fatalf("expected a token per host, got: %v\n", drv.dsns)
+	}
+}
@@ -0,0 +1,48 @@
+package godbm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	for _, spec := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "@every -1m", "@sometimes"} {
+		if _, err := parseCron(spec); err == nil {
+			t.Fatalf("expected an error parsing %q\n", spec)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	from := time.Date(2026, 10, 16, 13, 7, 30, 0, time.UTC) // a friday
+	cases := map[string]string{
+		"@hourly":        "2026-10-16 14:00",
+		"*/15 * * * *":   "2026-10-16 13:15",
+		"0 9-17/4 * * *": "2026-10-16 17:00",
+		"30 2 * * 1":     "2026-10-19 02:30",
+		"0 0 1 * *":      "2026-11-01 00:00",
+		"0 0 29 2 *":     "2028-02-29 00:00",
+		"0 12 1 * 0":     "2026-10-18 12:00",
+		"@every 90s":     "2026-10-16 13:09",
+	}
+	for spec, expected := range cases {
+		s, err := parseCron(spec)
+		if err != nil {
+			t.Fatalf("error parsing %q: %v\n", spec, err)
+		}
+		if next := s.next(from).Format("2006-01-02 15:04"); next != expected {
+			t.Fatalf("expected %q to fire at %s, got %s\n", spec, expected, next)
+		}
+	}
+
+	kolkata := time.FixedZone("IST", 5*3600+1800)
+	s, _ := parseCron("0 10 * * *")
+	if next := s.next(from.In(kolkata)); next.Format("2006-01-02 15:04") != "2026-10-17 10:00" {
+		t.Fatalf("expected a half hour offset zone to fire at 10:00 local time, got %v\n", next)
+	}
+
+	s, _ = parseCron("0 0 31 2 *")
+	if !s.next(from).IsZero() {
+		t.Fatalf("expected an impossible schedule to never fire\n")
+	}
+}
@@ -0,0 +1,149 @@
+package godbm
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"sync/atomic"
+)
+
+// cursorSeq numbers cursors so their names are unique within a transaction.
+var cursorSeq atomic.Uint64
+
+// Cursor streams the rows of a query in batches using a server side cursor, see QueryCursor.
+// Like *sql.Rows it is iterated with Next and Scan and must be closed. A Cursor is not safe
+// for concurrent use.
+type Cursor struct {
+	ctx       context.Context
+	tx        *Tx    // the transaction the cursor lives in
+	ownsTx    bool   // the transaction was started for the cursor and ends with it
+	name      string // quoted cursor name
+	fetchSize int
+	rows      *sql.Rows // the current batch
+	fetched   int       // rows read from the current batch
+	done      bool      // the last batch was short, so there are no more rows
+	err       error
+}
+
+// QueryCursor declares a cursor for the statement registered under key and returns a Cursor
+// which fetches its rows fetchSize at a time, 1000 if not positive, so very large results are
+// never held in memory at once. Cursors only exist inside a transaction, so unless ctx carries
+// one from WithTransaction a transaction is started which ends when the Cursor is closed,
+// holding a connection until then.
+func (store *SqlStore) QueryCursor(ctx context.Context, key string, fetchSize int, args ...interface{}) (*Cursor, error) {
+	if !store.Connected {
+		return nil, &ConnectionError{}
+	}
+	if fetchSize <= 0 {
+		fetchSize = 1000
+	}
+	st, err := store.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	if args, err = store.convertArgs(st.markEncrypted(args)); err != nil {
+		return nil, err
+	}
+	if err := st.checkParams(args); err != nil {
+		return nil, err
+	}
+
+	c := &Cursor{
+		ctx:       ctx,
+		name:      QuoteIdentifier("godbm_cursor_" + strconv.FormatUint(cursorSeq.Add(1), 10)),
+		fetchSize: fetchSize,
+	}
+	var ok bool
+	if c.tx, ok = TxFromContext(ctx); !ok {
+		if c.tx, err = store.BeginTx(ctx, nil); err != nil {
+			return nil, err
+		}
+		c.ownsTx = true
+	}
+
+	declare := &statement{key: st.key, query: "DECLARE " + c.name + " NO SCROLL CURSOR FOR " + st.query}
+	err = store.run(ctx, declare, args, func(ctx context.Context) error {
+		_, err := c.tx.ExecContext(ctx, declare.query, args...)
+		return err
+	})
+	if err != nil {
+		if c.ownsTx {
+			c.tx.Rollback()
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// Next prepares the next row for Scan, fetching the next batch from the server when the
+// current one is used up. It returns false when there are no more rows or an error occurred,
+// see Err.
+func (c *Cursor) Next() bool {
+	if c.err != nil {
+		return false
+	}
+	for {
+		if c.rows != nil {
+			if c.rows.Next() {
+				c.fetched++
+				return true
+			}
+			if c.err = c.rows.Err(); c.err != nil {
+				return false
+			}
+			c.rows.Close()
+			c.rows = nil
+			c.done = c.fetched < c.fetchSize
+		}
+		if c.done {
+			return false
+		}
+		c.fetched = 0
+		c.rows, c.err = c.tx.QueryContext(c.ctx, "FETCH FORWARD "+strconv.Itoa(c.fetchSize)+" FROM "+c.name)
+		if c.err != nil {
+			return false
+		}
+	}
+}
+
+// Scan copies the columns of the current row into dest, see sql.Rows.Scan.
+func (c *Cursor) Scan(dest ...interface{}) error {
+	if c.rows == nil {
+		return sql.ErrNoRows
+	}
+	return c.rows.Scan(dest...)
+}
+
+// Columns returns the column names of the current batch.
+func (c *Cursor) Columns() ([]string, error) {
+	if c.rows == nil {
+		return nil, sql.ErrNoRows
+	}
+	return c.rows.Columns()
+}
+
+// Err returns the error, if any, encountered while iterating.
+func (c *Cursor) Err() error {
+	return c.err
+}
+
+// Close closes the cursor, and commits the transaction QueryCursor started if it did.
+func (c *Cursor) Close() error {
+	if c.tx == nil {
+		return nil
+	}
+	if c.rows != nil {
+		c.rows.Close()
+		c.rows = nil
+	}
+	_, err := c.tx.ExecContext(c.ctx, "CLOSE "+c.name)
+	if c.ownsTx {
+		if err != nil {
+			c.tx.Rollback()
+		} else {
+			err = c.tx.Commit()
+		}
+	}
+	c.tx = nil
+	return err
+}
@@ -0,0 +1,102 @@
+package godbm
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+// Numeric holds the exact text of a postgres numeric value, so amounts are never rounded
+// through float64. A NULL column scans into an empty Numeric.
+//
+//	var total godbm.Numeric
+//	err := row.Scan(&total)
+//	amount, _ := decimal.NewFromString(total.String())
+type Numeric string
+
+// Scan implements sql.Scanner.
+func (n *Numeric) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*n = ""
+	case []byte:
+		*n = Numeric(v)
+	case string:
+		*n = Numeric(v)
+	case int64:
+		*n = Numeric(strconv.FormatInt(v, 10))
+	default:
+		return fmt.Errorf("godbm: error cannot scan %T into a Numeric", src)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer, an empty Numeric is sent as NULL.
+func (n Numeric) Value() (driver.Value, error) {
+	if n == "" {
+		return nil, nil
+	}
+	return string(n), nil
+}
+
+// String returns the exact text of the value.
+func (n Numeric) String() string {
+	return string(n)
+}
+
+// Rat returns the value as a big.Rat, ok is false if it is empty, NaN or infinite.
+func (n Numeric) Rat() (r *big.Rat, ok bool) {
+	return new(big.Rat).SetString(string(n))
+}
+
+// Float64 returns the nearest float64 to the value.
+func (n Numeric) Float64() (float64, error) {
+	return strconv.ParseFloat(string(n), 64)
+}
+
+// DecimalFunc converts the text of a numeric column into an application decimal type, for
+// example with shopspring/decimal:
+//
+//	store.SetDecimalType(func(text string) (interface{}, error) {
+//		return decimal.NewFromString(text)
+//	})
+type DecimalFunc func(text string) (interface{}, error)
+
+// SetDecimalType sets how numeric columns are returned by QueryPreparedMaps and Paginate, which
+// otherwise return them as strings. Decimal types implementing driver.Valuer, as most do, can be
+// passed as arguments directly, *big.Int, *big.Float and *big.Rat arguments are sent exactly.
+// Should be called before the store is in use.
+func (store *SqlStore) SetDecimalType(fn DecimalFunc) {
+	store.decimal = fn
+}
+
+// isDecimalArg reports whether arg is a math/big number which should be sent as numeric text.
+func isDecimalArg(arg interface{}) bool {
+	switch v := arg.(type) {
+	case *big.Int:
+		return v != nil
+	case *big.Float:
+		return v != nil
+	case *big.Rat:
+		return v != nil
+	}
+	return false
+}
+
+// decimalArg returns the exact text of a math/big number. Rationals without a finite decimal
+// expansion are rounded to 32 digits after the point.
+func decimalArg(arg interface{}) interface{} {
+	switch v := arg.(type) {
+	case *big.Int:
+		return v.String()
+	case *big.Float:
+		return v.Text('f', -1)
+	case *big.Rat:
+		if digits, exact := v.FloatPrec(); exact {
+			return v.FloatString(digits)
+		}
+		return v.FloatString(32)
+	}
+	return arg
+}
@@ -0,0 +1,41 @@
+package godbm
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestNumeric(t *testing.T) {
+	var n Numeric
+	if err := n.Scan([]byte("12345678901234567890.01")); err != nil || n.String() != "12345678901234567890.01" {
+		t.Fatalf("unexpected numeric: %v %v\n", n, err)
+	}
+	if r, ok := n.Rat(); !ok || r.FloatString(2) != "12345678901234567890.01" {
+		t.Fatalf("unexpected rat: %v\n", r)
+	}
+	if err := n.Scan(nil); err != nil || n != "" {
+		t.Fatalf("expected NULL to scan into an empty numeric: %v %v\n", n, err)
+	}
+	if v, _ := n.Value(); v != nil {
+		t.Fatalf("expected an empty numeric to be NULL: %v\n", v)
+	}
+	if _, ok := Numeric("NaN").Rat(); ok {
+		t.Fatalf("expected NaN not to convert to a rat\n")
+	}
+}
+
+func TestDecimalArgs(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	third := big.NewRat(1, 3)
+	args := []interface{}{big.NewRat(1, 8), third, big.NewFloat(0.5), new(big.Int).Lsh(big.NewInt(1), 70)}
+	converted, err := dbm.convertArgs(args)
+	if err != nil {
+		t.Fatalf("error converting args: %v\n", err)
+	}
+	expected := []string{"0.125", "0.33333333333333333333333333333333", "0.5", "1180591620717411303424"}
+	for i, want := range expected {
+		if converted[i] != want {
+			t.Fatalf("arg %d: expected %s got %v\n", i, want, converted[i])
+		}
+	}
+}
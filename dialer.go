@@ -0,0 +1,124 @@
+package godbm
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql/driver"
+	"encoding/binary"
+	"errors"
+	"github.com/lib/pq"
+	"io"
+	"net"
+	"time"
+)
+
+// errSSLRefused is returned when the server won't negotiate TLS with a custom TLS config.
+var errSSLRefused = errors.New("godbm: error server does not support ssl")
+
+// Dialer opens the network connections to the database. *net.Dialer and proxy dialers such as
+// golang.org/x/net/proxy's satisfy it, as can a dialer tunnelling through an SSH bastion.
+type Dialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// SetDialer sets the dialer used to open connections, for reaching the database through a
+// proxy or tunnel. Only used with lib/pq, configure other drivers directly. Must be called
+// before Connect.
+func (store *SqlStore) SetDialer(d Dialer) {
+	store.dialer = d
+}
+
+// SetTLSConfig sets the TLS configuration used for connections in place of the sslmode, sslcert,
+// sslkey and sslrootcert settings, for client certificates held in memory, custom verification
+// or SNI. If ServerName is empty it is set to the host being connected to. Only used with
+// lib/pq, configure other drivers directly. Must be called before Connect.
+func (store *SqlStore) SetTLSConfig(cfg *tls.Config) {
+	store.tlsConfig = cfg
+}
+
+// customDial reports whether connections are opened with our own dialer.
+func (store *SqlStore) customDial() bool {
+	return (store.dialer != nil || store.tlsConfig != nil) && store.isPQ()
+}
+
+// openPQ opens a lib/pq connection using our dialer and TLS configuration.
+func (store *SqlStore) openPQ(ctx context.Context, dsn string) (driver.Conn, error) {
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	connector.Dialer(&pqDialer{store: store})
+	return connector.Connect(ctx)
+}
+
+// pqDialer adapts the store's dialer and TLS configuration to lib/pq's dialer interfaces.
+type pqDialer struct {
+	store *SqlStore
+}
+
+// Dial implements pq.Dialer.
+func (d *pqDialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// DialTimeout implements pq.Dialer.
+func (d *pqDialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return d.DialContext(ctx, network, address)
+}
+
+// DialContext implements pq.DialerContext, negotiating TLS itself when a TLS config is set.
+// lib/pq is then told sslmode=disable so it doesn't negotiate again.
+func (d *pqDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	var dialer Dialer = &net.Dialer{}
+	if d.store.dialer != nil {
+		dialer = d.store.dialer
+	}
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil || d.store.tlsConfig == nil {
+		return conn, err
+	}
+
+	cfg := d.store.tlsConfig
+	if cfg.ServerName == "" {
+		cfg = cfg.Clone()
+		cfg.ServerName, _ = hostPort(address)
+	}
+	tlsConn, err := startTLS(ctx, conn, cfg)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// startTLS sends a postgres SSLRequest over conn and performs the TLS handshake if the server
+// accepts it.
+func startTLS(ctx context.Context, conn net.Conn, cfg *tls.Config) (net.Conn, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	request := make([]byte, 8)
+	binary.BigEndian.PutUint32(request[0:4], 8)
+	binary.BigEndian.PutUint32(request[4:8], 80877103) // SSLRequest code
+	if _, err := conn.Write(request); err != nil {
+		return nil, err
+	}
+
+	reply := make([]byte, 1)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil, err
+	}
+	if reply[0] != 'S' {
+		return nil, errSSLRefused
+	}
+
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return nil, err
+	}
+	return tlsConn, nil
+}
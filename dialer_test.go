@@ -0,0 +1,60 @@
+package godbm
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// countingDialer counts the connections it opens.
+type countingDialer struct {
+	dials int
+}
+
+func (d *countingDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	d.dials++
+	return (&net.Dialer{}).DialContext(ctx, network, address)
+}
+
+func TestDialerTLSRefused(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	requests := make(chan uint32, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 8)
+		if _, err := io.ReadFull(conn, buf); err == nil {
+			requests <- binary.BigEndian.Uint32(buf[4:])
+		}
+		conn.Write([]byte("N"))
+	}()
+
+	dbm := New(username, password, dbname, ln.Addr().String(), "verify-full", "")
+	d := &countingDialer{}
+	dbm.SetDialer(d)
+	dbm.SetTLSConfig(&tls.Config{})
+
+	if dsn := dbm.dsn(); !strings.Contains(dsn, "sslmode=disable") {
+		t.Fatalf("expected lib/pq to leave tls to us, got: %s\n", dsn)
+	}
+
+	_, err = dbm.newConnector(dbm.host).Connect(context.Background())
+	if err != errSSLRefused {
+		t.Fatalf("expected the server to refuse ssl, got: %v\n", err)
+	}
+	if d.dials != 1 || <-requests != 80877103 {
+		t.Fatalf("expected an SSLRequest through the custom dialer\n")
+	}
+}
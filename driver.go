@@ -0,0 +1,50 @@
+package godbm
+
+import (
+	"database/sql/driver"
+	"errors"
+	"github.com/lib/pq"
+)
+
+// ErrCopyUnsupported is returned by CopyStart when the store is not using lib/pq.
+var ErrCopyUnsupported = errors.New("godbm: error copy in is only supported by the lib/pq driver")
+
+// SetDriver sets the database/sql driver used to open connections, defaulting to lib/pq. Any
+// driver which accepts libpq style key=value connection strings can be used, for example pgx's
+// stdlib adapter:
+//
+//	store.SetDriver(stdlib.GetDefaultDriver())
+//
+// CopyStart relies on lib/pq's COPY support and returns ErrCopyUnsupported with other drivers.
+// Must be called before Connect.
+func (store *SqlStore) SetDriver(drv driver.Driver) {
+	store.driver = drv
+}
+
+// sqlDriver returns the driver used to open connections.
+func (store *SqlStore) sqlDriver() driver.Driver {
+	if store.driver == nil {
+		return &pq.Driver{}
+	}
+	return store.driver
+}
+
+// isPQ reports whether connections are opened with lib/pq.
+func (store *SqlStore) isPQ() bool {
+	switch store.sqlDriver().(type) {
+	case *pq.Driver, pq.Driver:
+		return true
+	}
+	return false
+}
+
+// SQLState returns the five character SQLSTATE code of a postgres error, or an empty string if
+// err doesn't carry one. Works with any driver whose errors expose a SQLState method, such as
+// lib/pq and pgx.
+func SQLState(err error) string {
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) {
+		return stateErr.SQLState()
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+package godbm
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"testing"
+)
+
+func TestSetDriver(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	if !dbm.isPQ() {
+		t.Fatalf("expected lib/pq to be the default driver\n")
+	}
+	dbm.SetDriver(&pq.Driver{})
+	if !dbm.isPQ() {
+		t.Fatalf("expected an explicit lib/pq driver to be recognized\n")
+	}
+
+	drv := &dsnDriver{}
+	dbm.SetDriver(drv)
+	if dbm.isPQ() || dbm.sqlDriver() != drv {
+		t.Fatalf("expected the driver set to be used\n")
+	}
+
+	dbm.Connected = true
+	if _, _, err := dbm.CopyStart("test", "val1"); err != ErrCopyUnsupported {
+		t.Fatalf("expected ErrCopyUnsupported got %v\n", err)
+	}
+	if len(drv.dsns) != 0 {
+		t.Fatalf("expected no connection to be opened for an unsupported copy\n")
+	}
+}
+
+func TestSQLState(t *testing.T) {
+	cases := map[error]string{
+		&pq.Error{Code: "23505"}:                              "23505",
+		fmt.Errorf("inserting: %w", &pq.Error{Code: "40001"}): "40001",
+		errors.New("not a postgres error"):                    "",
+		nil:                                                   "",
+	}
+	for err, expected := range cases {
+		if state := SQLState(err); state != expected {
+			t.Fatalf("expected %q for %v got %q\n", expected, err, state)
+		}
+	}
+}
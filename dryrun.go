@@ -0,0 +1,66 @@
+package godbm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+)
+
+// DryRunMode controls what ExecPrepared does in a dry run, see SetDryRun.
+type DryRunMode int
+
+const (
+	DryRunOff      DryRunMode = iota // statements are executed as normal.
+	DryRunValidate                   // statements are checked and logged but never sent to the server.
+	DryRunRollback                   // statements are executed in a transaction which is rolled back.
+)
+
+// errDryRun rolls back the transaction a statement runs in under DryRunRollback.
+var errDryRun = errors.New("godbm: error dry run")
+
+// SetDryRun sets a dry run mode for ExecPrepared, ad-hoc Exec and their variants, for --dry-run
+// flags in tools built on the store. It covers the helpers which write through them too, such
+// as DeleteInBatches, which stops after its first batch, RunRetention, DropPartitionsOlderThan,
+// schedules and Blobs. With DryRunValidate the statement and its arguments are checked as
+// usual, the call is logged at the event level, see SetLogger, and a result with no rows
+// affected is returned without contacting the server. With DryRunRollback each call runs in a
+// transaction, or a savepoint of the one in ctx, which is rolled back afterwards, so the server
+// checks the statement against the data and the real result is returned, but later calls don't
+// see its changes. Queries, including data modifying ones, and statements run directly on a
+// Tx or Db are unaffected. This should be called before the store is in use.
+func (store *SqlStore) SetDryRun(mode DryRunMode) {
+	store.dryRun = mode
+}
+
+// execRolledBack calls exec in a transaction, or a savepoint of the one in ctx, which is rolled
+// back afterwards.
+func (store *SqlStore) execRolledBack(ctx context.Context, exec func(ctx context.Context) (sql.Result, error)) (result sql.Result, err error) {
+	err = store.WithTransaction(ctx, func(ctx context.Context, tx *Tx) (err error) {
+		if result, err = exec(ctx); err == nil {
+			err = errDryRun
+		}
+		return err
+	})
+	switch {
+	case err == errDryRun:
+		return result, nil
+	case errors.Is(err, errDryRun):
+		// rolling back to the savepoint failed, which is the error to report.
+		return nil, errors.New("godbm: error dry run not rolled back" + strings.TrimPrefix(err.Error(), errDryRun.Error()))
+	}
+	return result, err
+}
+
+// dryRunResult is the result of a statement under DryRunValidate.
+type dryRunResult struct{}
+
+func (dryRunResult) LastInsertId() (int64, error) { return 0, nil }
+func (dryRunResult) RowsAffected() (int64, error) { return 0, nil }
+
+// logDryRun logs the statement a call would have executed.
+func (store *SqlStore) logDryRun(ctx context.Context, st *statement, args []interface{}) {
+	store.logEvent(ctx, "godbm: dry run", nil, slog.String("key", st.key), slog.String("query", st.query),
+		slog.Any("args", store.logArgs(store.redact(st, args))))
+}
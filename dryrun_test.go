@@ -0,0 +1,73 @@
+package godbm
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestDryRunValidate(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	var buf bytes.Buffer
+	dbm.SetLogger(slog.New(slog.NewTextHandler(&buf, nil)), nil)
+	dbm.SetDryRun(DryRunValidate)
+	dbm.Connected = true
+	dbm.queries = map[string]*statement{
+		"delete": {key: "delete", query: "delete from users where id = $1", params: 1},
+	}
+
+	result, err := dbm.ExecPrepared("delete", 1)
+	if err != nil {
+		t.Fatalf("expected the dry run to succeed without a database: %v\n", err)
+	}
+	if n, err := result.RowsAffected(); n != 0 || err != nil {
+		t.Fatalf("expected no rows affected got %d %v\n", n, err)
+	}
+	if out := buf.String(); !strings.Contains(out, "godbm: dry run") || !strings.Contains(out, "key=delete") {
+		t.Fatalf("expected the call to be logged got: %s\n", out)
+	}
+
+	if _, err := dbm.ExecPrepared("delete"); err == nil {
+		t.Fatalf("expected the argument count to still be checked\n")
+	}
+	if _, err := dbm.ExecPrepared("missing", 1); err == nil {
+		t.Fatalf("expected an unknown statement to still be an error\n")
+	}
+}
+
+func TestDryRunAdhocExec(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	var buf bytes.Buffer
+	dbm.SetLogger(slog.New(slog.NewTextHandler(&buf, nil)), nil)
+	dbm.SetDryRun(DryRunValidate)
+	dbm.Connected = true
+
+	if _, err := dbm.Exec("drop table events"); err != nil {
+		t.Fatalf("expected the dry run to succeed without a database: %v\n", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "godbm: dry run") || !strings.Contains(out, "drop table events") {
+		t.Fatalf("expected the ad-hoc call to be logged got: %s\n", out)
+	}
+}
+
+func TestDryRunBatches(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.SetDryRun(DryRunRollback)
+	calls := 0
+	total, err := dbm.inBatches(context.Background(), 0, nil, func() (sql.Result, error) {
+		calls++
+		return rowsAffected(5), nil
+	})
+	if err != nil || calls != 1 || total != 5 {
+		t.Fatalf("expected a dry run to stop after one batch got %d calls %d rows %v\n", calls, total, err)
+	}
+}
+
+// rowsAffected is a sql.Result affecting a fixed number of rows.
+type rowsAffected int64
+
+func (r rowsAffected) LastInsertId() (int64, error) { return 0, nil }
+func (r rowsAffected) RowsAffected() (int64, error) { return int64(r), nil }
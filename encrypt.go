@@ -0,0 +1,143 @@
+package godbm
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+)
+
+// ErrNoEncryption is returned when a value marked for encryption is used without a codec set
+// with SetEncryption.
+var ErrNoEncryption = errors.New("godbm: error encrypted values require an encryption codec")
+
+// EncryptionCodec encrypts values before they are sent to the database and decrypts them when
+// read back. column identifies the value, and can be used as associated data. Use a
+// deterministic scheme such as AES-SIV to be able to look rows up by an encrypted column.
+type EncryptionCodec interface {
+	Encrypt(column string, plaintext []byte) ([]byte, error)
+	Decrypt(column string, ciphertext []byte) ([]byte, error)
+}
+
+// SetEncryption sets the codec used for parameters marked with Encrypted and struct fields
+// tagged encrypted. Encrypted values are stored as bytea. Should be called before the store is
+// in use.
+func (store *SqlStore) SetEncryption(codec EncryptionCodec) {
+	store.codec = codec
+}
+
+// Encrypted marks the statement's parameters at the given 1 based positions as holding values
+// of column, which are encrypted before being sent. Arguments must be strings, []byte or nil.
+func Encrypted(column string, params ...int) StmtOption {
+	return func(o *stmtOptions) {
+		if o.encrypted == nil {
+			o.encrypted = make(map[int]string, len(params))
+		}
+		for _, p := range params {
+			o.encrypted[p] = column
+		}
+	}
+}
+
+// Decrypted returns a scanner which decrypts a column encrypted with the store's codec into
+// dest, which must be a *string or *[]byte. NULL scans into an empty value.
+//
+//	err := rows.Scan(&id, store.Decrypted("email", &email))
+func (store *SqlStore) Decrypted(column string, dest interface{}) sql.Scanner {
+	return &funcScanner{scanner: store.decryptFunc(column), dest: dest}
+}
+
+// encryptedArg marks an argument to be encrypted by convertArgs.
+type encryptedArg struct {
+	column string
+	value  interface{}
+}
+
+// markEncrypted wraps the arguments of st's encrypted parameters.
+func (st *statement) markEncrypted(args []interface{}) []interface{} {
+	if len(st.opts.encrypted) == 0 {
+		return args
+	}
+	marked := append([]interface{}(nil), args...)
+	for i := range marked {
+		if column, ok := st.opts.encrypted[i+1]; ok {
+			marked[i] = encryptedArg{column: column, value: marked[i]}
+		}
+	}
+	return marked
+}
+
+// encrypt returns the ciphertext of arg, or nil if its value is nil.
+func (store *SqlStore) encrypt(arg encryptedArg) (interface{}, error) {
+	if store.codec == nil {
+		return nil, ErrNoEncryption
+	}
+	value := arg.value
+	if valuer, ok := value.(driver.Valuer); ok {
+		var err error
+		if value, err = valuer.Value(); err != nil {
+			return nil, err
+		}
+	}
+
+	var plaintext []byte
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case *string:
+		if v == nil {
+			return nil, nil
+		}
+		plaintext = []byte(*v)
+	case string:
+		plaintext = []byte(v)
+	case []byte:
+		plaintext = v
+	default:
+		return nil, fmt.Errorf("godbm: error cannot encrypt %T for column %s", value, arg.column)
+	}
+	return store.codec.Encrypt(arg.column, plaintext)
+}
+
+// decryptFunc returns a ScannerFunc decrypting column into a *string or *[]byte.
+func (store *SqlStore) decryptFunc(column string) ScannerFunc {
+	return func(dest, src interface{}) error {
+		if store.codec == nil {
+			return ErrNoEncryption
+		}
+		var plaintext []byte
+		if src != nil {
+			ciphertext, ok := src.([]byte)
+			if !ok {
+				return fmt.Errorf("godbm: error cannot decrypt %T for column %s", src, column)
+			}
+			var err error
+			if plaintext, err = store.codec.Decrypt(column, ciphertext); err != nil {
+				return err
+			}
+		}
+
+		switch d := dest.(type) {
+		case *string:
+			*d = string(plaintext)
+		case *[]byte:
+			*d = plaintext
+		case **string:
+			if src == nil {
+				*d = nil
+			} else {
+				s := string(plaintext)
+				*d = &s
+			}
+		default:
+			return fmt.Errorf("godbm: error cannot decrypt column %s into %T", column, dest)
+		}
+		return nil
+	}
+}
+
+// isEncryptedArg reports whether arg was marked for encryption.
+func isEncryptedArg(arg interface{}) bool {
+	_, ok := arg.(encryptedArg)
+	return ok
+}
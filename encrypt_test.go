@@ -0,0 +1,92 @@
+package godbm
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// reverseCodec "encrypts" by prefixing the column and reversing the bytes.
+type reverseCodec struct{}
+
+func (reverseCodec) Encrypt(column string, plaintext []byte) ([]byte, error) {
+	out := []byte(column + ":")
+	for i := len(plaintext) - 1; i >= 0; i-- {
+		out = append(out, plaintext[i])
+	}
+	return out, nil
+}
+
+func (c reverseCodec) Decrypt(column string, ciphertext []byte) ([]byte, error) {
+	if !bytes.HasPrefix(ciphertext, []byte(column+":")) {
+		return nil, errors.New("wrong column")
+	}
+	ciphertext = ciphertext[len(column)+1:]
+	out := make([]byte, 0, len(ciphertext))
+	for i := len(ciphertext) - 1; i >= 0; i-- {
+		out = append(out, ciphertext[i])
+	}
+	return out, nil
+}
+
+func TestEncryptedParams(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	st := &statement{}
+	Encrypted("email", 2)(&st.opts)
+
+	args := st.markEncrypted([]interface{}{1, "a@b.c", nil})
+	if _, err := dbm.convertArgs(args); err != ErrNoEncryption {
+		t.Fatalf("expected ErrNoEncryption got %v\n", err)
+	}
+
+	dbm.SetEncryption(reverseCodec{})
+	converted, err := dbm.convertArgs(args)
+	if err != nil {
+		t.Fatalf("error converting args: %v\n", err)
+	}
+	if converted[0] != 1 || string(converted[1].([]byte)) != "email:c.b@a" || converted[2] != nil {
+		t.Fatalf("unexpected args: %v\n", converted)
+	}
+	if _, err := dbm.convertArgs(st.markEncrypted([]interface{}{1, 2})); err == nil {
+		t.Fatalf("expected an error encrypting an int\n")
+	}
+}
+
+func TestDecrypted(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.SetEncryption(reverseCodec{})
+
+	var email string
+	if err := dbm.Decrypted("email", &email).Scan([]byte("email:c.b@a")); err != nil || email != "a@b.c" {
+		t.Fatalf("unexpected decrypt: %v %v\n", email, err)
+	}
+	if err := dbm.Decrypted("phone", &email).Scan([]byte("email:c.b@a")); err == nil {
+		t.Fatalf("expected an error decrypting with the wrong column\n")
+	}
+
+	nick := &email
+	if err := dbm.Decrypted("nick", &nick).Scan(nil); err != nil || nick != nil {
+		t.Fatalf("expected NULL to scan into a nil pointer: %v %v\n", nick, err)
+	}
+}
+
+func TestEncryptedStructFields(t *testing.T) {
+	type customer struct {
+		ID    int64  `db:"id,omitempty"`
+		Email string `db:"email,omitempty,encrypted"`
+	}
+	c := &customer{ID: 1}
+	b, _, err := updateStruct("customers", c, []string{"email"})
+	if err != nil {
+		t.Fatalf("error building update: %v\n", err)
+	}
+	_, args := b.Build()
+	if arg, ok := args[len(args)-1].(encryptedArg); !ok || arg.column != "email" {
+		t.Fatalf("expected the where argument to be encrypted: %v\n", args)
+	}
+
+	if _, err := newStructScanner(reflect.TypeOf(*c), []string{"id", "email"}, nil); err != ErrNoEncryption {
+		t.Fatalf("expected ErrNoEncryption scanning without a store got %v\n", err)
+	}
+}
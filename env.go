@@ -0,0 +1,90 @@
+package godbm
+
+import (
+	"net"
+	"os"
+	"os/user"
+	"strings"
+)
+
+// envOptions maps libpq environment variables to the connection settings they control, see
+// https://www.postgresql.org/docs/current/libpq-envars.html
+var envOptions = []struct {
+	env, key string
+}{
+	{"PGAPPNAME", "application_name"},
+	{"PGCONNECT_TIMEOUT", "connect_timeout"},
+	{"PGSSLCERT", "sslcert"},
+	{"PGSSLKEY", "sslkey"},
+	{"PGSSLROOTCERT", "sslrootcert"},
+	{"PGTZ", "timezone"},
+	{"PGDATESTYLE", "datestyle"},
+	{"PGCLIENTENCODING", "client_encoding"},
+}
+
+// NewFromEnv creates a new *SqlStore from the standard libpq environment variables: PGHOST,
+// PGPORT, PGUSER, PGPASSWORD, PGDATABASE and PGSSLMODE, along with PGAPPNAME,
+// PGCONNECT_TIMEOUT, PGSSLCERT, PGSSLKEY, PGSSLROOTCERT, PGTZ, PGDATESTYLE and
+// PGCLIENTENCODING. As with libpq, PGHOST and PGPORT may be comma separated lists, a single
+// port applies to every host. Unset variables fall back to the libpq defaults, localhost, 5432,
+// the current OS user as the user and the user as the database name.
+func NewFromEnv() *SqlStore {
+	username := os.Getenv("PGUSER")
+	if username == "" {
+		if u, err := user.Current(); err == nil {
+			username = u.Username
+		}
+	}
+	dbname := os.Getenv("PGDATABASE")
+	if dbname == "" {
+		dbname = username
+	}
+	sslmode := os.Getenv("PGSSLMODE")
+	if sslmode == "" {
+		sslmode = "prefer"
+	}
+
+	var opts []string
+	for _, o := range envOptions {
+		if v := os.Getenv(o.env); v != "" {
+			opts = append(opts, o.key+"="+dsnValue(v))
+		}
+	}
+
+	host := envHosts(os.Getenv("PGHOST"), os.Getenv("PGPORT"))
+	return New(username, os.Getenv("PGPASSWORD"), dbname, host, sslmode, strings.Join(opts, " "))
+}
+
+// envHosts joins the PGHOST and PGPORT lists into the host:port list New expects.
+func envHosts(hosts, ports string) string {
+	if hosts == "" {
+		hosts = "localhost"
+	}
+	if ports == "" {
+		return hosts
+	}
+
+	hostList := strings.Split(hosts, ",")
+	portList := strings.Split(ports, ",")
+	for i, h := range hostList {
+		port := portList[0]
+		if len(portList) == len(hostList) {
+			port = portList[i]
+		}
+		h, port = strings.TrimSpace(h), strings.TrimSpace(port)
+		if port != "" {
+			h = net.JoinHostPort(h, port)
+		}
+		hostList[i] = h
+	}
+	return strings.Join(hostList, ",")
+}
+
+// dsnValue quotes v for use in a key=value connection string if it is empty or contains spaces,
+// quotes or backslashes.
+func dsnValue(v string) string {
+	if v != "" && !strings.ContainsAny(v, " '\\") {
+		return v
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
@@ -0,0 +1,37 @@
+package godbm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewFromEnv(t *testing.T) {
+	t.Setenv("PGHOST", "db1,db2")
+	t.Setenv("PGPORT", "5433")
+	t.Setenv("PGUSER", "app")
+	t.Setenv("PGPASSWORD", "it's secret")
+	t.Setenv("PGDATABASE", "")
+	t.Setenv("PGSSLMODE", "verify-full")
+	t.Setenv("PGAPPNAME", "worker")
+
+	dbm := NewFromEnv()
+	if dbm.host != "db1:5433,db2:5433" {
+		t.Fatalf("expected port to apply to every host, got: %s\n", dbm.host)
+	}
+
+	dsn := dbm.dsn()
+	for _, want := range []string{"user=app ", `password='it\'s secret' `, "dbname=app ", "host=db1 ", "port=5433", "sslmode=verify-full", "application_name=worker"} {
+		if !strings.Contains(dsn, want) {
+			t.Fatalf("expected %q in dsn: %s\n", want, dsn)
+		}
+	}
+}
+
+func TestEnvHosts(t *testing.T) {
+	if h := envHosts("", ""); h != "localhost" {
+		t.Fatalf("expected localhost default, got: %s\n", h)
+	}
+	if h := envHosts("a,b", "1,2"); h != "a:1,b:2" {
+		t.Fatalf("expected ports paired with hosts, got: %s\n", h)
+	}
+}
@@ -0,0 +1,228 @@
+// Package eventstore is an append-only event store kept in a postgres table through godbm,
+// with optimistic concurrency on streams and catch-up subscriptions fed by LISTEN/NOTIFY.
+package eventstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/wirepair/godbm"
+	"time"
+)
+
+// ErrWrongExpectedVersion is returned by AppendEvents when the stream's version isn't the one
+// expected, meaning another writer appended to it first.
+var ErrWrongExpectedVersion = errors.New("godbm: error stream is not at the expected version")
+
+const (
+	AnyVersion int64 = -1 // append regardless of the stream's version.
+	NoStream   int64 = 0  // the stream must not have any events yet.
+)
+
+// NewEvent is an event to append.
+type NewEvent struct {
+	Type     string
+	Data     json.RawMessage
+	Metadata json.RawMessage // optional.
+}
+
+// Event is a stored event.
+type Event struct {
+	Position int64 // position in the store across every stream, gapless in commit order.
+	Stream   string
+	Version  int64 // position in the stream, starting at 1.
+	Type     string
+	Data     json.RawMessage
+	Metadata json.RawMessage // nil if none was stored.
+	Created  time.Time
+}
+
+// Store keeps events in a single table.
+type Store struct {
+	db      *godbm.SqlStore
+	table   string
+	channel string
+}
+
+// New creates a Store keeping events in table, which may be schema qualified, see CreateTable.
+func New(db *godbm.SqlStore, table string) *Store {
+	return &Store{db: db, table: table, channel: "godbm_events_" + table}
+}
+
+// CreateTable creates the events table unless it exists.
+func (s *Store) CreateTable(ctx context.Context) error {
+	query, err := godbm.Format("create table if not exists %I (position bigint primary key, stream text not null, "+
+		"version bigint not null, type text not null, data jsonb not null, metadata jsonb, "+
+		"created timestamptz not null default now(), unique (stream, version))", s.table)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, query)
+	return err
+}
+
+// AppendEvents appends events to stream and returns the stream's new version. Unless
+// expectedVersion is AnyVersion, it must be the stream's current version, or NoStream for a new
+// stream, otherwise ErrWrongExpectedVersion is returned and nothing is appended.
+//
+// Appends take a store wide lock for the rest of the transaction so positions are handed out in
+// commit order and subscribers never skip an event committed late, which limits append
+// throughput to one transaction at a time. If ctx carries a transaction the events are
+// appended within it.
+func (s *Store) AppendEvents(ctx context.Context, stream string, expectedVersion int64, events ...NewEvent) (version int64, err error) {
+	if len(events) == 0 {
+		return 0, errors.New("godbm: error no events to append")
+	}
+	current, err := godbm.Format("select (select coalesce(max(position), 0) from %I), "+
+		"(select coalesce(max(version), 0) from %I where stream = $1)", s.table, s.table)
+	if err != nil {
+		return 0, err
+	}
+	insert, err := godbm.Format("insert into %I (position, stream, version, type, data, metadata) values ($1, $2, $3, $4, $5, $6)", s.table)
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.db.WithTransaction(ctx, func(ctx context.Context, tx *godbm.Tx) error {
+		// the lock is taken by its own statement so the next one's snapshot sees every earlier append.
+		if _, err := s.db.ExecContext(ctx, "select pg_advisory_xact_lock(hashtext($1))", s.table); err != nil {
+			return err
+		}
+		var position int64
+		rows, err := s.db.QueryContext(ctx, current, stream)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			err = rows.Scan(&position, &version)
+		}
+		if cerr := rows.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+		if expectedVersion != AnyVersion && expectedVersion != version {
+			return ErrWrongExpectedVersion
+		}
+
+		for _, event := range events {
+			position++
+			version++
+			var metadata interface{}
+			if event.Metadata != nil {
+				metadata = string(event.Metadata)
+			}
+			if _, err := s.db.ExecContext(ctx, insert, position, stream, version, event.Type, string(event.Data), metadata); err != nil {
+				return err
+			}
+		}
+		// notifications are only delivered if the transaction commits.
+		return s.db.Notify(ctx, s.channel, stream)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// ReadStream returns up to limit events of stream starting at version from, in order.
+func (s *Store) ReadStream(ctx context.Context, stream string, from int64, limit int) ([]Event, error) {
+	query, err := godbm.Format("select position, stream, version, type, data::text, metadata::text, created from %I "+
+		"where stream = $1 and version >= $2 order by version limit $3", s.table)
+	if err != nil {
+		return nil, err
+	}
+	return s.read(ctx, query, stream, from, limit)
+}
+
+// ReadAll returns up to limit events of every stream after position, in order.
+func (s *Store) ReadAll(ctx context.Context, after int64, limit int) ([]Event, error) {
+	query, err := godbm.Format("select position, stream, version, type, data::text, metadata::text, created from %I "+
+		"where position > $1 order by position limit $2", s.table)
+	if err != nil {
+		return nil, err
+	}
+	return s.read(ctx, query, after, limit)
+}
+
+// read runs a query returning events.
+func (s *Store) read(ctx context.Context, query string, args ...interface{}) ([]Event, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var e Event
+		var data string
+		var metadata *string
+		if err := rows.Scan(&e.Position, &e.Stream, &e.Version, &e.Type, &data, &metadata, &e.Created); err != nil {
+			return nil, err
+		}
+		e.Data = json.RawMessage(data)
+		if metadata != nil {
+			e.Metadata = json.RawMessage(*metadata)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// subscribeBatch is how many events a subscription reads at a time.
+const subscribeBatch = 500
+
+// Subscribe calls fn for every event after position in order, first catching up with those
+// already stored and then as new events are appended, until ctx is done or fn returns an
+// error. Store the position of each handled event to resume from it later. New events are
+// noticed through LISTEN/NOTIFY, with a poll every pollInterval, defaulting to a minute, in case
+// a notification is missed.
+func (s *Store) Subscribe(ctx context.Context, after int64, pollInterval time.Duration, fn func(ctx context.Context, e Event) error) error {
+	if pollInterval <= 0 {
+		pollInterval = time.Minute
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	wake := make(chan struct{}, 1)
+	listening := make(chan error, 1)
+	go func() {
+		listening <- s.db.Listen(ctx, func(n godbm.Notification) {
+			select {
+			case wake <- struct{}{}:
+			default:
+			}
+		}, s.channel)
+	}()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		for {
+			events, err := s.ReadAll(ctx, after, subscribeBatch)
+			if err != nil {
+				return err
+			}
+			for _, e := range events {
+				if err := fn(ctx, e); err != nil {
+					return err
+				}
+				after = e.Position
+			}
+			if len(events) < subscribeBatch {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-listening:
+			return err
+		case <-wake:
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package eventstore
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/wirepair/godbm"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	s := New(nil, "events")
+	if s.table != "events" || s.channel != "godbm_events_events" {
+		t.Fatalf("unexpected store: %+v\n", s)
+	}
+}
+
+func TestAppendNothing(t *testing.T) {
+	if _, err := New(nil, "events").AppendEvents(context.Background(), "order-1", NoStream); err == nil {
+		t.Fatalf("expected an error appending no events\n")
+	}
+}
+
+func TestAppendAndRead(t *testing.T) {
+	db := godbm.New("postgres", "testpass", "godbm_test", "127.0.0.1", "disable", "")
+	if err := db.Connect(); err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer db.Disconnect()
+	defer db.Exec("drop table events_test")
+
+	ctx := context.Background()
+	s := New(db, "events_test")
+	if err := s.CreateTable(ctx); err != nil {
+		t.Fatalf("error creating table: %v\n", err)
+	}
+
+	version, err := s.AppendEvents(ctx, "order-1", NoStream, NewEvent{Type: "created", Data: json.RawMessage(`{"total":10}`)}, NewEvent{Type: "paid", Data: json.RawMessage(`{}`)})
+	if err != nil || version != 2 {
+		t.Fatalf("error appending: %v %v\n", version, err)
+	}
+	if _, err := s.AppendEvents(ctx, "order-1", 1, NewEvent{Type: "shipped", Data: json.RawMessage(`{}`)}); err != ErrWrongExpectedVersion {
+		t.Fatalf("expected a concurrency error, got %v\n", err)
+	}
+	if _, err := s.AppendEvents(ctx, "order-2", NoStream, NewEvent{Type: "created", Data: json.RawMessage(`{}`)}); err != nil {
+		t.Fatalf("error appending: %v\n", err)
+	}
+
+	events, err := s.ReadStream(ctx, "order-1", 2, 10)
+	if err != nil || len(events) != 1 || events[0].Type != "paid" || events[0].Position != 2 {
+		t.Fatalf("unexpected stream: %+v %v\n", events, err)
+	}
+
+	// catch up with positions 2 and 3, then receive 4 through a notification.
+	sub, cancel := context.WithCancel(ctx)
+	var seen []int64
+	err = s.Subscribe(sub, 1, time.Minute, func(ctx context.Context, e Event) error {
+		seen = append(seen, e.Position)
+		switch len(seen) {
+		case 2:
+			go s.AppendEvents(context.Background(), "order-2", 1, NewEvent{Type: "paid", Data: json.RawMessage(`{}`)})
+		case 3:
+			cancel()
+		}
+		return nil
+	})
+	if err != context.Canceled || len(seen) != 3 || seen[2] != 4 {
+		t.Fatalf("unexpected subscription: %v %v\n", seen, err)
+	}
+}
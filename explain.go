@@ -0,0 +1,133 @@
+package godbm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// ErrExplainAnalyzeDisabled is returned by ExplainAnalyze unless it was enabled with
+// EnableExplainAnalyze.
+var ErrExplainAnalyzeDisabled = errors.New("godbm: error explain analyze is not enabled")
+
+// Plan is the parsed output of EXPLAIN (FORMAT JSON).
+type Plan struct {
+	Plan          PlanNode `json:"Plan"`
+	PlanningTime  float64  `json:"Planning Time,omitempty"`  // milliseconds, ExplainAnalyze only.
+	ExecutionTime float64  `json:"Execution Time,omitempty"` // milliseconds, ExplainAnalyze only.
+}
+
+// PlanNode is a single node in a query plan. The Actual fields are only set by ExplainAnalyze.
+type PlanNode struct {
+	NodeType          string     `json:"Node Type"`
+	RelationName      string     `json:"Relation Name,omitempty"`
+	Alias             string     `json:"Alias,omitempty"`
+	IndexName         string     `json:"Index Name,omitempty"`
+	JoinType          string     `json:"Join Type,omitempty"`
+	Strategy          string     `json:"Strategy,omitempty"`
+	IndexCond         string     `json:"Index Cond,omitempty"`
+	Filter            string     `json:"Filter,omitempty"`
+	StartupCost       float64    `json:"Startup Cost"`
+	TotalCost         float64    `json:"Total Cost"`
+	PlanRows          float64    `json:"Plan Rows"`
+	PlanWidth         int        `json:"Plan Width"`
+	ActualStartupTime float64    `json:"Actual Startup Time,omitempty"`
+	ActualTotalTime   float64    `json:"Actual Total Time,omitempty"`
+	ActualRows        float64    `json:"Actual Rows,omitempty"`
+	ActualLoops       float64    `json:"Actual Loops,omitempty"`
+	Plans             []PlanNode `json:"Plans,omitempty"`
+}
+
+// Walk calls fn for the node and every node below it, depth first.
+func (n *PlanNode) Walk(fn func(node *PlanNode)) {
+	fn(n)
+	for i := range n.Plans {
+		n.Plans[i].Walk(fn)
+	}
+}
+
+// EnableExplainAnalyze allows ExplainAnalyze to be used. It is disabled by default because
+// EXPLAIN ANALYZE executes the statement.
+func (store *SqlStore) EnableExplainAnalyze(enabled bool) {
+	store.explainAnalyze = enabled
+}
+
+// Explain returns the plan for a registered statement key, or for keyOrQuery as sql if no
+// statement is registered under it. The statement is planned but not executed.
+func (store *SqlStore) Explain(ctx context.Context, keyOrQuery string, args ...interface{}) (*Plan, error) {
+	if !store.Connected {
+		return nil, &ConnectionError{}
+	}
+	query, err := store.resolveQuery(keyOrQuery)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := store.explainJSON(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parsePlan(raw)
+}
+
+// ExplainAnalyze is the same as Explain but executes the statement to report actual timings.
+// The statement runs inside a transaction which is always rolled back so writes are discarded.
+// Returns ErrExplainAnalyzeDisabled unless EnableExplainAnalyze(true) was called.
+func (store *SqlStore) ExplainAnalyze(ctx context.Context, keyOrQuery string, args ...interface{}) (*Plan, error) {
+	if !store.Connected {
+		return nil, &ConnectionError{}
+	}
+	if !store.explainAnalyze {
+		return nil, ErrExplainAnalyzeDisabled
+	}
+
+	query, err := store.resolveQuery(keyOrQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	txn, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer txn.Rollback()
+
+	var raw string
+	if args, err = store.convertArgs(args); err != nil {
+		return nil, err
+	}
+	if err := txn.QueryRowContext(ctx, "EXPLAIN (ANALYZE, FORMAT JSON) "+query, args...).Scan(&raw); err != nil {
+		return nil, err
+	}
+	return parsePlan(raw)
+}
+
+// resolveQuery returns the sql registered under keyOrQuery, or keyOrQuery itself unless the
+// registry is locked.
+func (store *SqlStore) resolveQuery(keyOrQuery string) (string, error) {
+	if st, err := store.lookup(keyOrQuery); err == nil {
+		return st.query, nil
+	}
+	return keyOrQuery, store.checkUnlocked()
+}
+
+// explainJSON returns the raw EXPLAIN (FORMAT JSON) output for query. The query is planned
+// but not executed.
+func (store *SqlStore) explainJSON(ctx context.Context, query string, args ...interface{}) (plan string, err error) {
+	if args, err = store.convertArgs(args); err != nil {
+		return "", err
+	}
+	err = store.db.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+query, args...).Scan(&plan)
+	return plan, err
+}
+
+// parsePlan decodes EXPLAIN (FORMAT JSON) output, which is an array holding a single plan.
+func parsePlan(raw string) (*Plan, error) {
+	var plans []Plan
+	if err := json.Unmarshal([]byte(raw), &plans); err != nil {
+		return nil, err
+	}
+	if len(plans) == 0 {
+		return nil, errors.New("godbm: error explain returned no plan")
+	}
+	return &plans[0], nil
+}
@@ -0,0 +1,23 @@
+package godbm
+
+import "testing"
+
+func TestParsePlan(t *testing.T) {
+	raw := `[{"Plan": {"Node Type": "Nested Loop", "Total Cost": 12.5, "Plan Rows": 3, "Plans": [
+		{"Node Type": "Seq Scan", "Relation Name": "test", "Total Cost": 1.5},
+		{"Node Type": "Index Scan", "Relation Name": "users", "Index Name": "users_pkey", "Total Cost": 8}
+	]}}]`
+
+	plan, err := parsePlan(raw)
+	if err != nil {
+		t.Fatalf("error parsing plan: %v\n", err)
+	}
+
+	var nodes []string
+	plan.Plan.Walk(func(node *PlanNode) {
+		nodes = append(nodes, node.NodeType)
+	})
+	if len(nodes) != 3 || nodes[1] != "Seq Scan" || plan.Plan.Plans[1].IndexName != "users_pkey" {
+		t.Fatalf("unexpected plan: %+v\n", plan)
+	}
+}
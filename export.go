@@ -0,0 +1,180 @@
+package godbm
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// QueryJSON executes the prepared statement registered under key and streams the rows to w as
+// a JSON array of objects keyed by column name, in column order. Rows are written as they are
+// read so the result is never buffered in full. json and jsonb columns are embedded as is.
+func (store *SqlStore) QueryJSON(ctx context.Context, key string, w io.Writer, args ...interface{}) error {
+	return store.queryJSON(ctx, key, w, false, args...)
+}
+
+// QueryNDJSON is the same as QueryJSON but writes newline delimited JSON, one object per line.
+func (store *SqlStore) QueryNDJSON(ctx context.Context, key string, w io.Writer, args ...interface{}) error {
+	return store.queryJSON(ctx, key, w, true, args...)
+}
+
+func (store *SqlStore) queryJSON(ctx context.Context, key string, w io.Writer, ndjson bool, args ...interface{}) error {
+	rows, err := store.QueryPreparedContext(ctx, key, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	reader, err := newRowReader(rows)
+	if err != nil {
+		return err
+	}
+
+	// encode the column names once.
+	names := make([][]byte, len(reader.columns))
+	for i, col := range reader.columns {
+		if names[i], err = json.Marshal(col); err != nil {
+			return err
+		}
+	}
+
+	buf := bufio.NewWriter(w)
+	if !ndjson {
+		buf.WriteByte('[')
+	}
+
+	for first := true; rows.Next(); first = false {
+		values, err := reader.read(rows)
+		if err != nil {
+			return err
+		}
+
+		if !first && !ndjson {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('{')
+		for i, v := range values {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.Write(names[i])
+			buf.WriteByte(':')
+			if err := writeJSONValue(buf, reader.types[i].DatabaseTypeName(), v); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		if ndjson {
+			buf.WriteByte('\n')
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if !ndjson {
+		buf.WriteByte(']')
+	}
+	return buf.Flush()
+}
+
+// writeJSONValue encodes a single column value.
+func writeJSONValue(w *bufio.Writer, typeName string, v interface{}) error {
+	if s, ok := v.(string); ok && (typeName == "JSON" || typeName == "JSONB") {
+		_, err := w.WriteString(s)
+		return err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// CSVOptions controls how QueryCSV writes results.
+type CSVOptions struct {
+	Comma      rune   // field delimiter, defaults to ','.
+	UseCRLF    bool   // terminate lines with \r\n.
+	NoHeader   bool   // omit the header row of column names.
+	Null       string // written for NULL values, defaults to an empty field.
+	TimeFormat string // layout for timestamps, defaults to time.RFC3339Nano.
+}
+
+// QueryCSV executes the prepared statement registered under key and writes the rows to w as
+// CSV, preceded by a header row of column names unless opts.NoHeader is set. Fields are quoted
+// as needed, bytea values are written in postgres hex format (\x...).
+func (store *SqlStore) QueryCSV(ctx context.Context, key string, w io.Writer, opts CSVOptions, args ...interface{}) error {
+	rows, err := store.QueryPreparedContext(ctx, key, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	reader, err := newRowReader(rows)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		writer.Comma = opts.Comma
+	}
+	writer.UseCRLF = opts.UseCRLF
+
+	if !opts.NoHeader {
+		if err := writer.Write(reader.columns); err != nil {
+			return err
+		}
+	}
+
+	record := make([]string, len(reader.columns))
+	for rows.Next() {
+		values, err := reader.read(rows)
+		if err != nil {
+			return err
+		}
+		for i, v := range values {
+			record[i] = csvField(v, &opts)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvField formats a single column value.
+func csvField(v interface{}, opts *CSVOptions) string {
+	switch v := v.(type) {
+	case nil:
+		return opts.Null
+	case string:
+		return v
+	case []byte:
+		return "\\x" + hex.EncodeToString(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		if opts.TimeFormat != "" {
+			return v.Format(opts.TimeFormat)
+		}
+		return v.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
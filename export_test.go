@@ -0,0 +1,29 @@
+package godbm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCSVField(t *testing.T) {
+	opts := &CSVOptions{Null: "NULL"}
+	ts := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+	cases := []struct {
+		value    interface{}
+		expected string
+	}{
+		{nil, "NULL"},
+		{"boop", "boop"},
+		{[]byte{0xde, 0xad}, `\xdead`},
+		{int64(3), "3"},
+		{1.5, "1.5"},
+		{true, "true"},
+		{ts, "2016-01-02T03:04:05Z"},
+	}
+
+	for _, c := range cases {
+		if got := csvField(c.value, opts); got != c.expected {
+			t.Fatalf("expected %q for %v, got %q\n", c.expected, c.value, got)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package godbm
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// WKB geometry type codes and the EWKB flags postgis adds to them.
+const (
+	wkbPoint           = 1
+	wkbLineString      = 2
+	wkbPolygon         = 3
+	wkbMultiPoint      = 4
+	wkbMultiLineString = 5
+	wkbMultiPolygon    = 6
+	ewkbSRID           = 0x20000000
+	ewkbZM             = 0xc0000000
+)
+
+// errShortWKB is returned when WKB ends before the geometry it describes.
+var errShortWKB = errors.New("godbm: error WKB is truncated")
+
+// Shape is a two dimensional geometry held by a Geometry: a Point, LineString, Polygon,
+// MultiPoint, MultiLineString or MultiPolygon.
+type Shape interface {
+	wkbType() uint32
+	appendWKB(b []byte) []byte
+}
+
+// Point is a position, X is the longitude and Y the latitude for geographic coordinates.
+type Point struct {
+	X, Y float64
+}
+
+// LineString is a connected sequence of points.
+type LineString []Point
+
+// Polygon is a list of closed rings, the first is the exterior and any others are holes.
+type Polygon [][]Point
+
+// MultiPoint, MultiLineString and MultiPolygon are collections of a single kind of shape.
+type (
+	MultiPoint      []Point
+	MultiLineString []LineString
+	MultiPolygon    []Polygon
+)
+
+// Geometry scans a postgis geometry or geography column and binds as one. Values are read from
+// the hex EWKB postgres returns them as and sent as hex EWKB, which postgis accepts as input to
+// both types, so Geometry works as an argument to ST_ functions. Where a function is
+// overloaded, such as ST_DWithin, cast the parameter to pick one:
+//
+//	store.QueryPrepared("near", godbm.Geometry{SRID: 4326, Shape: godbm.Point{X: lon, Y: lat}})
+//	// select id from places where ST_DWithin(location, $1::geography, 500)
+//
+// Only two dimensional geometries are supported. A NULL column scans as a nil Shape. For other
+// geometry types register a ScannerFunc with RegisterType and decode the WKB yourself.
+type Geometry struct {
+	SRID  int // spatial reference id, such as 4326 for WGS 84, zero if unset.
+	Shape Shape
+}
+
+// Scan implements sql.Scanner.
+func (g *Geometry) Scan(src interface{}) error {
+	var raw []byte
+	switch v := src.(type) {
+	case nil:
+		*g = Geometry{}
+		return nil
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("godbm: error cannot scan %T into a Geometry", src)
+	}
+
+	// text results are hex, binary results are raw WKB starting with a byte order of 0 or 1.
+	if len(raw) > 0 && raw[0] > 1 {
+		decoded := make([]byte, hex.DecodedLen(len(raw)))
+		if _, err := hex.Decode(decoded, raw); err != nil {
+			return fmt.Errorf("godbm: error invalid EWKB: %w", err)
+		}
+		raw = decoded
+	}
+	parsed, err := ParseEWKB(raw)
+	if err != nil {
+		return err
+	}
+	*g = parsed
+	return nil
+}
+
+// Value implements driver.Valuer, a nil Shape is sent as NULL.
+func (g Geometry) Value() (driver.Value, error) {
+	if g.Shape == nil {
+		return nil, nil
+	}
+	return hex.EncodeToString(g.EWKB()), nil
+}
+
+// EWKB returns the geometry as little endian EWKB, including the SRID if set.
+func (g Geometry) EWKB() []byte {
+	typ := g.Shape.wkbType()
+	if g.SRID != 0 {
+		typ |= ewkbSRID
+	}
+	b := binary.LittleEndian.AppendUint32([]byte{1}, typ)
+	if g.SRID != 0 {
+		b = binary.LittleEndian.AppendUint32(b, uint32(g.SRID))
+	}
+	return g.Shape.appendWKB(b)
+}
+
+// ParseEWKB parses a geometry in WKB or postgis EWKB.
+func ParseEWKB(b []byte) (Geometry, error) {
+	r := &wkbReader{b: b}
+	shape, srid, err := r.geometry()
+	if err == nil && len(r.b) > 0 {
+		err = errors.New("godbm: error WKB has trailing bytes")
+	}
+	return Geometry{SRID: srid, Shape: shape}, err
+}
+
+func (p Point) wkbType() uint32           { return wkbPoint }
+func (l LineString) wkbType() uint32      { return wkbLineString }
+func (p Polygon) wkbType() uint32         { return wkbPolygon }
+func (m MultiPoint) wkbType() uint32      { return wkbMultiPoint }
+func (m MultiLineString) wkbType() uint32 { return wkbMultiLineString }
+func (m MultiPolygon) wkbType() uint32    { return wkbMultiPolygon }
+
+func (p Point) appendWKB(b []byte) []byte {
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(p.X))
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(p.Y))
+}
+
+func (l LineString) appendWKB(b []byte) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(l)))
+	for _, p := range l {
+		b = p.appendWKB(b)
+	}
+	return b
+}
+
+func (p Polygon) appendWKB(b []byte) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(p)))
+	for _, ring := range p {
+		b = LineString(ring).appendWKB(b)
+	}
+	return b
+}
+
+func (m MultiPoint) appendWKB(b []byte) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(m)))
+	for _, p := range m {
+		b = appendMember(b, p)
+	}
+	return b
+}
+
+func (m MultiLineString) appendWKB(b []byte) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(m)))
+	for _, l := range m {
+		b = appendMember(b, l)
+	}
+	return b
+}
+
+func (m MultiPolygon) appendWKB(b []byte) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(m)))
+	for _, p := range m {
+		b = appendMember(b, p)
+	}
+	return b
+}
+
+// appendMember appends a member of a multi geometry, which is a complete WKB geometry.
+func appendMember(b []byte, s Shape) []byte {
+	b = binary.LittleEndian.AppendUint32(append(b, 1), s.wkbType())
+	return s.appendWKB(b)
+}
+
+// wkbReader reads WKB, each geometry sets the byte order for what follows it.
+type wkbReader struct {
+	b     []byte
+	order binary.ByteOrder
+}
+
+// geometry reads a geometry along with its SRID, if any.
+func (r *wkbReader) geometry() (Shape, int, error) {
+	if len(r.b) < 1 {
+		return nil, 0, errShortWKB
+	}
+	switch r.b[0] {
+	case 0:
+		r.order = binary.BigEndian
+	case 1:
+		r.order = binary.LittleEndian
+	default:
+		return nil, 0, errors.New("godbm: error invalid WKB byte order")
+	}
+	r.b = r.b[1:]
+
+	typ, err := r.uint32()
+	if err != nil {
+		return nil, 0, err
+	}
+	var srid int
+	if typ&ewkbSRID != 0 {
+		s, err := r.uint32()
+		if err != nil {
+			return nil, 0, err
+		}
+		srid = int(s)
+	}
+	if typ&ewkbZM != 0 || typ&0xffff > 1000 {
+		return nil, 0, errors.New("godbm: error only two dimensional geometries are supported")
+	}
+
+	shape, err := r.shape(typ & 0xffff)
+	return shape, srid, err
+}
+
+// shape reads the body of a geometry of type typ.
+func (r *wkbReader) shape(typ uint32) (Shape, error) {
+	switch typ {
+	case wkbPoint:
+		return r.point()
+	case wkbLineString:
+		return r.lineString()
+	case wkbPolygon:
+		n, err := r.count(4)
+		if err != nil {
+			return nil, err
+		}
+		polygon := make(Polygon, n)
+		for i := range polygon {
+			if polygon[i], err = r.lineString(); err != nil {
+				return nil, err
+			}
+		}
+		return polygon, nil
+	case wkbMultiPoint, wkbMultiLineString, wkbMultiPolygon:
+		n, err := r.count(9)
+		if err != nil {
+			return nil, err
+		}
+		members := make([]Shape, n)
+		for i := range members {
+			member, _, err := r.geometry()
+			if err != nil {
+				return nil, err
+			}
+			if member.wkbType() != typ-3 {
+				return nil, fmt.Errorf("godbm: error unexpected WKB type %d in a collection of %d", member.wkbType(), typ)
+			}
+			members[i] = member
+		}
+		return collect(typ, members), nil
+	}
+	return nil, fmt.Errorf("godbm: error unsupported WKB geometry type %d", typ)
+}
+
+// collect converts the members of a multi geometry of type typ to its Shape.
+func collect(typ uint32, members []Shape) Shape {
+	switch typ {
+	case wkbMultiPoint:
+		multi := make(MultiPoint, len(members))
+		for i, m := range members {
+			multi[i] = m.(Point)
+		}
+		return multi
+	case wkbMultiLineString:
+		multi := make(MultiLineString, len(members))
+		for i, m := range members {
+			multi[i] = m.(LineString)
+		}
+		return multi
+	}
+	multi := make(MultiPolygon, len(members))
+	for i, m := range members {
+		multi[i] = m.(Polygon)
+	}
+	return multi
+}
+
+func (r *wkbReader) point() (Point, error) {
+	if len(r.b) < 16 {
+		return Point{}, errShortWKB
+	}
+	p := Point{X: math.Float64frombits(r.order.Uint64(r.b)), Y: math.Float64frombits(r.order.Uint64(r.b[8:]))}
+	r.b = r.b[16:]
+	return p, nil
+}
+
+func (r *wkbReader) lineString() (LineString, error) {
+	n, err := r.count(16)
+	if err != nil {
+		return nil, err
+	}
+	line := make(LineString, n)
+	for i := range line {
+		if line[i], err = r.point(); err != nil {
+			return nil, err
+		}
+	}
+	return line, nil
+}
+
+// count reads the number of elements which follow, checking there are enough bytes left for
+// elements of at least size bytes so a corrupt count can't allocate a huge slice.
+func (r *wkbReader) count(size int) (int, error) {
+	n, err := r.uint32()
+	if err != nil {
+		return 0, err
+	}
+	if uint64(n)*uint64(size) > uint64(len(r.b)) {
+		return 0, errShortWKB
+	}
+	return int(n), nil
+}
+
+func (r *wkbReader) uint32() (uint32, error) {
+	if len(r.b) < 4 {
+		return 0, errShortWKB
+	}
+	v := r.order.Uint32(r.b)
+	r.b = r.b[4:]
+	return v, nil
+}
@@ -0,0 +1,50 @@
+package godbm
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGeometryScan(t *testing.T) {
+	var g Geometry
+	if err := g.Scan([]byte("0101000020E6100000000000000000F03F0000000000000040")); err != nil {
+		t.Fatalf("error scanning: %v\n", err)
+	}
+	if g.SRID != 4326 || g.Shape != (Point{X: 1, Y: 2}) {
+		t.Fatalf("unexpected geometry: %+v\n", g)
+	}
+	value, err := g.Value()
+	if err != nil || strings.ToUpper(value.(string)) != "0101000020E6100000000000000000F03F0000000000000040" {
+		t.Fatalf("unexpected value: %v %v\n", value, err)
+	}
+
+	// big endian WKB without an SRID.
+	if err := g.Scan([]byte("00000000013FF00000000000004000000000000000")); err != nil || g.SRID != 0 || g.Shape != (Point{X: 1, Y: 2}) {
+		t.Fatalf("unexpected geometry: %+v %v\n", g, err)
+	}
+	if err := g.Scan(nil); err != nil || g.Shape != nil {
+		t.Fatalf("expected NULL to scan as a nil shape: %v\n", err)
+	}
+	if err := g.Scan([]byte("0101000020E6100000000000000000F03F")); err != errShortWKB {
+		t.Fatalf("expected errShortWKB got %v\n", err)
+	}
+}
+
+func TestGeometryRoundTrip(t *testing.T) {
+	ring := []Point{{0, 0}, {4, 0}, {4, 4}, {0, 0}}
+	shapes := []Shape{
+		LineString{{0, 0}, {1, 1}},
+		Polygon{ring, {{1, 1}, {2, 1}, {2, 2}, {1, 1}}},
+		MultiPoint{{1, 2}, {3, 4}},
+		MultiLineString{{{0, 0}, {1, 1}}, {{2, 2}, {3, 3}}},
+		MultiPolygon{{ring}, {ring}},
+	}
+	for _, shape := range shapes {
+		g := Geometry{SRID: 3857, Shape: shape}
+		parsed, err := ParseEWKB(g.EWKB())
+		if err != nil || !reflect.DeepEqual(parsed, g) {
+			t.Fatalf("unexpected round trip of %T: %+v %v\n", shape, parsed, err)
+		}
+	}
+}
@@ -26,9 +26,16 @@ THE SOFTWARE.
 package godbm
 
 import (
+	"context"
+	"crypto/tls"
 	"database/sql"
+	"database/sql/driver"
 	"github.com/lib/pq"
+	"log/slog"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // SqlStorer interface
@@ -39,10 +46,13 @@ type SqlStorer interface {
 	Query(query string, data ...interface{}) (*sql.Rows, error)
 	PrepareStatement(query string) (*sql.Stmt, error)
 	PrepareAdd(key, query string) error
+	PrepareAddWithOptions(key, query string, opts ...StmtOption) error
 	HasStatement(key string) bool
 	PrepareDel(key string) error
 	QueryPrepared(key string, data ...interface{}) (*sql.Rows, error)
+	QueryPreparedContext(ctx context.Context, key string, data ...interface{}) (*sql.Rows, error)
 	ExecPrepared(key string, data ...interface{}) (sql.Result, error)
+	ExecPreparedContext(ctx context.Context, key string, data ...interface{}) (sql.Result, error)
 }
 
 // UnknownStmtError holds the invalid key which was attempted in a look up.
@@ -63,22 +73,122 @@ func (e *ConnectionError) Error() string {
 	return "godbm: error not connected to the database"
 }
 
+// statement holds a prepared statement along with the query text and the options
+// it was registered with.
+type statement struct {
+	key          string                 // the key the statement was registered under, empty for ad-hoc queries
+	stmt         *sql.Stmt              // the prepared statement
+	query        string                 // the sql used to prepare the statement
+	opts         stmtOptions            // options supplied to PrepareAddWithOptions
+	params       int                    // number of parameters the statement expects
+	calls        atomic.Int64           // number of times the statement was called
+	errors       atomic.Int64           // number of calls which returned an error
+	lastUsed     atomic.Int64           // unix nanoseconds of the last call, zero if never called
+	registered   time.Time              // when the statement was registered
+	pages        *pageStatements        // statements derived for Paginate, prepared on first use
+	replicaStmts map[*replica]*sql.Stmt // the statement prepared on replicas, on first use
+	locked       map[RowLock]*statement // statements derived for WithRowLock, prepared on first use
+	in           *inList                // variants of PrepareAddIn statements, nil for others
+}
+
+// close closes the prepared statement along with any statements derived from it.
+func (st *statement) close() error {
+	if st.pages != nil {
+		st.pages.close()
+	}
+	for _, stmt := range st.replicaStmts {
+		stmt.Close()
+	}
+	for _, locked := range st.locked {
+		locked.close()
+	}
+	if st.in != nil {
+		for _, variant := range st.in.stmts {
+			variant.close()
+		}
+	}
+	if st.stmt == nil {
+		return nil
+	}
+	return st.stmt.Close()
+}
+
 // SqlStore holds a reference to the database, a list of prepared statements
 // and a boolean for if we are connected.
 type SqlStore struct {
-	sync.RWMutex                      // a mutex to synchronize adding/calling/removing new statements.
-	Connected    bool                 // indicates if we are connected or not.
-	db           *sql.DB              // the underlying database reference
-	queries      map[string]*sql.Stmt // a map of prepared statements referenced by the key
-	username     string               // database username
-	password     string               // database password
-	dbname       string               // database name to connect to
-	host         string               // database host
-	sslmode      string               // sslmode one of: require, verify-full, verify-ca, disable. (check postgres docs for more)
-	opts         string               // add your own options.
-}
-
-// New creates a new *SqlStore with the connection properties as arguments.
+	sync.RWMutex                           // a mutex to synchronize adding/calling/removing new statements.
+	Connected        bool                  // indicates if we are connected or not.
+	db               *sql.DB               // the underlying database reference
+	queries          map[string]*statement // a map of prepared statements referenced by the key
+	versions         map[string]string     // default version of versioned keys, see SetDefaultVersion
+	retry            *RetryPolicy          // optional retry policy for idempotent prepared statements
+	breaker          *CircuitBreaker       // optional circuit breaker guarding calls to the database
+	timeouts         Timeouts              // session timeouts applied to every connection
+	hooks            []Hook                // hooks called around every query, in registration order
+	logger           *slog.Logger          // optional structured logger
+	logOpts          LogOptions            // controls what logger logs
+	counters         storeCounters         // counters reported by Stats
+	slow             *SlowQueryOptions     // optional slow query detection
+	slowLock         *SlowLockOptions      // optional detection of calls waiting on locks
+	planGuard        *PlanGuardOptions     // optional detection of plan changes, see SetPlanGuard
+	warmConns        int                   // connections warmed up by Connect, see SetWarmUp
+	plans            sync.Map              // plans seen for each watched statement key
+	explainAnalyze   bool                  // allows ExplainAnalyze to execute statements
+	replicas         []*replica            // read replicas, see NewWithReplicas
+	replicaCheck     time.Duration         // how often replicas are health checked
+	stopReplicaCheck chan struct{}         // closed to stop health checking replicas
+	replicaCheckDone chan struct{}         // closed once health checking replicas has stopped
+	nextReplica      atomic.Uint32         // round robin counter for picking replicas
+	maxLag           int64                 // bytes a replica may lag before reads avoid it, zero to disable
+	targetSession    string                // which of several hosts are acceptable, see SetTargetSessionAttrs
+	pool             PoolOptions           // connection pool sizes, see SetPoolOptions
+	driver           driver.Driver         // driver used to open connections, nil for lib/pq
+	noPrepare        bool                  // run statements without preparing them, see SetNoPrepare
+	cache            *stmtCache            // optional cache of ad-hoc statements for Exec and Query
+	preparing        flightGroup           // deduplicates concurrent GetOrPrepare calls
+	reads            flightGroup           // deduplicates concurrent calls of Coalesced statements
+	concurrency      semaphore             // optional limit on calls running at once, see SetConcurrencyLimit
+	poolClasses      map[string]semaphore  // optional limits on calls per class, see SetPoolClasses
+	statementDirs    []string              // directories of .sql files prepared on Connect
+	credentials      CredentialProvider    // optional source of rotating credentials
+	authToken        AuthTokenGenerator    // optional per connection password generator
+	dialer           Dialer                // optional dialer used to open connections
+	tlsConfig        *tls.Config           // optional TLS configuration used in place of sslmode
+	sessionParams    map[string]string     // run-time parameters sent when connections start
+	connHooks        ConnectionHooks       // callbacks run as connections change state
+	comments         *QueryComments        // optional sqlcommenter comments appended to executed sql
+	stmtLabels       func(string) string   // labels statements with their keys, see SetStatementLabels
+	dryRun           DryRunMode            // how ExecPrepared runs statements, see SetDryRun
+	leakOpts         *LeakOptions          // optional debug tracking of unclosed rows and transactions
+	leaks            *leakTracker          // rows and transactions tracked while leak detection runs
+	redactor         Redactor              // optional redaction of arguments seen by hooks and logs
+	auditOpts        *AuditOptions         // optional auditing of ExecPrepared calls
+	readOnly         bool                  // refuse to write, see SetReadOnly
+	registryLocked   atomic.Bool           // only registered statements may run, see LockRegistry
+	decimal          DecimalFunc           // optional conversion of numeric columns, see SetDecimalType
+	converters       typeConverters        // bind and scan functions, see RegisterType
+	codec            EncryptionCodec       // optional encryption of marked parameters and fields
+	resultCache      ResultCache           // optional cache of results for statements marked Cached
+	cacheGens        sync.Map              // generation of each key's cached results, see InvalidateAll
+	invalidations    map[string][]string   // statement keys invalidated by each channel, see InvalidateOn
+	stopListening    context.CancelFunc    // stops listening for invalidations, see InvalidateOn
+	retention        *RetentionOptions     // optional retention policies, see SetRetention
+	stopRetention    context.CancelFunc    // stops running the retention policies
+	refreshed        sync.Map              // when each materialized view was last refreshed
+	refreshes        []scheduledRefresh    // materialized views refreshed while connected, see ScheduleRefresh
+	stopRefreshes    context.CancelFunc    // stops refreshing the scheduled materialized views
+	schedules        []scheduledJob        // statements run on a cron schedule while connected, see Schedule
+	stopSchedules    context.CancelFunc    // stops running the scheduled statements
+	username         string                // database username
+	password         string                // database password
+	dbname           string                // database name to connect to
+	host             string                // database host
+	sslmode          string                // sslmode one of: require, verify-full, verify-ca, disable. (check postgres docs for more)
+	opts             string                // add your own options.
+}
+
+// New creates a new *SqlStore with the connection properties as arguments. host may be a comma
+// separated list of host or host:port entries which are tried in turn, see SetTargetSessionAttrs.
 func New(username, password, dbname, host, sslmode, opts string) *SqlStore {
 	s := new(SqlStore)
 	s.username = username
@@ -86,77 +196,229 @@ func New(username, password, dbname, host, sslmode, opts string) *SqlStore {
 	s.host = host
 	s.dbname = dbname
 	s.sslmode = sslmode
+	s.opts = opts
 	return s
 }
 
-// Connect connects to the database. Returns err on sql.Open error or sets
-// our connected state to true.
+// Connect sets up the connection pool and sets our connected state to true. Connections are
+// opened as they are needed, so a host which can't be reached is reported when a connection
+// to it fails, see ConnectionHooks and SetWarmUp. Returns err if a replica pool, the statement
+// directories or the warm up fail.
 func (store *SqlStore) Connect() (err error) {
 	store.Connected = false
-	store.db, err = sql.Open("postgres", "user="+store.username+" password="+store.password+" dbname="+store.dbname+" host="+store.host+" sslmode="+store.sslmode+" "+store.opts)
-	if err != nil {
+	store.counters.connects.Add(1)
+	store.db = sql.OpenDB(store.newConnector(store.host))
+	store.applyPool(store.db)
+	store.logEvent(context.Background(), "godbm: open pool", nil, slog.String("host", store.host), slog.String("dbname", store.dbname))
+	if err = store.connectReplicas(); err != nil {
+		store.db.Close()
 		return err
 	}
 	store.Connected = true
+	store.startLeakDetection()
+	store.startInvalidation()
+	store.startRetention()
+	store.startRefreshes()
+	store.startSchedules()
+	if err = store.prepareStatementDirs(); err != nil {
+		store.Disconnect()
+		return err
+	}
+	if err = store.warmUp(); err != nil {
+		store.Disconnect()
+		return err
+	}
 	return err
 }
 
+// dsn builds the connection string for the first host from our connection properties.
+func (store *SqlStore) dsn() string {
+	return store.dsnFor(strings.Split(store.host, ",")[0])
+}
+
+// dsnFor builds the connection string for host from our connection properties.
+func (store *SqlStore) dsnFor(host string) string {
+	return store.dsnWith(host, store.username, store.password)
+}
+
+// dsnWith builds the connection string for host using the username and password given.
+func (store *SqlStore) dsnWith(host, username, password string) string {
+	host, port := hostPort(host)
+	dsn := "user=" + dsnValue(username) + " password=" + dsnValue(password) + " dbname=" + dsnValue(store.dbname) + " host=" + host + " sslmode=" + store.sslmodeFor()
+	if port != "" {
+		dsn += " port=" + port
+	}
+	// lib/pq passes unknown keys to the server as run-time parameters.
+	for _, setting := range store.timeouts.settings() {
+		dsn += " " + setting[0] + "=" + setting[1]
+	}
+	if store.noPrepare && store.isPQ() {
+		dsn += " binary_parameters=yes"
+	}
+	dsn += store.sessionSettings()
+	return dsn + " " + store.opts
+}
+
+// sslmodeFor returns the sslmode to pass to the driver, lib/pq mustn't negotiate TLS when we do.
+func (store *SqlStore) sslmodeFor() string {
+	if store.tlsConfig != nil && store.isPQ() {
+		return "disable"
+	}
+	return store.sslmode
+}
+
 // Disconnect iterates through any prepared statements and closes them then calls close
 // on the db driver.
 func (store *SqlStore) Disconnect() (err error) {
 	for _, v := range store.queries {
-		v.Close()
+		v.close()
 	}
+	if store.cache != nil {
+		store.cache.clear()
+	}
+	store.disconnectReplicas()
+	store.stopLeakDetection()
+	store.stopInvalidation()
+	store.endRetention()
+	store.endRefreshes()
+	store.endSchedules()
 	err = store.db.Close()
 	store.Connected = false
+	if store.connHooks.OnDisconnect != nil {
+		store.connHooks.OnDisconnect()
+	}
+	store.logEvent(context.Background(), "godbm: disconnect", err, slog.String("host", store.host), slog.String("dbname", store.dbname))
 	return err
 }
 
 // Exec creates a new prepared statement, executes and closes. Takes a query string as the first
 // parameter and a variable number of arguments to be used in the statement. Closes the statement
-// when finished and returns a sql.Result. You should only use this for testing as creating new
-// statements every time is non-performant.
+// when finished and returns a sql.Result. Creating new statements every time is non-performant,
+// use SetStatementCacheSize to keep them around or register them with PrepareAdd.
 func (store *SqlStore) Exec(query string, data ...interface{}) (results sql.Result, err error) {
+	return store.ExecContext(context.Background(), query, data...)
+}
+
+// ExecContext is the same as Exec but takes a context, which may carry a transaction started
+// by WithTransaction.
+func (store *SqlStore) ExecContext(ctx context.Context, query string, data ...interface{}) (results sql.Result, err error) {
 	if !store.Connected {
 		return nil, &ConnectionError{}
 	}
-
-	stmt, err := store.PrepareStatement(query)
-	if err != nil {
+	if err := store.checkUnlocked(); err != nil {
+		return nil, err
+	}
+	if err := store.checkWrite(); err != nil {
 		return nil, err
 	}
-	defer stmt.Close()
-
-	return stmt.Exec(data...)
 
+	if data, err = store.convertArgs(data); err != nil {
+		return nil, err
+	}
+	st := &statement{query: query}
+	if store.dryRun == DryRunValidate {
+		store.logDryRun(ctx, st, data)
+		return dryRunResult{}, nil
+	}
+	exec := func(ctx context.Context) (sql.Result, error) {
+		release, err := store.adhocStmt(st)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return store.stmtFor(ctx, st).ExecContext(ctx, data...)
+	}
+	err = store.run(ctx, st, data, func(ctx context.Context) (err error) {
+		if store.dryRun == DryRunRollback {
+			results, err = store.execRolledBack(ctx, exec)
+			return err
+		}
+		results, err = exec(ctx)
+		if err == nil {
+			store.afterExec(ctx, st, results)
+		}
+		return err
+	})
+	return results, err
 }
 
 // Query creates a new prepared statement, executes and closes. Takes a query string as the first
 // parameter and a variable number of arguments to be used in the statement. Closes the statement
-// when finished and returns *sql.Rows if any. You should only use this for testing as creating new
-// statements every time is non-performant.
+// when finished and returns *sql.Rows if any. Creating new statements every time is non-performant,
+// use SetStatementCacheSize to keep them around or register them with PrepareAdd.
 func (store *SqlStore) Query(query string, data ...interface{}) (results *sql.Rows, err error) {
+	return store.QueryContext(context.Background(), query, data...)
+}
+
+// QueryContext is the same as Query but takes a context, which may carry a transaction started
+// by WithTransaction.
+func (store *SqlStore) QueryContext(ctx context.Context, query string, data ...interface{}) (results *sql.Rows, err error) {
 	if !store.Connected {
 		return nil, &ConnectionError{}
 	}
+	if err := store.checkUnlocked(); err != nil {
+		return nil, err
+	}
+	if err := store.checkRead(query); err != nil {
+		return nil, err
+	}
 
-	stmt, err := store.PrepareStatement(query)
+	if data, err = store.convertArgs(data); err != nil {
+		return nil, err
+	}
+	st := &statement{query: query}
+	ctx, releaseClass, err := store.holdClass(ctx, st)
 	if err != nil {
 		return nil, err
 	}
-	defer stmt.Close()
+	err = store.run(ctx, st, data, func(ctx context.Context) (err error) {
+		release, err := store.adhocStmt(st)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		results, err = store.stmtFor(ctx, st).QueryContext(ctx, data...)
+		return err
+	})
+	store.rowsReturned(st, results, err, releaseClass)
+	return results, err
+}
 
-	return stmt.Query(data...)
+// adhocStmt sets the statement for an ad-hoc Exec or Query, from the statement cache if one is
+// set, and returns a function to release it.
+func (store *SqlStore) adhocStmt(st *statement) (release func(), err error) {
+	if store.cache == nil || store.noPrepare {
+		if st.stmt, err = store.prepare(st.key, st.query); err != nil {
+			return nil, err
+		}
+		return func() { st.close() }, nil
+	}
+
+	cs, err := store.cache.acquire(st.query, store.prepareStatement)
+	if err != nil {
+		return nil, err
+	}
+	st.stmt = cs.stmt
+	return func() { store.cache.release(cs) }, nil
 }
 
 // PrepareStatement prepares a query and returns the statement to the caller, or error
 // if it is invalid.
 func (store *SqlStore) PrepareStatement(query string) (stmt *sql.Stmt, err error) {
+	if err := store.checkUnlocked(); err != nil {
+		return nil, err
+	}
+	return store.prepareStatement(query)
+}
+
+// prepareStatement prepares a query for internal use, regardless of LockRegistry.
+func (store *SqlStore) prepareStatement(query string) (stmt *sql.Stmt, err error) {
 	if !store.Connected {
 		return nil, &ConnectionError{}
 	}
 
-	stmt, err = store.db.Prepare(query)
+	stmt, err = store.db.Prepare(store.commentWith(query, nil))
 	if err != nil {
 		return nil, err
 	}
@@ -165,21 +427,39 @@ func (store *SqlStore) PrepareStatement(query string) (stmt *sql.Stmt, err error
 
 // PrepareAdd creates a prepared statement and safely adds it to our map with the provided key.
 func (store *SqlStore) PrepareAdd(key, query string) (err error) {
+	return store.PrepareAddWithOptions(key, query)
+}
+
+// PrepareAddWithOptions creates a prepared statement with the supplied options and safely adds
+// it to our map with the provided key.
+func (store *SqlStore) PrepareAddWithOptions(key, query string, opts ...StmtOption) (err error) {
 	if !store.Connected {
 		return &ConnectionError{}
 	}
+	if err := store.checkUnlocked(); err != nil {
+		return err
+	}
+	if err := store.checkRead(query); err != nil {
+		return err
+	}
 
-	stmt, err := store.PrepareStatement(query)
+	stmt, err := store.prepare(key, query)
+	store.logEvent(context.Background(), "godbm: prepare statement", err, slog.String("key", key))
 	if err != nil {
 		return err
 	}
+
+	st := &statement{key: key, stmt: stmt, query: query, params: countParams(query), registered: time.Now()}
+	for _, opt := range opts {
+		opt(&st.opts)
+	}
 	defer store.Unlock()
 
 	store.Lock()
 	if store.queries != nil {
-		store.queries[key] = stmt
+		store.queries[key] = st
 	} else {
-		store.queries = map[string]*sql.Stmt{key: stmt}
+		store.queries = map[string]*statement{key: st}
 	}
 	return nil
 }
@@ -189,58 +469,251 @@ func (store *SqlStore) PrepareDel(key string) (err error) {
 	if !store.Connected {
 		return &ConnectionError{}
 	}
+	if err := store.checkUnlocked(); err != nil {
+		return err
+	}
 	defer store.Unlock()
 
 	store.Lock()
-	stmt, found := store.queries[key]
+	st, found := store.queries[key]
 	if !found {
 		return nil
 	}
-	err = stmt.Close()
+	err = st.close()
 	delete(store.queries, key)
+	store.logEvent(context.Background(), "godbm: delete statement", err, slog.String("key", key))
 	return err
 }
 
 // returns true if the statement has been added
 func (store *SqlStore) HasStatement(key string) bool {
+	_, err := store.lookup(key)
+	return err == nil
+}
+
+// lookup safely returns the statement registered under key, or under its default version, or
+// an UnknownStmtError.
+func (store *SqlStore) lookup(key string) (*statement, error) {
 	store.RLock()
-	_, found := store.queries[key]
+	if version, ok := store.versions[key]; ok {
+		key = versionedKey(key, version)
+	}
+	st, found := store.queries[key]
 	store.RUnlock()
-	return found
+	if !found {
+		return nil, &UnknownStmtError{StmtKey: key}
+	}
+	return st, nil
 }
 
 // QueryPrepared executes a prepared statement which is looked up by the provided key. If the key was
 // not found, an UnknownStmtError is returned. This method takes a variable number of arguments to
 // pass to the underlying statement and returns *sql.Rows or an error.
 func (store *SqlStore) QueryPrepared(key string, data ...interface{}) (rows *sql.Rows, err error) {
+	return store.QueryPreparedContext(context.Background(), key, data...)
+}
+
+// QueryPreparedContext is the same as QueryPrepared but takes a context which is passed to the
+// underlying statement.
+func (store *SqlStore) QueryPreparedContext(ctx context.Context, key string, data ...interface{}) (rows *sql.Rows, err error) {
 	if !store.Connected {
 		return nil, &ConnectionError{}
 	}
-	defer store.RUnlock()
 
-	store.RLock()
-	stmt, found := store.queries[key]
-	if !found {
-		return nil, &UnknownStmtError{StmtKey: key}
+	st, err := store.lookup(key)
+	if err != nil {
+		return nil, err
 	}
-	return stmt.Query(data...)
+	return store.queryStatement(ctx, st, data...)
+}
+
+// queryStatement queries a registered statement, applying its options.
+func (store *SqlStore) queryStatement(ctx context.Context, st *statement, data ...interface{}) (rows *sql.Rows, err error) {
+	if data, err = store.convertArgs(st.markEncrypted(data)); err != nil {
+		return nil, err
+	}
+	if err := st.checkParams(data); err != nil {
+		return nil, err
+	}
+	if needsSessionTx(ctx) {
+		return nil, ErrSessionSettingsNeedTx
+	}
+	if lock, ok := rowLock(ctx); ok {
+		if _, inTx := TxFromContext(ctx); !inTx {
+			return nil, ErrRowLockNeedsTx
+		}
+		if st, err = store.lockedStmt(st, lock); err != nil {
+			return nil, err
+		}
+	}
+
+	if st.opts.timeout > 0 {
+		// the rows are only valid while the context is, so leave the cancel to the deadline
+		// unless the query failed outright.
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, st.opts.timeout)
+		defer func() {
+			if err != nil {
+				cancel()
+			} else {
+				time.AfterFunc(st.opts.timeout, cancel)
+			}
+		}()
+	}
+
+	ctx, releaseClass, err := store.holdClass(ctx, st)
+	if err != nil {
+		return nil, err
+	}
+	err = store.run(ctx, st, data, func(ctx context.Context) (err error) {
+		stmt, r := store.readStmt(ctx, st)
+		rows, err = stmt.QueryContext(ctx, data...)
+		if r != nil && IsConnectionError(err) {
+			store.setReplicaHealth(r, err)
+		}
+		return err
+	})
+	store.rowsReturned(st, rows, err, releaseClass)
+	return rows, err
+}
+
+// rowsReturned tracks the rows a query returned and releases the query's pool class slot once
+// they are closed, or straight away if the query failed.
+func (store *SqlStore) rowsReturned(st *statement, rows *sql.Rows, err error, releaseClass func()) {
+	if err != nil {
+		if releaseClass != nil {
+			releaseClass()
+		}
+		return
+	}
+	store.trackRows(st.key, rows)
+	releaseOnClose(rows, releaseClass)
 }
 
 // ExecPrepared executes a prepared statement which is looked up by the provided key. If the key was
 // not found, an UnknownStmtError is returned. This method takes a variable number of arguments to
 // pass to the underlying statement and returns sql.Result or an error.
 func (store *SqlStore) ExecPrepared(key string, data ...interface{}) (result sql.Result, err error) {
+	return store.ExecPreparedContext(context.Background(), key, data...)
+}
+
+// ExecPreparedContext is the same as ExecPrepared but takes a context which is passed to the
+// underlying statement.
+func (store *SqlStore) ExecPreparedContext(ctx context.Context, key string, data ...interface{}) (result sql.Result, err error) {
 	if !store.Connected {
 		return nil, &ConnectionError{}
 	}
-	defer store.RUnlock()
 
-	store.RLock()
-	stmt, found := store.queries[key]
-	if !found {
-		return nil, &UnknownStmtError{StmtKey: key}
+	st, err := store.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	return store.execStatement(ctx, st, data...)
+}
+
+// execStatement executes a registered statement, applying its options.
+func (store *SqlStore) execStatement(ctx context.Context, st *statement, data ...interface{}) (result sql.Result, err error) {
+	if data, err = store.convertArgs(st.markEncrypted(data)); err != nil {
+		return nil, err
+	}
+	if err := store.checkWrite(); err != nil {
+		return nil, err
+	}
+	if err := st.checkParams(data); err != nil {
+		return nil, err
+	}
+	if store.dryRun == DryRunValidate {
+		store.logDryRun(ctx, st, data)
+		return dryRunResult{}, nil
+	}
+
+	if st.opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, st.opts.timeout)
+		defer cancel()
 	}
-	return stmt.Exec(data...)
+
+	exec := func(ctx context.Context) (result sql.Result, err error) {
+		if t, ok := callTimeouts(ctx); ok {
+			result, err = store.execWithTimeouts(ctx, t, st, data...)
+		} else {
+			result, err = store.stmtFor(ctx, st).ExecContext(ctx, data...)
+		}
+		if err == nil {
+			err = store.audit(ctx, st, data, result)
+		}
+		return result, err
+	}
+
+	err = store.run(ctx, st, data, func(ctx context.Context) (err error) {
+		if store.dryRun == DryRunRollback {
+			result, err = store.execRolledBack(ctx, exec)
+			return err
+		} else if needsSessionTx(ctx) || store.needsAuditTx(ctx) {
+			err = store.WithTransaction(ctx, func(ctx context.Context, tx *Tx) (err error) {
+				result, err = exec(ctx)
+				return err
+			})
+		} else {
+			result, err = exec(ctx)
+		}
+		if isReadOnlyError(err) && strings.Contains(store.host, ",") {
+			store.reresolve()
+		}
+		if err == nil {
+			store.afterExec(ctx, st, result)
+		}
+		return err
+	})
+	return result, err
+}
+
+// run calls fn for the statement, retrying according to the store's RetryPolicy if the statement
+// was registered as idempotent and is not part of a transaction. Every attempt passes through
+// the circuit breaker if one is set and the call as a whole is reported to any registered hooks
+// and the logger.
+func (store *SqlStore) run(ctx context.Context, st *statement, args []interface{}, fn func(ctx context.Context) error) (err error) {
+	visible := store.redact(st, args)
+	for _, hook := range store.hooks {
+		ctx = hook.BeforeQuery(ctx, st.key, st.query, visible)
+	}
+	start := time.Now()
+	stopWatch := store.watchSlowLock(st, start)
+	defer func() {
+		stopWatch()
+		duration := time.Since(start)
+		for i := len(store.hooks) - 1; i >= 0; i-- {
+			store.hooks[i].AfterQuery(ctx, st.key, duration, err)
+		}
+		store.count(st, err)
+		store.logQuery(ctx, st, visible, duration, err)
+		store.checkSlow(st, args, duration)
+		store.samplePlan(st, args, err)
+	}()
+
+	if store.limited(st) {
+		call := fn
+		fn = func(ctx context.Context) error {
+			release, err := store.limit(ctx, st)
+			if err != nil {
+				return err
+			}
+			defer release()
+			return call(ctx)
+		}
+	}
+	if store.breaker != nil {
+		call := fn
+		fn = func(ctx context.Context) error {
+			return store.breaker.do(ctx, call)
+		}
+	}
+
+	// retrying a single statement inside a transaction would not retry the transaction.
+	if _, inTx := TxFromContext(ctx); inTx || store.retry == nil || !st.opts.idempotent {
+		return fn(ctx)
+	}
+	return store.retry.do(ctx, fn)
 }
 
 // CopyStart opens up a transaction for us with the provided table and column names. Returns the transaction
@@ -250,6 +723,12 @@ func (store *SqlStore) CopyStart(table string, columns ...string) (txn *sql.Tx,
 	if !store.Connected {
 		return nil, nil, &ConnectionError{}
 	}
+	if err := store.checkWrite(); err != nil {
+		return nil, nil, err
+	}
+	if !store.isPQ() {
+		return nil, nil, ErrCopyUnsupported
+	}
 
 	txn, err = store.db.Begin()
 	if err != nil {
@@ -264,11 +743,17 @@ func (store *SqlStore) CopyStartWithTxn(txn *sql.Tx, table string, columns ...st
 	if !store.Connected {
 		return nil, &ConnectionError{}
 	}
+	if err := store.checkWrite(); err != nil {
+		return nil, err
+	}
 	return store.copyStart(txn, table, columns...)
 }
 
 // Prepares the transaction for pq.CopyIn.
 func (store *SqlStore) copyStart(txn *sql.Tx, table string, columns ...string) (stmt *sql.Stmt, err error) {
+	if !store.isPQ() {
+		return nil, ErrCopyUnsupported
+	}
 	stmt, err = txn.Prepare(pq.CopyIn(table, columns...))
 	if err != nil {
 		return nil, err
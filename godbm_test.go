@@ -1,7 +1,14 @@
 package godbm
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 )
 
 const (
@@ -141,6 +148,1716 @@ func TestPreparedInsertAndQuery(t *testing.T) {
 
 }
 
+func TestForEachRow(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	createTestTable(t, dbm)
+
+	if _, err := dbm.Exec("insert into test (val1, val2, val3) values ('boop', 'zoop', 1), ('boop', 'zoop', 2)"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := dbm.PrepareAdd("get", "select val3 from test where val1 = $1 order by val3"); err != nil {
+		t.Fatal(err)
+	}
+
+	var vals []int
+	err = dbm.ForEachRow("get", func(scan func(dest ...interface{}) error) error {
+		var val3 int
+		if err := scan(&val3); err != nil {
+			return err
+		}
+		vals = append(vals, val3)
+		return nil
+	}, "boop")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(vals) != 2 || vals[0] != 1 || vals[1] != 2 {
+		t.Fatalf("Error returned values are not correct, got back: %v\n", vals)
+	}
+}
+
+func TestQueryPreparedMaps(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	createTestTable(t, dbm)
+
+	if _, err := dbm.Exec("insert into test (val1, val2, val3) values ('boop', null, 3)"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := dbm.PrepareAdd("get", "select * from test where val3 = $1"); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := dbm.QueryPreparedMaps("get", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 1 || rows[0]["val1"] != "boop" || rows[0]["val2"] != nil || rows[0]["val3"] != int64(3) {
+		t.Fatalf("Error returned values are not correct, got back: %v\n", rows)
+	}
+}
+
+func TestQueryJSON(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	if err := dbm.PrepareAdd("get", "select 'boop' as val1, 3 as val3, '{\"a\": 1}'::jsonb as payload"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := dbm.QueryJSON(context.Background(), "get", &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `[{"val1":"boop","val3":3,"payload":{"a": 1}}]`
+	if buf.String() != expected {
+		t.Fatalf("expected %s got %s\n", expected, buf.String())
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	createTestTable(t, dbm)
+
+	if _, err := dbm.Exec("insert into test (val1, val2, val3) select 'boop', 'zoop', i from generate_series(1, 5) i"); err != nil {
+		t.Fatal(err)
+	}
+
+	err = dbm.PrepareAddWithOptions("get", "select * from test where val1 = $1", Paginated(Pagination{KeyColumn: "val3"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var vals []int64
+	req := PageRequest{Limit: 2}
+	for pages := 0; ; pages++ {
+		page, err := dbm.Paginate(context.Background(), "get", req, "boop")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, row := range page.Rows {
+			vals = append(vals, row["val3"].(int64))
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		if pages > 5 {
+			t.Fatalf("expected pagination to finish\n")
+		}
+		req.Cursor = page.NextCursor
+	}
+
+	if len(vals) != 5 || vals[0] != 1 || vals[4] != 5 {
+		t.Fatalf("Error returned values are not correct, got back: %v\n", vals)
+	}
+
+	if _, err := dbm.Exec("insert into test (val1, val2) values ('blank', 'zoop'), ('blank', 'zoop')"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbm.Paginate(context.Background(), "get", PageRequest{Limit: 1}, "blank"); err == nil {
+		t.Fatalf("expected an error rather than a cursor without a key\n")
+	}
+}
+
+func TestCountAndExists(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	createTestTable(t, dbm)
+
+	if _, err := dbm.Exec("insert into test (val1, val2, val3) values ('boop', 'zoop', 1), ('boop', 'zoop', 2)"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := dbm.PrepareAdd("count", "select count(*) from test where val1 = $1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dbm.PrepareAdd("exists", "select exists(select 1 from test where val3 = $1)"); err != nil {
+		t.Fatal(err)
+	}
+
+	if count, err := dbm.CountPrepared("count", "boop"); err != nil || count != 2 {
+		t.Fatalf("expected a count of 2, got %d %v\n", count, err)
+	}
+
+	if exists, err := dbm.ExistsPrepared("exists", 3); err != nil || exists {
+		t.Fatalf("expected val3 = 3 to not exist, got %v %v\n", exists, err)
+	}
+}
+
+func TestNestedTransaction(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	createTestTable(t, dbm)
+
+	if err := dbm.PrepareAdd("insert", "insert into test (val1, val2, val3) values ($1, $2, $3)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dbm.PrepareAdd("count", "select count(*) from test"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	err = dbm.WithTransaction(ctx, func(ctx context.Context, tx *Tx) error {
+		if _, err := dbm.ExecPreparedContext(ctx, "insert", "boop", "zoop", 1); err != nil {
+			return err
+		}
+
+		nestedErr := dbm.WithTransaction(ctx, func(ctx context.Context, tx *Tx) error {
+			if _, err := tx.ExecPrepared("insert", "boop", "zoop", 2); err != nil {
+				return err
+			}
+			return errors.New("undo the nested insert")
+		})
+		if nestedErr == nil {
+			t.Fatalf("expected nested transaction to return its error\n")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count, err := dbm.CountPrepared("count"); err != nil || count != 1 {
+		t.Fatalf("expected only the outer insert to be committed, got %d %v\n", count, err)
+	}
+}
+
+func TestReadOnlyTransaction(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	createTestTable(t, dbm)
+
+	if err := dbm.PrepareAdd("insert", "insert into test (val1, val2, val3) values ($1, $2, $3)"); err != nil {
+		t.Fatal(err)
+	}
+
+	err = dbm.WithReadOnlyTx(context.Background(), func(ctx context.Context, tx *Tx) error {
+		_, err := tx.ExecPrepared("insert", "boop", "zoop", 1)
+		return err
+	})
+	if err == nil {
+		t.Fatalf("expected insert in a read only transaction to fail\n")
+	}
+}
+
+func TestPrepareFS(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	fsys := fstest.MapFS{
+		"users/get.sql": {Data: []byte("select $1::int")},
+		"README.md":     {Data: []byte("not a statement")},
+	}
+	if err := dbm.PrepareFS(fsys); err != nil {
+		t.Fatalf("error preparing statements: %v\n", err)
+	}
+
+	if !dbm.HasStatement("users/get") || dbm.HasStatement("README") {
+		t.Fatalf("expected only .sql files to be registered\n")
+	}
+}
+
+func TestSessionSettings(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	if err := dbm.PrepareAdd("tenant", "select current_setting('app.current_tenant', true)"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := WithSessionSettings(context.Background(), map[string]string{"app.current_tenant": "acme"})
+	if _, err := dbm.QueryPreparedContext(ctx, "tenant"); err != ErrSessionSettingsNeedTx {
+		t.Fatalf("expected queries outside a transaction to be refused, got: %v\n", err)
+	}
+
+	err = dbm.WithTransaction(ctx, func(ctx context.Context, tx *Tx) error {
+		var tenant string
+		if err := tx.QueryRowContext(ctx, "select current_setting('app.current_tenant')").Scan(&tenant); err != nil {
+			return err
+		}
+		if tenant != "acme" {
+			t.Fatalf("expected the tenant to be set, got: %s\n", tenant)
+		}
+
+		if err := tx.SetLocal("app.current_tenant", "globex"); err != nil {
+			return err
+		}
+		return tx.QueryRowContext(ctx, "select current_setting('app.current_tenant')").Scan(&tenant)
+	})
+	if err != nil {
+		t.Fatalf("error in transaction: %v\n", err)
+	}
+}
+
+func TestTenant(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	for _, schema := range []string{"tenant_a", "tenant_b"} {
+		if _, err := dbm.Exec("create schema if not exists " + schema); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := dbm.Exec("create table if not exists " + schema + ".settings (name text)"); err != nil {
+			t.Fatal(err)
+		}
+		defer dbm.Exec("drop schema " + schema + " cascade")
+	}
+
+	if err := dbm.PrepareAdd("add", "insert into settings (name) values ($1)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dbm.PrepareAdd("count", "select count(*) from settings"); err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := dbm.ForTenant("tenant_a"), dbm.ForTenant("tenant_b")
+	if _, err := a.ExecPreparedContext(context.Background(), "add", "theme"); err != nil {
+		t.Fatalf("error inserting for tenant: %v\n", err)
+	}
+
+	err = dbm.WithTransaction(context.Background(), func(ctx context.Context, tx *Tx) error {
+		for tenant, want := range map[*Tenant]int{a: 1, b: 0} {
+			rows, err := tenant.QueryPreparedContext(ctx, "count")
+			if err != nil {
+				return err
+			}
+			var count int
+			rows.Next()
+			rows.Scan(&count)
+			rows.Close()
+			if count != want {
+				t.Fatalf("expected %d rows for %s, got %d\n", want, tenant.Name(), count)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("error in transaction: %v\n", err)
+	}
+}
+
+func TestAuditTable(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	createTestTable(t, dbm)
+	if _, err := dbm.Exec("create table if not exists audit_log (stmt_key text, actor text, at timestamptz, rows_affected bigint)"); err != nil {
+		t.Fatal(err)
+	}
+	defer dbm.Exec("drop table audit_log")
+	dbm.SetAudit(&AuditOptions{Sink: NewTableAuditSink(dbm, "audit_log"), InTransaction: true})
+
+	if err := dbm.PrepareAdd("insert", "insert into test (val1, val2, val3) values ($1, $2, $3)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbm.ExecPreparedContext(WithActor(context.Background(), "alice"), "insert", "a", "b", 1); err != nil {
+		t.Fatalf("error executing audited statement: %v\n", err)
+	}
+
+	if count, err := dbm.Query("select count(*) from audit_log where actor = 'alice' and stmt_key = 'insert'"); err != nil {
+		t.Fatal(err)
+	} else {
+		var n int
+		count.Next()
+		count.Scan(&n)
+		count.Close()
+		if n != 1 {
+			t.Fatalf("expected an audit record, got %d\n", n)
+		}
+	}
+}
+
+func TestInsertStruct(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	if _, err := dbm.Exec("create table if not exists struct_users (id serial primary key, email text, name text, created_at timestamptz default now())"); err != nil {
+		t.Fatal(err)
+	}
+	defer dbm.Exec("drop table struct_users")
+
+	u := &structUser{Email: "a@example.com", Name: "a"}
+	if err := dbm.InsertStruct("struct_users", u); err != nil {
+		t.Fatalf("error inserting struct: %v\n", err)
+	}
+	if u.ID == 0 || u.Created.IsZero() {
+		t.Fatalf("expected generated columns to be read back, got %+v\n", u)
+	}
+
+	u.Name = "b"
+	if n, err := dbm.UpdateStruct("struct_users", u, "id"); err != nil || n != 1 {
+		t.Fatalf("expected one row updated, got %d %v\n", n, err)
+	}
+}
+
+func TestRowLockSkipLocked(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	createTestTable(t, dbm)
+	if _, err := dbm.Exec("insert into test (val1, val2, val3) values ('a', 'a', 1), ('b', 'b', 2)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dbm.PrepareAdd("next", "select val3 from test order by val3 limit 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	lock := RowLock{Strength: ForUpdate, Wait: SkipLocked}
+	next := func(ctx context.Context) (val int) {
+		err := dbm.ForEachRowContext(WithRowLock(ctx, lock), "next", func(scan func(dest ...interface{}) error) error {
+			return scan(&val)
+		})
+		if err != nil {
+			t.Fatalf("error locking row: %v\n", err)
+		}
+		return val
+	}
+
+	err = dbm.WithTransaction(context.Background(), func(ctx context.Context, tx *Tx) error {
+		first := next(ctx)
+		return dbm.WithTransaction(context.Background(), func(ctx context.Context, tx *Tx) error {
+			if second := next(ctx); second == first {
+				t.Fatalf("expected the locked row to be skipped\n")
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestArrayArgs(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	createTestTable(t, dbm)
+	if _, err := dbm.Exec("insert into test (val1, val2, val3) values ('a', 'a', 1), ('b', 'b', 2), ('c', 'c', 3)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dbm.PrepareAdd("any", "select array_agg(val1 order by val1) from test where val3 = any($1)"); err != nil {
+		t.Fatal(err)
+	}
+
+	var vals []string
+	err = dbm.ForEachRow("any", func(scan func(dest ...interface{}) error) error {
+		return scan(Array(&vals))
+	}, []int64{1, 3})
+	if err != nil || len(vals) != 2 || vals[0] != "a" || vals[1] != "c" {
+		t.Fatalf("unexpected array result: %v %v\n", vals, err)
+	}
+}
+
+func TestJSONArgs(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	if err := dbm.PrepareAdd("echo_json", "select $1::jsonb"); err != nil {
+		t.Fatal(err)
+	}
+
+	var out JSONColumn[map[string]int]
+	err = dbm.ForEachRow("echo_json", func(scan func(dest ...interface{}) error) error {
+		return scan(&out)
+	}, map[string]int{"a": 1})
+	if err != nil || !out.Valid || out.V["a"] != 1 {
+		t.Fatalf("unexpected json result: %+v %v\n", out, err)
+	}
+}
+
+func TestQueryPreparedStructs(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	createTestTable(t, dbm)
+	if _, err := dbm.Exec("insert into test (val1, val2, val3) values ('a', null, 1), ('b', 'b', null)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dbm.PrepareAdd("all", "select val1, val2, val3 from test order by val1"); err != nil {
+		t.Fatal(err)
+	}
+
+	type row struct {
+		Val1 string
+		Val2 *string
+		Val3 Optional[int64]
+	}
+	var rows []row
+	if err := dbm.QueryPreparedStructs("all", &rows); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Val2 != nil || rows[0].Val3.Or(0) != 1 || *rows[1].Val2 != "b" || rows[1].Val3.Valid {
+		t.Fatalf("unexpected rows: %+v\n", rows)
+	}
+}
+
+func TestListenNotify(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	received := make(chan Notification, 1)
+	go dbm.Listen(ctx, func(n Notification) {
+		if !n.Reconnected {
+			received <- n
+		}
+	}, "godbm_test")
+
+	// the listener connects in the background, so keep notifying until it hears one.
+	for {
+		if err := dbm.Notify(ctx, "godbm_test", "hello"); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case n := <-received:
+			if n.Channel != "godbm_test" || n.Payload != "hello" {
+				t.Fatalf("unexpected notification: %+v\n", n)
+			}
+			return
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+			t.Fatalf("expected a notification\n")
+		}
+	}
+}
+
+func TestBatch(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	createTestTable(t, dbm)
+	if err := dbm.PrepareAdd("insert", "insert into test (val1, val2, val3) values ($1, $2, $3)"); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := dbm.Batch().Queue("insert", "a", "a", 1).Queue("insert", "b", "b", 2).Exec(context.Background())
+	if err != nil || len(results) != 2 {
+		t.Fatalf("unexpected batch results: %v %v\n", results, err)
+	}
+
+	// the second insert fails as val1 is too long, so neither is committed.
+	_, err = dbm.Batch().Queue("insert", "c", "c", 3).Queue("insert", "toolong", "d", 4).Exec(context.Background())
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || batchErr.Index != 1 {
+		t.Fatalf("expected a BatchError got %v\n", err)
+	}
+	if count, err := dbm.Query("select 1 from test where val3 = 3"); err != nil || count.Next() {
+		t.Fatalf("expected the failed batch to be rolled back\n")
+	}
+}
+
+func TestExecScript(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	script := `
+create or replace function godbm_script_test() returns int as $$
+begin
+	return 42;
+end;
+$$ language plpgsql;
+select godbm_script_test();
+drop function godbm_script_test();
+`
+	if err := dbm.ExecScript(context.Background(), strings.NewReader(script)); err != nil {
+		t.Fatal(err)
+	}
+
+	err = dbm.ExecScript(context.Background(), strings.NewReader("select 1;\nselect * from godbm_missing_table;"))
+	var scriptErr *ScriptError
+	if !errors.As(err, &scriptErr) || scriptErr.Statement != 2 || scriptErr.Line != 2 {
+		t.Fatalf("expected a ScriptError for the second statement got %v\n", err)
+	}
+}
+
+func TestQueryCursor(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	if err := dbm.PrepareAdd("series", "select i from generate_series(1, $1::int) i"); err != nil {
+		t.Fatal(err)
+	}
+
+	cursor, err := dbm.QueryCursor(context.Background(), "series", 3, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sum, rows int
+	for cursor.Next() {
+		var i int
+		if err := cursor.Scan(&i); err != nil {
+			t.Fatal(err)
+		}
+		sum += i
+		rows++
+	}
+	if err := cursor.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cursor.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if rows != 10 || sum != 55 {
+		t.Fatalf("expected 10 rows summing to 55 got %d %d\n", rows, sum)
+	}
+}
+
+func TestScanTableInChunks(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	createTestTable(t, dbm)
+	if _, err := dbm.Exec("insert into test (val1, val2, val3) select 'a', 'b', i from generate_series(1, 7) i"); err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []int64
+	var checkpoint interface{}
+	stop := errors.New("stop")
+	err = dbm.ScanTableInChunks(context.Background(), "test", "val3", 3, func(ctx context.Context, chunk Chunk) error {
+		for _, row := range chunk.Rows {
+			keys = append(keys, row["val3"].(int64))
+		}
+		checkpoint = chunk.LastKey
+		if chunk.Number == 2 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || checkpoint != int64(6) {
+		t.Fatalf("expected the scan to stop after the second chunk: %v %v\n", err, checkpoint)
+	}
+
+	err = dbm.ScanTableInChunksFrom(context.Background(), "test", "val3", checkpoint, 3, func(ctx context.Context, chunk Chunk) error {
+		for _, row := range chunk.Rows {
+			keys = append(keys, row["val3"].(int64))
+		}
+		return nil
+	})
+	if err != nil || len(keys) != 7 || keys[6] != 7 {
+		t.Fatalf("unexpected keys after resuming: %v %v\n", keys, err)
+	}
+}
+
+func TestDeleteInBatches(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	createTestTable(t, dbm)
+	if _, err := dbm.Exec("insert into test (val1, val2, val3) select 'a', 'b', i from generate_series(1, 10) i"); err != nil {
+		t.Fatal(err)
+	}
+	err = dbm.PrepareAdd("purge", "delete from test where ctid in (select ctid from test where val3 <= $1 limit $2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var batches []int64
+	total, err := dbm.DeleteInBatches(context.Background(), "purge", 3, time.Millisecond, func(p BatchProgress) {
+		batches = append(batches, p.RowsAffected)
+	}, 8)
+	if err != nil || total != 8 || len(batches) != 4 || batches[2] != 2 || batches[3] != 0 {
+		t.Fatalf("unexpected batches: %v %v %v\n", total, batches, err)
+	}
+}
+
+func TestRunRetention(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.SetRetention(&RetentionOptions{Policies: []RetentionPolicy{{Table: "events", Column: "created", MaxAge: time.Hour, BatchSize: 2}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = dbm.Connect(); err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+	defer dbm.Exec("drop table events")
+
+	if _, err := dbm.Exec("create table events (id int, created timestamptz)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbm.Exec("insert into events select i, now() - i * interval '1 minute' * 20 from generate_series(1, 10) i"); err != nil {
+		t.Fatal(err)
+	}
+
+	purged, err := dbm.RunRetention(context.Background())
+	if err != nil || purged["events"] != 8 || dbm.Stats().Purged != 8 {
+		t.Fatalf("unexpected retention: %v %v %v\n", purged, dbm.Stats().Purged, err)
+	}
+}
+
+func TestRunRetentionPartitioned(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.SetRetention(&RetentionOptions{Policies: []RetentionPolicy{{Table: "events", Column: "created", MaxAge: time.Hour}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = dbm.Connect(); err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+	defer dbm.Exec("drop table events")
+
+	for _, query := range []string{
+		"create table events (region text, created timestamptz) partition by list (region)",
+		"create table events_old partition of events for values in ('old')",
+		"create table events_new partition of events for values in ('new')",
+		// the first row of each partition has the same ctid.
+		"insert into events values ('old', now() - interval '2 hours'), ('new', now())",
+	} {
+		if _, err := dbm.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	purged, err := dbm.RunRetention(context.Background())
+	if err != nil || purged["events"] != 1 {
+		t.Fatalf("unexpected retention: %v %v\n", purged, err)
+	}
+	var left int
+	if err := dbm.Db().QueryRow("select count(*) from events where region = 'new'").Scan(&left); err != nil || left != 1 {
+		t.Fatalf("expected the unexpired row in the other partition to be kept: %d %v\n", left, err)
+	}
+}
+
+func TestPartitions(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+	defer dbm.Exec("drop table if exists measurements")
+
+	if _, err := dbm.Exec("create table measurements (taken timestamptz, value int) partition by range (taken)"); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	created, err := dbm.CreatePartitions(ctx, "measurements", PartitionMonthly, 2)
+	if err != nil || len(created) != 3 {
+		t.Fatalf("unexpected partitions created: %v %v\n", created, err)
+	}
+	if created, err = dbm.CreatePartitions(ctx, "measurements", PartitionMonthly, 3); err != nil || len(created) != 1 {
+		t.Fatalf("expected only the missing partition to be created: %v %v\n", created, err)
+	}
+
+	old := time.Now().UTC().AddDate(-1, 0, 0)
+	if _, err := dbm.Exec("create table measurements_old (taken timestamptz, value int)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dbm.AttachPartition(ctx, "measurements", "measurements_old", old, old.AddDate(0, 0, 1)); err != nil {
+		t.Fatalf("error attaching partition: %v\n", err)
+	}
+	dropped, err := dbm.DropPartitionsOlderThan(ctx, "measurements", time.Now().AddDate(0, -1, 0))
+	if err != nil || len(dropped) != 1 || dropped[0] != "measurements_old" {
+		t.Fatalf("unexpected partitions dropped: %v %v\n", dropped, err)
+	}
+
+	partitions, err := dbm.Partitions(ctx, "measurements")
+	if err != nil || len(partitions) != 4 {
+		t.Fatalf("unexpected partitions: %v %v\n", partitions, err)
+	}
+	if err := dbm.DetachPartition(ctx, "measurements", partitions[0].Name, false); err != nil {
+		t.Fatalf("error detaching partition: %v\n", err)
+	}
+}
+
+func TestRefreshMaterializedView(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+	defer dbm.Exec("drop materialized view if exists test_totals")
+
+	createTestTable(t, dbm)
+	if _, err := dbm.Exec("create materialized view test_totals as select val1, sum(val3) total from test group by val1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbm.Exec("create unique index on test_totals (val1)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := dbm.LastRefreshed("test_totals"); ok {
+		t.Fatalf("expected no refresh yet\n")
+	}
+
+	ctx := context.Background()
+	if err := dbm.RefreshMaterializedView(ctx, "test_totals", true); err != nil {
+		t.Fatalf("error refreshing: %v\n", err)
+	}
+	if _, ok := dbm.LastRefreshed("test_totals"); !ok {
+		t.Fatalf("expected the refresh to be recorded\n")
+	}
+
+	err = dbm.WithTransaction(ctx, func(ctx context.Context, tx *Tx) error {
+		if _, err := dbm.ExecContext(ctx, "select pg_advisory_xact_lock(hashtext('godbm.matview'), hashtext('test_totals'))"); err != nil {
+			return err
+		}
+		if err := dbm.RefreshMaterializedView(context.Background(), "test_totals", false); err != ErrRefreshInProgress {
+			t.Fatalf("expected the refresh to be skipped, got %v\n", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEnsureIndex(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	createTestTable(t, dbm)
+	if _, err := dbm.Exec("insert into test (val1, val2, val3) values ('a', 'b', 1), ('a', 'c', 1)"); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// a unique index on duplicate values fails, which must not leave an invalid index behind.
+	spec := IndexSpec{Name: "test_val3_idx", Table: "test", Columns: []string{"val3"}, Unique: true}
+	if _, err := dbm.EnsureIndex(ctx, spec); err == nil {
+		t.Fatalf("expected the unique index to fail\n")
+	}
+	if _, exists, err := dbm.indexValid(ctx, "test_val3_idx"); exists || err != nil {
+		t.Fatalf("expected the failed index to be dropped: %v\n", err)
+	}
+
+	spec.Unique = false
+	if created, err := dbm.EnsureIndex(ctx, spec); !created || err != nil {
+		t.Fatalf("error creating index: %v %v\n", created, err)
+	}
+	if created, err := dbm.EnsureIndex(ctx, spec); created || err != nil {
+		t.Fatalf("expected the existing index to be kept: %v %v\n", created, err)
+	}
+}
+
+func TestActiveQueries(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.SetApplicationName("godbm_activity")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	ctx := context.Background()
+	sleeping := make(chan error, 1)
+	go func() {
+		_, err := dbm.ExecContext(ctx, "select pg_sleep(10)")
+		sleeping <- err
+	}()
+
+	var pid int
+	for i := 0; i < 50 && pid == 0; i++ {
+		time.Sleep(20 * time.Millisecond)
+		activity, err := dbm.ActiveQueries(ctx)
+		if err != nil {
+			t.Fatalf("error reading activity: %v\n", err)
+		}
+		for _, a := range activity {
+			if a.State == "active" && strings.Contains(a.Query, "pg_sleep") && a.ApplicationName == "godbm_activity" {
+				pid = a.Pid
+			}
+		}
+	}
+	if pid == 0 {
+		t.Fatalf("expected to find the sleeping query\n")
+	}
+
+	if sent, err := dbm.CancelQuery(ctx, pid); !sent || err != nil {
+		t.Fatalf("error cancelling query: %v %v\n", sent, err)
+	}
+	if err := <-sleeping; SQLState(err) != "57014" {
+		t.Fatalf("expected the query to be cancelled, got %v\n", err)
+	}
+}
+
+func TestBlockedQueries(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	waits := make(chan SlowLock, 1)
+	dbm.SetSlowLockOptions(&SlowLockOptions{Threshold: 200 * time.Millisecond, Callback: func(l SlowLock) { waits <- l }})
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	createTestTable(t, dbm)
+	if _, err := dbm.Exec("insert into test (val1, val2, val3) values ('a', 'b', 1)"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	tx, err := dbm.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbm.ExecContext(withTx(ctx, tx), "update test set val3 = 2"); err != nil {
+		t.Fatal(err)
+	}
+	updated := make(chan error, 1)
+	go func() {
+		_, err := dbm.ExecContext(ctx, "update test set val3 = 3")
+		updated <- err
+	}()
+
+	var wait SlowLock
+	select {
+	case wait = <-waits:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the lock wait to be reported\n")
+	}
+	if wait.Query != "update test set val3 = 3" || len(wait.Blockers) != 1 || wait.Blockers[0].BlockingState != "idle in transaction" {
+		t.Fatalf("unexpected lock wait: %+v\n", wait)
+	}
+
+	blocked, err := dbm.BlockedQueries(ctx)
+	if err != nil || len(blocked) != 1 || blocked[0].LockType != "transactionid" {
+		t.Fatalf("unexpected blocked queries: %+v %v\n", blocked, err)
+	}
+	tx.Rollback()
+	if err := <-updated; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifySchema(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	createTestTable(t, dbm)
+	if _, err := dbm.Exec("create index test_val3_idx on test (val3)"); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	spec := SchemaSpec{Tables: []TableSpec{{
+		Name:    "test",
+		Columns: []ColumnSpec{{Name: "val1", Type: "varchar(5)"}, {Name: "val2"}, {Name: "val3", Type: "int"}},
+		Indexes: []string{"test_val3_idx"},
+	}}}
+	if err := dbm.VerifySchema(ctx, spec); err != nil {
+		t.Fatalf("expected the schema to match: %v\n", err)
+	}
+
+	spec.Tables[0].Columns = append(spec.Tables[0].Columns, ColumnSpec{Name: "val4"})
+	spec.Tables[0].Columns[2].Type = "bigint"
+	spec.Tables = append(spec.Tables, TableSpec{Name: "missing"})
+	var schemaErr *SchemaError
+	if err := dbm.VerifySchema(ctx, spec); !errors.As(err, &schemaErr) || len(schemaErr.Drift) != 3 {
+		t.Fatalf("unexpected drift: %v\n", err)
+	}
+}
+
+func TestPrepareTransaction(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	var maxPrepared string
+	if err := dbm.Db().QueryRow("show max_prepared_transactions").Scan(&maxPrepared); err != nil {
+		t.Fatal(err)
+	}
+	if maxPrepared == "0" {
+		t.Skip("prepared transactions are not enabled, max_prepared_transactions is 0")
+	}
+
+	createTestTable(t, dbm)
+	ctx := context.Background()
+	for _, id := range []string{"godbm_commit", "godbm_rollback"} {
+		tx, err := dbm.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := dbm.ExecContext(withTx(ctx, tx), "insert into test (val1, val2, val3) values ($1, 'b', 1)", id[6:11]); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.PrepareTransaction(id); err != nil {
+			t.Fatalf("error preparing transaction %s: %v\n", id, err)
+		}
+	}
+
+	prepared, err := dbm.PreparedTransactions(ctx)
+	if err != nil || len(prepared) != 2 || prepared[0].ID != "godbm_commit" {
+		t.Fatalf("unexpected prepared transactions: %v %v\n", prepared, err)
+	}
+	if err := dbm.CommitPrepared(ctx, "godbm_commit"); err != nil {
+		t.Fatalf("error committing prepared: %v\n", err)
+	}
+	if err := dbm.RollbackPrepared(ctx, "godbm_rollback"); err != nil {
+		t.Fatalf("error rolling back prepared: %v\n", err)
+	}
+
+	rows, err := dbm.Query("select val1 from test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var vals []string
+	for rows.Next() {
+		var val string
+		rows.Scan(&val)
+		vals = append(vals, val)
+	}
+	if len(vals) != 1 || vals[0] != "commi" {
+		t.Fatalf("expected only the committed row: %v\n", vals)
+	}
+}
+
+func TestAcquireLease(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+	defer dbm.Exec("drop table godbm_leases")
+
+	ctx := context.Background()
+	lease, err := dbm.AcquireLease(ctx, "reports", 300*time.Millisecond)
+	if err != nil {
+		t.Fatalf("error acquiring lease: %v\n", err)
+	}
+	if _, err := dbm.AcquireLease(ctx, "reports", time.Second); err != ErrLeaseHeld {
+		t.Fatalf("expected the lease to be held, got %v\n", err)
+	}
+
+	// renewal keeps the lease past its ttl.
+	time.Sleep(500 * time.Millisecond)
+	if _, err := dbm.AcquireLease(ctx, "reports", time.Second); err != ErrLeaseHeld {
+		t.Fatalf("expected the renewed lease to be held, got %v\n", err)
+	}
+	if err := lease.Release(ctx); err != nil {
+		t.Fatalf("error releasing lease: %v\n", err)
+	}
+
+	next, err := dbm.AcquireLease(ctx, "reports", time.Second)
+	if err != nil || next.Token != lease.Token+1 {
+		t.Fatalf("unexpected lease after release: %v %v\n", next, err)
+	}
+	next.Release(ctx)
+}
+
+func TestScheduledRun(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+	defer dbm.Exec("drop table godbm_schedule_runs")
+
+	createTestTable(t, dbm)
+	if _, err := dbm.Exec("insert into test (val1, val2, val3) values ('a', 'b', 1), ('a', 'b', 2)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dbm.PrepareAdd("purge", "delete from test where val3 < $1"); err != nil {
+		t.Fatal(err)
+	}
+	job := scheduledJob{name: "purge", key: "purge", args: []interface{}{2}}
+
+	ctx := context.Background()
+	ran, err := dbm.runScheduled(ctx, job)
+	if !ran || err != nil {
+		t.Fatalf("error running job: %v %v\n", ran, err)
+	}
+
+	// another process holding the job's lock makes the run skip.
+	err = dbm.WithTransaction(ctx, func(ctx context.Context, tx *Tx) error {
+		if locked, err := dbm.tryXactLock(ctx, "godbm.schedule", "purge"); !locked || err != nil {
+			t.Fatalf("error locking: %v %v\n", locked, err)
+		}
+		if ran, err := dbm.runScheduled(context.Background(), job); ran || err != nil {
+			t.Fatalf("expected the run to be skipped: %v %v\n", ran, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err := dbm.ScheduleHistory(ctx, "purge", 10)
+	if err != nil || len(runs) != 1 || runs[0].RowsAffected != 1 || runs[0].Err != "" {
+		t.Fatalf("unexpected history: %v %v\n", runs, err)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	createTestTable(t, dbm)
+
+	if err := dbm.PrepareAdd("get", "select val1, val3 from test where val3 = $1"); err != nil {
+		t.Fatal(err)
+	}
+	opts := GoldenOptions{Dir: t.TempDir(), Args: map[string][]interface{}{"get": {1}}}
+	if err := dbm.VerifyGolden(context.Background(), opts); err == nil {
+		t.Fatalf("expected a mismatch without golden files\n")
+	}
+	if err := dbm.RecordGolden(context.Background(), opts); err != nil {
+		t.Fatalf("error recording golden files: %v\n", err)
+	}
+	if err := dbm.VerifyGolden(context.Background(), opts); err != nil {
+		t.Fatalf("expected the golden files to match: %v\n", err)
+	}
+
+	if _, err := dbm.Exec("alter table test alter column val3 type bigint"); err != nil {
+		t.Fatal(err)
+	}
+	err = dbm.VerifyGolden(context.Background(), opts)
+	gerr, ok := err.(*GoldenError)
+	if !ok || len(gerr.Mismatches) != 1 || gerr.Mismatches[0].Key != "get" {
+		t.Fatalf("expected the columns of get to differ, got: %v\n", err)
+	}
+}
+
+func TestBenchmarkStatement(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	createTestTable(t, dbm)
+
+	if err := dbm.PrepareAdd("get", "select val1 from test where val3 = $1"); err != nil {
+		t.Fatal(err)
+	}
+	result, err := dbm.BenchmarkStatement(context.Background(), "get", func(i int) []interface{} { return []interface{}{i} }, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("error benchmarking: %v\n", err)
+	}
+	if result.Calls == 0 || result.P50 > result.P99 || result.Throughput <= 0 {
+		t.Fatalf("unexpected result: %v\n", result)
+	}
+
+	results, err := dbm.BenchmarkStatements(context.Background(), map[string][]interface{}{"get": {1}}, 50*time.Millisecond)
+	if err != nil || len(results) != 1 || results[0].Key != "get" {
+		t.Fatalf("unexpected results: %v %v\n", results, err)
+	}
+}
+
+func TestPrepareAddGroup(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	createTestTable(t, dbm)
+
+	err = dbm.PrepareAddGroup("test", map[string]string{"get": "select val1 from test", "count": "select count(*) from test"})
+	if err != nil {
+		t.Fatalf("error adding group: %v\n", err)
+	}
+	if keys := dbm.GroupKeys("test"); len(keys) != 2 || keys[0] != "test.count" || keys[1] != "test.get" {
+		t.Fatalf("unexpected keys: %v\n", keys)
+	}
+
+	err = dbm.PrepareAddGroup("test", map[string]string{"get": "select val2 from test", "bad": "select nope from test"})
+	if err == nil || !dbm.HasStatement("test.count") || dbm.HasStatement("test.bad") {
+		t.Fatalf("expected a failed group to leave the old one in place: %v\n", err)
+	}
+
+	if err := dbm.PrepareAddGroup("test", map[string]string{"get": "select val2 from test"}); err != nil {
+		t.Fatalf("error replacing group: %v\n", err)
+	}
+	if keys := dbm.GroupKeys("test"); len(keys) != 1 || keys[0] != "test.get" {
+		t.Fatalf("expected the group to be replaced, got: %v\n", keys)
+	}
+
+	if err := dbm.PrepareDelGroup("test"); err != nil || len(dbm.GroupKeys("test")) != 0 {
+		t.Fatalf("expected the group to be deleted: %v\n", err)
+	}
+}
+
+func TestQueryPreparedIn(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	createTestTable(t, dbm)
+	for i := 1; i <= 5; i++ {
+		if _, err := dbm.Exec("insert into test (val1, val2, val3) values ('a', 'b', $1)", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := dbm.PrepareAddIn("count", "select count(*) from test where val1 = $1 and val3 in (?)"); err != nil {
+		t.Fatalf("error adding statement: %v\n", err)
+	}
+	if err := dbm.PrepareAddIn("delete", "delete from test where val3 = any(?)"); err != nil {
+		t.Fatalf("error adding statement: %v\n", err)
+	}
+	if err := dbm.ValidateStatements(context.Background()); err != nil {
+		t.Fatalf("expected the statements to be valid: %v\n", err)
+	}
+
+	rows, err := dbm.QueryPreparedIn(context.Background(), "count", []int{1, 3, 5}, "a")
+	if err != nil {
+		t.Fatalf("error querying: %v\n", err)
+	}
+	var count int
+	for rows.Next() {
+		if err := rows.Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rows.Close()
+	if count != 3 {
+		t.Fatalf("expected 3 rows got %d\n", count)
+	}
+
+	result, err := dbm.ExecPreparedIn(context.Background(), "delete", []int{2, 4})
+	if err != nil {
+		t.Fatalf("error deleting: %v\n", err)
+	}
+	if affected, _ := result.RowsAffected(); affected != 2 {
+		t.Fatalf("expected 2 rows deleted got %d\n", affected)
+	}
+	if _, err := dbm.QueryPrepared("count", "a"); err != ErrNeedsInList {
+		t.Fatalf("expected ErrNeedsInList got %v\n", err)
+	}
+}
+
+func TestSearchStructs(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	createTestTable(t, dbm)
+	for i, words := range []string{"cats", "dogs", "cats dogs"} {
+		if _, err := dbm.Exec("insert into test (val1, val2, val3) values ($1, $2, $3)", "a", words, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type result struct {
+		Val2     string  `db:"val2"`
+		Rank     float64 `db:"rank"`
+		Headline string  `db:"headline"`
+	}
+	search := TextSearch{Table: "test", Document: "to_tsvector('english', val2)", Config: "english",
+		Columns: []string{"val2"}, Highlight: "val2", Where: "val3 > ?", WhereArgs: []interface{}{0}}
+	var results []result
+	if err := dbm.SearchStructs(context.Background(), search, `cat -"dog's"`, &results); err != nil {
+		t.Fatalf("error searching: %v\n", err)
+	}
+	if len(results) != 0 {
+		t.Fatalf("expected no results got %+v\n", results)
+	}
+
+	search.Mode = PrefixQuery
+	if err := dbm.SearchStructs(context.Background(), search, "do", &results); err != nil {
+		t.Fatalf("error searching: %v\n", err)
+	}
+	if len(results) != 2 || results[0].Rank <= 0 || !strings.Contains(results[0].Headline, "<b>dogs</b>") {
+		t.Fatalf("unexpected results: %+v\n", results)
+	}
+}
+
+func TestGeometry(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	if _, err := dbm.Exec("create extension if not exists postgis"); err != nil {
+		t.Skipf("postgis is not available: %v\n", err)
+	}
+	point := Geometry{SRID: 4326, Shape: Point{X: 13.4, Y: 52.5}}
+	var g Geometry
+	var distance float64
+	err = dbm.Db().QueryRow("select ST_Buffer($1::geometry, 1), ST_Distance($1::geography, ST_MakePoint(13.5, 52.5)::geography)", point).Scan(&g, &distance)
+	if err != nil {
+		t.Fatalf("error querying: %v\n", err)
+	}
+	if polygon, ok := g.Shape.(Polygon); !ok || g.SRID != 4326 || len(polygon[0]) < 4 || distance < 6000 || distance > 7000 {
+		t.Fatalf("unexpected results: %+v %v\n", g, distance)
+	}
+}
+
+func TestLargeObject(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	data := bytes.Repeat([]byte("0123456789"), loChunk/4)
+	oid, err := dbm.CreateLargeObject(context.Background(), bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("error creating large object: %v\n", err)
+	}
+	defer dbm.UnlinkLargeObject(context.Background(), oid)
+
+	err = dbm.WithTransaction(context.Background(), func(ctx context.Context, tx *Tx) error {
+		lo, err := dbm.OpenLargeObject(ctx, oid, true)
+		if err != nil {
+			return err
+		}
+		defer lo.Close()
+		read, err := io.ReadAll(lo)
+		if err != nil || !bytes.Equal(read, data) {
+			t.Fatalf("unexpected contents: %d bytes %v\n", len(read), err)
+		}
+		if position, err := lo.Seek(-4, io.SeekEnd); err != nil || position != int64(len(data)-4) {
+			t.Fatalf("unexpected position: %d %v\n", position, err)
+		}
+		if err := lo.Truncate(10); err != nil {
+			return err
+		}
+		if _, err := lo.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if read, err = io.ReadAll(lo); err != nil || string(read) != "0123456789" {
+			t.Fatalf("unexpected truncated contents: %q %v\n", read, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("error reading large object: %v\n", err)
+	}
+}
+
+func TestBlobs(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	if _, err := dbm.Exec("drop table if exists files_chunks, files"); err != nil {
+		t.Fatal(err)
+	}
+	blobs := dbm.Blobs("files", 1000)
+	if err := blobs.CreateTables(context.Background()); err != nil {
+		t.Fatalf("error creating tables: %v\n", err)
+	}
+
+	w, err := blobs.Create(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("error creating blob: %v\n", err)
+	}
+	data := bytes.Repeat([]byte("0123456789"), 250)
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("error writing blob: %v\n", err)
+	}
+	if _, err := blobs.Open(context.Background(), "a"); err != ErrBlobNotFound {
+		t.Fatalf("expected an incomplete blob to be hidden, got %v\n", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("error closing blob: %v\n", err)
+	}
+
+	r, err := blobs.Open(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("error opening blob: %v\n", err)
+	}
+	read, err := io.ReadAll(r)
+	if err != nil || r.Size != int64(len(data)) || !bytes.Equal(read, data) {
+		t.Fatalf("unexpected contents: %d bytes of %d %v\n", len(read), r.Size, err)
+	}
+
+	r, err = blobs.Open(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("error opening blob: %v\n", err)
+	}
+	if _, err := r.Read(make([]byte, 10)); err != nil {
+		t.Fatalf("error reading blob: %v\n", err)
+	}
+	w, err = blobs.Create(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("error replacing blob: %v\n", err)
+	}
+	replacement := bytes.Repeat([]byte("abcdefghij"), 150)
+	if _, err := w.Write(replacement); err != nil {
+		t.Fatalf("error writing blob: %v\n", err)
+	}
+	if old, err := blobs.Open(context.Background(), "a"); err != nil || old.Size != int64(len(data)) {
+		t.Fatalf("expected the old blob to stay readable until the replacement is closed: %v\n", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("error closing blob: %v\n", err)
+	}
+	if _, err := io.ReadAll(r); err != ErrBlobChanged {
+		t.Fatalf("expected reading a replaced blob to fail got %v\n", err)
+	}
+	r, err = blobs.Open(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("error opening blob: %v\n", err)
+	}
+	if read, err := io.ReadAll(r); err != nil || !bytes.Equal(read, replacement) {
+		t.Fatalf("unexpected contents after replacing: %d bytes %v\n", len(read), err)
+	}
+
+	if err := blobs.Delete(context.Background(), "a"); err != nil {
+		t.Fatalf("error deleting blob: %v\n", err)
+	}
+	var chunks int
+	if err := dbm.Db().QueryRow("select count(*) from files_chunks").Scan(&chunks); err != nil || chunks != 0 {
+		t.Fatalf("expected the chunks to be deleted: %d %v\n", chunks, err)
+	}
+}
+
+func TestPlanGuard(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	changes := make(chan PlanChange, 1)
+	dbm.SetPlanGuard(&PlanGuardOptions{Interval: time.Nanosecond, Callback: func(change PlanChange) { changes <- change }})
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	createTestTable(t, dbm)
+	if _, err := dbm.Exec("insert into test (val1, val2, val3) select 'a', 'b', i from generate_series(1, 10000) i"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbm.Exec("analyze test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dbm.PrepareAdd("get", "select val1 from test where val3 = $1"); err != nil {
+		t.Fatal(err)
+	}
+
+	query := func() {
+		rows, err := dbm.QueryPrepared("get", 5)
+		if err != nil {
+			t.Fatalf("error querying: %v\n", err)
+		}
+		rows.Close()
+	}
+	query()
+	time.Sleep(200 * time.Millisecond)
+	if _, err := dbm.Exec("create index test_val3 on test (val3)"); err != nil {
+		t.Fatal(err)
+	}
+	query()
+
+	select {
+	case change := <-changes:
+		if change.Key != "get" || !strings.Contains(change.Previous, "Seq Scan") || !strings.Contains(change.Plan, "test_val3") {
+			t.Fatalf("unexpected change: %+v\n", change)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the plan change to be reported\n")
+	}
+}
+
+func TestTopStatements(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	if _, err := dbm.Exec("create extension if not exists pg_stat_statements"); err != nil {
+		t.Skipf("pg_stat_statements is not available: %v\n", err)
+	}
+	createTestTable(t, dbm)
+	if err := dbm.PrepareAdd("count", "SELECT count(*)\n FROM test WHERE val3 > $1"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := dbm.ExecPrepared("count", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := dbm.TopStatements(context.Background(), ByCalls, 1000)
+	if err != nil {
+		t.Skipf("pg_stat_statements can't be read, it needs shared_preload_libraries: %v\n", err)
+	}
+	for _, s := range stats {
+		if s.Key == "count" {
+			if s.Calls < 5 {
+				t.Fatalf("expected at least 5 calls got %d\n", s.Calls)
+			}
+			return
+		}
+	}
+	t.Fatalf("expected count in the top statements: %+v\n", stats)
+}
+
+func TestPoolWait(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.SetPoolOptions(PoolOptions{MaxOpenConns: 1})
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	if err := dbm.PrepareAdd("get", "select $1::int"); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := dbm.QueryPreparedContext(WithPoolWait(context.Background(), time.Second), "get", 1)
+	if err != nil {
+		t.Fatalf("error querying: %v\n", err)
+	}
+	rows.Close()
+
+	conn, err := dbm.Db().Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbm.ExecPreparedContext(WithPoolWait(context.Background(), 50*time.Millisecond), "get", 1); err != ErrPoolTimeout {
+		t.Fatalf("expected ErrPoolTimeout got %v\n", err)
+	}
+	conn.Close()
+	if _, err := dbm.ExecPreparedContext(WithPoolWait(context.Background(), time.Second), "get", 1); err != nil {
+		t.Fatalf("expected the call to run once a connection is free: %v\n", err)
+	}
+}
+
+func TestWarmUp(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.SetWarmUp(4)
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	if open := dbm.Db().Stats().OpenConnections; open != 4 {
+		t.Fatalf("expected 4 open connections after Connect got %d\n", open)
+	}
+	if err := dbm.PrepareAdd("get", "select $1::int"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dbm.WarmUp(context.Background(), 4); err != nil {
+		t.Fatalf("error warming up: %v\n", err)
+	}
+	if idle := dbm.Db().Stats().Idle; idle != 4 {
+		t.Fatalf("expected the warmed connections to stay idle got %d\n", idle)
+	}
+	if _, err := dbm.ExecPrepared("get", 1); err != nil {
+		t.Fatalf("error executing on a warmed connection: %v\n", err)
+	}
+}
+
+func TestDryRunRollback(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+	createTestTable(t, dbm)
+
+	if err := dbm.PrepareAdd("insert", "insert into test (val1, val2, val3) values ($1, $2, $3)"); err != nil {
+		t.Fatal(err)
+	}
+	dbm.SetDryRun(DryRunRollback)
+	result, err := dbm.ExecPrepared("insert", "a", "b", 1)
+	if err != nil {
+		t.Fatalf("error executing in a dry run: %v\n", err)
+	}
+	if n, _ := result.RowsAffected(); n != 1 {
+		t.Fatalf("expected the real rows affected got %d\n", n)
+	}
+	err = dbm.WithTransaction(context.Background(), func(ctx context.Context, tx *Tx) error {
+		_, err := dbm.ExecPreparedContext(ctx, "insert", "c", "d", 2)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("error executing in a dry run inside a transaction: %v\n", err)
+	}
+
+	var count int
+	if err := dbm.Db().QueryRow("select count(*) from test").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Fatalf("expected the dry runs to leave no rows got %d\n", count)
+	}
+}
+
+func TestPrepareAddTemplate(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	createTestTable(t, dbm)
+	if _, err := dbm.Exec("insert into test (val1, val2, val3) select 'boop', 'zoop', i from generate_series(1, 5) i"); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := "select val3 from {{ident .Table}} where val1 = $1 and val3 in ({{params .Values 2}}) order by val3"
+	if err := dbm.PrepareAddTemplate("get", tmpl, map[string]interface{}{"Table": "test", "Values": 2}); err != nil {
+		t.Fatalf("error preparing template: %v\n", err)
+	}
+	rows, err := dbm.QueryPreparedMaps("get", "boop", 2, 4)
+	if err != nil {
+		t.Fatalf("error querying template: %v\n", err)
+	}
+	if len(rows) != 2 || rows[0]["val3"] != int64(2) || rows[1]["val3"] != int64(4) {
+		t.Fatalf("unexpected rows: %v\n", rows)
+	}
+
+	if err := dbm.PrepareAddTemplate("get", "select {{.Missing}} from test", map[string]interface{}{}); err == nil {
+		t.Fatalf("expected an error for a missing key\n")
+	}
+	st, err := dbm.lookup("get")
+	if err != nil || !strings.Contains(st.query, "val3 in ($2, $3)") {
+		t.Fatalf("expected a failed render to leave the registered statement alone: %v\n", err)
+	}
+}
+
+func TestPreparedTimeout(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	err = dbm.PrepareAddWithOptions("sleep", "select pg_sleep($1)", WithTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := dbm.ExecPrepared("sleep", 1); err == nil {
+		t.Fatalf("expected statement to time out\n")
+	}
+}
+
 func TestCopyIn(t *testing.T) {
 	dbm := New(username, password, dbname, host, "disable", "")
 	err := dbm.Connect()
@@ -237,3 +1954,32 @@ func disconnect(t *testing.T, dbm *SqlStore) {
 		t.Fatalf("Error disconnecting from the testdatabase: %v\n", err)
 	}
 }
+
+func TestValidateStatements(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	err := dbm.Connect()
+	if err != nil {
+		t.Fatalf("Error connecting to the testdatabase: %v\n", err)
+	}
+	defer disconnect(t, dbm)
+
+	createTestTable(t, dbm)
+
+	if err := dbm.PrepareAdd("get", "select val1 from test"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := dbm.ValidateStatements(context.Background()); err != nil {
+		t.Fatalf("expected statements to be valid: %v\n", err)
+	}
+
+	if _, err := dbm.Exec("alter table test drop column val1"); err != nil {
+		t.Fatal(err)
+	}
+
+	err = dbm.ValidateStatements(context.Background())
+	verr, ok := err.(*ValidationError)
+	if !ok || len(verr.Failures) != 1 || verr.Failures[0].Key != "get" {
+		t.Fatalf("expected get to fail validation, got: %v\n", err)
+	}
+}
@@ -0,0 +1,219 @@
+package godbm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GoldenOptions configures RecordGolden and VerifyGolden.
+type GoldenOptions struct {
+	Dir  string                   // directory of the golden files, one <key>.golden per statement.
+	Keys []string                 // statements to check, defaults to every registered statement.
+	Args map[string][]interface{} // arguments to run each statement with, keyed by statement key, IN list values first.
+}
+
+// GoldenMismatch is a statement whose golden file doesn't match the live database.
+type GoldenMismatch struct {
+	Key  string
+	Want string // the recorded golden file, empty if there was none.
+	Got  string
+}
+
+// GoldenError is returned by VerifyGolden and holds every statement which didn't match.
+type GoldenError struct {
+	Mismatches []GoldenMismatch // mismatches sorted by statement key
+}
+
+func (e *GoldenError) Error() string {
+	msgs := make([]string, len(e.Mismatches))
+	for i, m := range e.Mismatches {
+		msgs[i] = m.Key + ": " + firstDifference(m.Want, m.Got)
+	}
+	return "godbm: error " + strconv.Itoa(len(e.Mismatches)) + " statements differ from their golden files: " + strings.Join(msgs, "; ")
+}
+
+// RecordGolden writes a golden file for each statement holding its sql, its normalized plan
+// and the names and types of the columns it returns. Costs and row estimates are left out of
+// the plan so only a change of shape, such as a lost index, is a difference. Record against a
+// database whose statistics resemble production or the plans won't either.
+//
+// Each statement is run with its Args inside a transaction which is rolled back, so writes
+// are discarded.
+func (store *SqlStore) RecordGolden(ctx context.Context, opts GoldenOptions) error {
+	return store.eachGolden(ctx, opts, func(st *statement, file, got string) error {
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return err
+		}
+		return os.WriteFile(file, []byte(got), 0o644)
+	})
+}
+
+// VerifyGolden checks every statement against the golden files written by RecordGolden and
+// returns a *GoldenError listing the ones which differ, for use in CI before a deploy.
+func (store *SqlStore) VerifyGolden(ctx context.Context, opts GoldenOptions) error {
+	golden := &GoldenError{}
+	err := store.eachGolden(ctx, opts, func(st *statement, file, got string) error {
+		want, err := os.ReadFile(file)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		if string(want) != got {
+			golden.Mismatches = append(golden.Mismatches, GoldenMismatch{Key: st.key, Want: string(want), Got: got})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(golden.Mismatches) > 0 {
+		return golden
+	}
+	return nil
+}
+
+// eachGolden renders the golden file of each statement in key order and calls fn with it.
+func (store *SqlStore) eachGolden(ctx context.Context, opts GoldenOptions, fn func(st *statement, file, got string) error) error {
+	if !store.Connected {
+		return &ConnectionError{}
+	}
+
+	var statements []*statement
+	if len(opts.Keys) == 0 {
+		store.RLock()
+		for _, st := range store.queries {
+			statements = append(statements, st)
+		}
+		store.RUnlock()
+	}
+	for _, key := range opts.Keys {
+		st, err := store.lookup(key)
+		if err != nil {
+			return err
+		}
+		statements = append(statements, st)
+	}
+	sort.Slice(statements, func(i, j int) bool { return statements[i].key < statements[j].key })
+
+	for _, st := range statements {
+		got, err := store.golden(ctx, st, opts.Args[st.key])
+		if err != nil {
+			return &StatementError{Key: st.key, Query: st.query, Err: err}
+		}
+		if err := fn(st, filepath.Join(opts.Dir, filepath.FromSlash(st.key)+".golden"), got); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// golden plans and runs st with args in a transaction which is rolled back and renders its
+// golden file. Only the column types are read, not the rows.
+func (store *SqlStore) golden(ctx context.Context, st *statement, args []interface{}) (string, error) {
+	query := st.query
+	if st.in != nil {
+		var err error
+		if query, args, err = st.inCall(args); err != nil {
+			return "", err
+		}
+	}
+	args, err := store.convertArgs(args)
+	if err != nil {
+		return "", err
+	}
+	txn, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer txn.Rollback()
+
+	var raw string
+	if err := txn.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+query, args...).Scan(&raw); err != nil {
+		return "", err
+	}
+	plan, err := parsePlan(raw)
+	if err != nil {
+		return "", err
+	}
+
+	rows, err := txn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return "", err
+	}
+	columns := make([]string, len(types))
+	for i, typ := range types {
+		columns[i] = typ.Name() + " " + strings.ToLower(typ.DatabaseTypeName())
+	}
+	return renderGolden(query, &plan.Plan, columns), nil
+}
+
+// renderGolden formats a golden file with a section each for the sql, the plan and the columns.
+func renderGolden(query string, plan *PlanNode, columns []string) string {
+	var b strings.Builder
+	b.WriteString("-- sql\n" + strings.TrimSpace(query) + "\n-- plan\n")
+	writePlan(&b, plan, 0)
+	b.WriteString("-- columns\n")
+	for _, column := range columns {
+		b.WriteString(column + "\n")
+	}
+	return b.String()
+}
+
+// writePlan writes node and its children indented by depth, in the style of EXPLAIN's text
+// format without costs or estimates.
+func writePlan(b *strings.Builder, node *PlanNode, depth int) {
+	indent := strings.Repeat("  ", depth)
+	line := node.NodeType
+	if node.Strategy != "" && node.Strategy != "Plain" {
+		line += " (" + node.Strategy + ")"
+	}
+	if node.JoinType != "" && node.JoinType != "Inner" {
+		line += " " + node.JoinType
+	}
+	if node.IndexName != "" {
+		line += " using " + node.IndexName
+	}
+	if node.RelationName != "" {
+		line += " on " + node.RelationName
+	}
+	b.WriteString(indent + line + "\n")
+	if node.IndexCond != "" {
+		b.WriteString(indent + "  Index Cond: " + node.IndexCond + "\n")
+	}
+	if node.Filter != "" {
+		b.WriteString(indent + "  Filter: " + node.Filter + "\n")
+	}
+	for i := range node.Plans {
+		writePlan(b, &node.Plans[i], depth+1)
+	}
+}
+
+// firstDifference describes the first line where want and got differ.
+func firstDifference(want, got string) string {
+	if want == "" {
+		return "no golden file"
+	}
+	wantLines, gotLines := strings.Split(want, "\n"), strings.Split(got, "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return "line " + strconv.Itoa(i+1) + " want " + strconv.Quote(w) + " got " + strconv.Quote(g)
+		}
+	}
+	return "files differ"
+}
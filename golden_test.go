@@ -0,0 +1,24 @@
+package godbm
+
+import "testing"
+
+func TestRenderGolden(t *testing.T) {
+	plan := &PlanNode{NodeType: "Hash Join", JoinType: "Left", TotalCost: 12.5, Plans: []PlanNode{
+		{NodeType: "Seq Scan", RelationName: "test", Filter: "(val3 > 1)", PlanRows: 40},
+		{NodeType: "Index Scan", RelationName: "users", IndexName: "users_pkey", IndexCond: "(id = 1)"},
+	}}
+	expected := "-- sql\nselect 1\n-- plan\nHash Join Left\n  Seq Scan on test\n    Filter: (val3 > 1)\n" +
+		"  Index Scan using users_pkey on users\n    Index Cond: (id = 1)\n-- columns\nval1 varchar\n"
+	if got := renderGolden(" select 1\n", plan, []string{"val1 varchar"}); got != expected {
+		t.Fatalf("unexpected golden file:\n%s\n", got)
+	}
+}
+
+func TestFirstDifference(t *testing.T) {
+	if diff := firstDifference("a\nb\n", "a\nc\n"); diff != `line 2 want "b" got "c"` {
+		t.Fatalf("unexpected difference: %s\n", diff)
+	}
+	if diff := firstDifference("", "a\n"); diff != "no golden file" {
+		t.Fatalf("unexpected difference: %s\n", diff)
+	}
+}
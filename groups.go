@@ -0,0 +1,128 @@
+package godbm
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PrepareAddGroup registers each of queries under the key "<ns>.<name>", such as "users.get",
+// so each module of an application can own a set of statements without key collisions.
+//
+// Every statement is prepared before the registry is touched, then any statements already
+// registered in ns are replaced by the new set at once, so callers never see a mix of the two
+// and a group which fails to prepare leaves the old one in place. Nested namespaces such as
+// "billing.invoices" are part of "billing".
+func (store *SqlStore) PrepareAddGroup(ns string, queries map[string]string, opts ...StmtOption) error {
+	if !store.Connected {
+		return &ConnectionError{}
+	}
+	if ns == "" {
+		return errors.New("godbm: error statement group needs a namespace")
+	}
+	if err := store.checkUnlocked(); err != nil {
+		return err
+	}
+
+	group := make([]*statement, 0, len(queries))
+	for name, query := range queries {
+		st, err := store.prepareGroupStatement(ns+"."+name, query, opts)
+		if err != nil {
+			closeStatements(group)
+			store.logEvent(context.Background(), "godbm: prepare statement group", err, slog.String("namespace", ns), slog.String("key", ns+"."+name))
+			return err
+		}
+		group = append(group, st)
+	}
+
+	store.Lock()
+	replaced := store.removeGroup(ns)
+	if store.queries == nil {
+		store.queries = make(map[string]*statement, len(group))
+	}
+	for _, st := range group {
+		store.queries[st.key] = st
+	}
+	store.Unlock()
+
+	err := closeStatements(replaced)
+	store.logEvent(context.Background(), "godbm: prepare statement group", err, slog.String("namespace", ns), slog.Int("statements", len(group)))
+	return err
+}
+
+// PrepareDelGroup removes every statement registered in ns at once.
+func (store *SqlStore) PrepareDelGroup(ns string) error {
+	if !store.Connected {
+		return &ConnectionError{}
+	}
+	if err := store.checkUnlocked(); err != nil {
+		return err
+	}
+
+	store.Lock()
+	removed := store.removeGroup(ns)
+	store.Unlock()
+
+	err := closeStatements(removed)
+	store.logEvent(context.Background(), "godbm: delete statement group", err, slog.String("namespace", ns), slog.Int("statements", len(removed)))
+	return err
+}
+
+// GroupKeys returns the sorted keys of the statements registered in ns.
+func (store *SqlStore) GroupKeys(ns string) []string {
+	prefix := ns + "."
+	var keys []string
+	store.RLock()
+	for key := range store.queries {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	store.RUnlock()
+	sort.Strings(keys)
+	return keys
+}
+
+// prepareGroupStatement prepares query as the statement key without registering it.
+func (store *SqlStore) prepareGroupStatement(key, query string, opts []StmtOption) (*statement, error) {
+	if err := store.checkRead(query); err != nil {
+		return nil, err
+	}
+	stmt, err := store.prepare(key, query)
+	if err != nil {
+		return nil, err
+	}
+	st := &statement{key: key, stmt: stmt, query: query, params: countParams(query), registered: time.Now()}
+	for _, opt := range opts {
+		opt(&st.opts)
+	}
+	return st, nil
+}
+
+// removeGroup removes the statements registered in ns from the registry and returns them, the
+// store must be locked.
+func (store *SqlStore) removeGroup(ns string) []*statement {
+	prefix := ns + "."
+	var removed []*statement
+	for key, st := range store.queries {
+		if strings.HasPrefix(key, prefix) {
+			removed = append(removed, st)
+			delete(store.queries, key)
+		}
+	}
+	return removed
+}
+
+// closeStatements closes statements removed from the registry, returning the first error.
+func closeStatements(statements []*statement) error {
+	var first error
+	for _, st := range statements {
+		if err := st.close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
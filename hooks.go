@@ -0,0 +1,59 @@
+package godbm
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Hook is called around every query and exec the store makes, giving a single extension point
+// for logging, metrics, tracing and auditing. The key is empty for ad-hoc Exec and Query calls.
+type Hook interface {
+	// BeforeQuery is called before the statement is executed. The returned context is passed
+	// to the statement and to AfterQuery, allowing hooks to carry state such as trace spans.
+	BeforeQuery(ctx context.Context, key, query string, args []interface{}) context.Context
+	// AfterQuery is called once the statement, including any retries, has finished.
+	AfterQuery(ctx context.Context, key string, duration time.Duration, err error)
+}
+
+// ResultHook may be implemented by a Hook to also receive the result of successful Exec and
+// ExecPrepared calls. AfterExec is called before AfterQuery.
+type ResultHook interface {
+	AfterExec(ctx context.Context, key string, result sql.Result)
+}
+
+// Use registers a hook. Hooks are called in the order they were registered before a query and
+// in reverse order after it. This should be called before the store is in use.
+func (store *SqlStore) Use(hook Hook) {
+	store.hooks = append(store.hooks, hook)
+}
+
+// HookFuncs adapts a pair of functions to the Hook interface, either may be nil.
+type HookFuncs struct {
+	Before func(ctx context.Context, key, query string, args []interface{}) context.Context
+	After  func(ctx context.Context, key string, duration time.Duration, err error)
+}
+
+// BeforeQuery calls h.Before if set.
+func (h HookFuncs) BeforeQuery(ctx context.Context, key, query string, args []interface{}) context.Context {
+	if h.Before == nil {
+		return ctx
+	}
+	return h.Before(ctx, key, query, args)
+}
+
+// AfterQuery calls h.After if set.
+func (h HookFuncs) AfterQuery(ctx context.Context, key string, duration time.Duration, err error) {
+	if h.After != nil {
+		h.After(ctx, key, duration, err)
+	}
+}
+
+// afterExec passes the result of a successful exec to any hooks implementing ResultHook.
+func (store *SqlStore) afterExec(ctx context.Context, st *statement, result sql.Result) {
+	for i := len(store.hooks) - 1; i >= 0; i-- {
+		if hook, ok := store.hooks[i].(ResultHook); ok {
+			hook.AfterExec(ctx, st.key, result)
+		}
+	}
+}
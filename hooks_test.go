@@ -0,0 +1,48 @@
+package godbm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type ctxKey string
+
+func TestHooks(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+
+	var order []string
+	var afterErr error
+	dbm.Use(HookFuncs{
+		Before: func(ctx context.Context, key, query string, args []interface{}) context.Context {
+			order = append(order, "before "+key)
+			return context.WithValue(ctx, ctxKey("hook"), "set")
+		},
+		After: func(ctx context.Context, key string, duration time.Duration, err error) {
+			if ctx.Value(ctxKey("hook")) != "set" {
+				t.Fatalf("expected context from BeforeQuery to be passed to AfterQuery\n")
+			}
+			order = append(order, "after "+key)
+			afterErr = err
+		},
+	})
+
+	expected := errors.New("boom")
+	st := &statement{key: "get", query: "select 1"}
+	err := dbm.run(context.Background(), st, nil, func(ctx context.Context) error {
+		if ctx.Value(ctxKey("hook")) != "set" {
+			t.Fatalf("expected context from BeforeQuery to be passed to the statement\n")
+		}
+		order = append(order, "run")
+		return expected
+	})
+
+	if err != expected || afterErr != expected {
+		t.Fatalf("expected error to be returned and passed to AfterQuery, got %v and %v\n", err, afterErr)
+	}
+
+	if len(order) != 3 || order[0] != "before get" || order[1] != "run" || order[2] != "after get" {
+		t.Fatalf("unexpected hook order: %v\n", order)
+	}
+}
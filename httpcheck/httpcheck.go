@@ -0,0 +1,133 @@
+// Package httpcheck serves the health of a godbm store as JSON for liveness and readiness
+// probes such as /healthz and /readyz.
+package httpcheck
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/wirepair/godbm"
+	"net/http"
+	"time"
+)
+
+// Options configures the checks made by Handler.
+type Options struct {
+	Timeout       time.Duration // time allowed for all the checks, defaults to two seconds.
+	MaxPoolUse    float64       // fraction of the pool's maximum open connections in use above which the store isn't ready, zero to not check.
+	MaxReplicaLag int64         // WAL bytes a replica may lag before the store isn't ready, zero to report lag without checking it.
+	VersionQuery  string        // optional query returning the migration version, e.g. "select max(version) from schema_migrations".
+}
+
+// Report is the JSON body written by the handlers.
+type Report struct {
+	Status   string    `json:"status"` // "ok" or "unavailable".
+	Pool     *Pool     `json:"pool,omitempty"`
+	Replicas []Replica `json:"replicas,omitempty"`
+	Version  string    `json:"version,omitempty"`
+	Errors   []string  `json:"errors,omitempty"` // why the store is unavailable.
+}
+
+// Pool is the state of the connection pool.
+type Pool struct {
+	Open      int   `json:"open"`
+	InUse     int   `json:"in_use"`
+	Idle      int   `json:"idle"`
+	MaxOpen   int   `json:"max_open"` // zero when unlimited.
+	WaitCount int64 `json:"wait_count"`
+}
+
+// Replica is the lag of a read replica.
+type Replica struct {
+	Host        string  `json:"host"`
+	LagBytes    int64   `json:"lag_bytes"`
+	ReplayDelay float64 `json:"replay_delay_seconds"`
+}
+
+// Handler returns a readiness handler which pings the database and checks pool saturation,
+// replica lag and the migration version as configured by opts, which may be nil. It responds
+// 200 with the report when every check passes and 503 otherwise.
+func Handler(store *godbm.SqlStore, opts *Options) http.Handler {
+	if opts == nil {
+		opts = &Options{}
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout(opts))
+		defer cancel()
+		write(w, check(ctx, store, opts, true))
+	})
+}
+
+// LivenessHandler returns a liveness handler which only pings the database, so a saturated pool
+// or a lagging replica doesn't get the process restarted. It responds 200 or 503.
+func LivenessHandler(store *godbm.SqlStore) http.Handler {
+	opts := &Options{}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout(opts))
+		defer cancel()
+		write(w, check(ctx, store, opts, false))
+	})
+}
+
+// timeout returns the time allowed for the checks.
+func timeout(opts *Options) time.Duration {
+	if opts.Timeout > 0 {
+		return opts.Timeout
+	}
+	return 2 * time.Second
+}
+
+// check builds the report, only pinging unless ready is set.
+func check(ctx context.Context, store *godbm.SqlStore, opts *Options, ready bool) Report {
+	report := Report{Status: "ok"}
+	fail := func(msg string) {
+		report.Status = "unavailable"
+		report.Errors = append(report.Errors, msg)
+	}
+
+	db := store.Db()
+	if !store.Connected || db == nil {
+		fail("not connected")
+		return report
+	}
+	if err := db.PingContext(ctx); err != nil {
+		fail("ping: " + err.Error())
+		return report
+	}
+	if !ready {
+		return report
+	}
+
+	stats := db.Stats()
+	report.Pool = &Pool{Open: stats.OpenConnections, InUse: stats.InUse, Idle: stats.Idle, MaxOpen: stats.MaxOpenConnections, WaitCount: stats.WaitCount}
+	if opts.MaxPoolUse > 0 && stats.MaxOpenConnections > 0 && float64(stats.InUse)/float64(stats.MaxOpenConnections) > opts.MaxPoolUse {
+		fail("pool saturated")
+	}
+
+	lags, err := store.ReplicaLag(ctx)
+	if err != nil {
+		fail("replica lag: " + err.Error())
+	}
+	for _, lag := range lags {
+		report.Replicas = append(report.Replicas, Replica{Host: lag.Host, LagBytes: lag.Bytes, ReplayDelay: lag.ReplayDelay.Seconds()})
+		if opts.MaxReplicaLag > 0 && lag.Bytes > opts.MaxReplicaLag {
+			fail("replica " + lag.Host + " lagging")
+		}
+	}
+
+	if opts.VersionQuery != "" {
+		if err := db.QueryRowContext(ctx, opts.VersionQuery).Scan(&report.Version); err != nil {
+			fail("version: " + err.Error())
+		}
+	}
+	return report
+}
+
+// write writes report as JSON with the status code matching its status.
+func write(w http.ResponseWriter, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
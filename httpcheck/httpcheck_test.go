@@ -0,0 +1,25 @@
+package httpcheck
+
+import (
+	"encoding/json"
+	"github.com/wirepair/godbm"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandlerNotConnected(t *testing.T) {
+	store := godbm.New("postgres", "testpass", "godbm_test", "127.0.0.1", "disable", "")
+	for _, handler := range []http.Handler{Handler(store, nil), LivenessHandler(store)} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected 503, got %d\n", w.Code)
+		}
+
+		var report Report
+		if err := json.NewDecoder(w.Body).Decode(&report); err != nil || report.Status != "unavailable" || len(report.Errors) != 1 {
+			t.Fatalf("unexpected report: %+v %v\n", report, err)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package godbm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// inMarker marks where PrepareAddIn statements take their list of values.
+const inMarker = "(?)"
+
+// ErrEmptyInList is returned by QueryPreparedIn and ExecPreparedIn when values is empty, as
+// postgres doesn't accept an empty IN list.
+var ErrEmptyInList = errors.New("godbm: error IN list is empty")
+
+// ErrNeedsInList is returned when a statement registered with PrepareAddIn is called without a
+// list of values.
+var ErrNeedsInList = errors.New("godbm: error statement takes an IN list, call it with QueryPreparedIn or ExecPreparedIn")
+
+// inList holds the variants of a PrepareAddIn statement prepared for each list length.
+type inList struct {
+	marker int                // offset of inMarker in the query
+	array  bool               // the marker follows ANY so the list is an array constructor
+	stmts  map[int]*statement // variants by padded list length, prepared on first use
+}
+
+// PrepareAddIn registers a statement taking a variable length list of values at a single (?)
+// marker, either as "in (?)" or "= any(?)". It is called with QueryPreparedIn or
+// ExecPreparedIn, which rewrite the marker into numbered placeholders following the
+// statement's own, so "select * from users where org = $1 and id in (?)" called with three ids
+// runs "... id in ($2, $3, $4)".
+//
+// A variant is prepared for each distinct list length on first use. Lengths are padded to the
+// next power of two by repeating the last value, which doesn't change the result, so a
+// statement needs at most a handful of variants.
+func (store *SqlStore) PrepareAddIn(key, query string, opts ...StmtOption) error {
+	if !store.Connected {
+		return &ConnectionError{}
+	}
+	if err := store.checkUnlocked(); err != nil {
+		return err
+	}
+	if err := store.checkRead(query); err != nil {
+		return err
+	}
+	marker := strings.Index(query, inMarker)
+	if marker < 0 || strings.Count(query, inMarker) != 1 {
+		return errors.New("godbm: error statement " + key + " needs exactly one " + inMarker + " marker")
+	}
+
+	before := strings.ToLower(strings.TrimRight(query[:marker], " \t\r\n"))
+	st := &statement{key: key, query: query, params: countParams(query), registered: time.Now(),
+		in: &inList{marker: marker, array: strings.HasSuffix(before, "any")}}
+	for _, opt := range opts {
+		opt(&st.opts)
+	}
+	store.logEvent(context.Background(), "godbm: prepare statement", nil, slog.String("key", key))
+	defer store.Unlock()
+
+	store.Lock()
+	if store.queries != nil {
+		store.queries[key] = st
+	} else {
+		store.queries = map[string]*statement{key: st}
+	}
+	return nil
+}
+
+// QueryPreparedIn queries the PrepareAddIn statement registered under key with the slice
+// values as its list and data as its own parameters.
+func (store *SqlStore) QueryPreparedIn(ctx context.Context, key string, values interface{}, data ...interface{}) (*sql.Rows, error) {
+	st, args, err := store.inStatement(key, values, data)
+	if err != nil {
+		return nil, err
+	}
+	return store.queryStatement(ctx, st, args...)
+}
+
+// ExecPreparedIn executes the PrepareAddIn statement registered under key with the slice
+// values as its list and data as its own parameters.
+func (store *SqlStore) ExecPreparedIn(ctx context.Context, key string, values interface{}, data ...interface{}) (sql.Result, error) {
+	st, args, err := store.inStatement(key, values, data)
+	if err != nil {
+		return nil, err
+	}
+	return store.execStatement(ctx, st, args...)
+}
+
+// inStatement returns the variant of the statement key for the length of values along with
+// its arguments.
+func (store *SqlStore) inStatement(key string, values interface{}, data []interface{}) (*statement, []interface{}, error) {
+	if !store.Connected {
+		return nil, nil, &ConnectionError{}
+	}
+	st, err := store.lookup(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if st.in == nil {
+		return nil, nil, errors.New("godbm: error statement " + key + " has no " + inMarker + " marker")
+	}
+	args, err := inArgs(values, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	variant, err := store.inVariant(st, len(args)-len(data))
+	return variant, args, err
+}
+
+// inVariant returns the variant of st for n values, preparing it on first use.
+func (store *SqlStore) inVariant(st *statement, n int) (*statement, error) {
+	store.RLock()
+	variant := st.in.stmts[n]
+	store.RUnlock()
+	if variant != nil {
+		return variant, nil
+	}
+
+	// prepared outside the lock so a slow prepare doesn't hold up every other call.
+	variant, err := store.derive(st, st.in.expand(st.query, st.params, n), st.params+n)
+	if err != nil {
+		return nil, err
+	}
+
+	store.Lock()
+	defer store.Unlock()
+	if existing := st.in.stmts[n]; existing != nil {
+		variant.close()
+		return existing, nil
+	}
+	if st.in.stmts == nil {
+		st.in.stmts = make(map[int]*statement)
+	}
+	st.in.stmts[n] = variant
+	return variant, nil
+}
+
+// expand replaces the marker in query with n placeholders following the statement's params.
+func (in *inList) expand(query string, params, n int) string {
+	list, _ := placeholders(n, params+1)
+	if in.array {
+		list = "array[" + list + "]"
+	}
+	return query[:in.marker] + "(" + list + ")" + query[in.marker+len(inMarker):]
+}
+
+// inCall returns the sql and arguments for calling the PrepareAddIn statement st with args,
+// the first of which is the slice of list values as passed to QueryPreparedIn.
+func (st *statement) inCall(args []interface{}) (string, []interface{}, error) {
+	if len(args) == 0 {
+		return "", nil, ErrNeedsInList
+	}
+	expanded, err := inArgs(args[0], args[1:])
+	if err != nil {
+		return "", nil, err
+	}
+	return st.in.expand(st.query, st.params, len(expanded)-len(args)+1), expanded, nil
+}
+
+// inArgs appends the elements of the slice values to data, padding them to the next power of
+// two with the last element.
+func inArgs(values interface{}, data []interface{}) ([]interface{}, error) {
+	v := reflect.ValueOf(values)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, errors.New("godbm: error IN list must be a slice")
+	}
+	if v.Len() == 0 {
+		return nil, ErrEmptyInList
+	}
+
+	padded := 1
+	for padded < v.Len() {
+		padded <<= 1
+	}
+	args := make([]interface{}, len(data), len(data)+padded)
+	copy(args, data)
+	for i := 0; i < padded; i++ {
+		args = append(args, v.Index(min(i, v.Len()-1)).Interface())
+	}
+	return args, nil
+}
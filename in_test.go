@@ -0,0 +1,54 @@
+package godbm
+
+import "testing"
+
+func TestInExpand(t *testing.T) {
+	query := "select * from users where org = $1 and id in (?)"
+	in := &inList{marker: len("select * from users where org = $1 and id in ")}
+	if expanded := in.expand(query, 1, 3); expanded != "select * from users where org = $1 and id in ($2, $3, $4)" {
+		t.Fatalf("unexpected expansion: %s\n", expanded)
+	}
+
+	query = "select * from users where id = any(?)"
+	in = &inList{marker: len("select * from users where id = any"), array: true}
+	if expanded := in.expand(query, 0, 2); expanded != "select * from users where id = any(array[$1, $2])" {
+		t.Fatalf("unexpected expansion: %s\n", expanded)
+	}
+}
+
+func TestInArgs(t *testing.T) {
+	args, err := inArgs([]int{1, 2, 3}, []interface{}{"org"})
+	if err != nil || len(args) != 5 || args[0] != "org" || args[3] != 3 || args[4] != 3 {
+		t.Fatalf("unexpected args: %v %v\n", args, err)
+	}
+	if _, err := inArgs([]string{}, nil); err != ErrEmptyInList {
+		t.Fatalf("expected ErrEmptyInList got %v\n", err)
+	}
+	if _, err := inArgs(1, nil); err == nil {
+		t.Fatalf("expected an error for a non slice\n")
+	}
+	st := &statement{key: "get", in: &inList{}}
+	if err := st.checkParams(nil); err != ErrNeedsInList {
+		t.Fatalf("expected ErrNeedsInList got %v\n", err)
+	}
+}
+
+func TestInCall(t *testing.T) {
+	query := "select * from users where org = $1 and id in (?)"
+	st := &statement{key: "get", query: query, params: 1, in: &inList{marker: len("select * from users where org = $1 and id in ")}}
+	expanded, args, err := st.inCall([]interface{}{[]int{7, 8, 9}, "org"})
+	if err != nil || expanded != "select * from users where org = $1 and id in ($2, $3, $4, $5)" || len(args) != 5 || args[0] != "org" {
+		t.Fatalf("unexpected call: %s %v %v\n", expanded, args, err)
+	}
+	if _, _, err := st.inCall(nil); err != ErrNeedsInList {
+		t.Fatalf("expected ErrNeedsInList got %v\n", err)
+	}
+
+	dbm := New(username, password, dbname, host, "disable", "")
+	if _, err := dbm.lockedStmt(st, RowLock{Strength: ForUpdate}); err != ErrNeedsInList {
+		t.Fatalf("expected row locking an IN statement to be refused got %v\n", err)
+	}
+	if _, err := dbm.pageStatements(st); err != ErrNeedsInList {
+		t.Fatalf("expected paginating an IN statement to be refused got %v\n", err)
+	}
+}
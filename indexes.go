@@ -0,0 +1,178 @@
+package godbm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// IndexSpec describes an index for EnsureIndex.
+type IndexSpec struct {
+	Name    string   // index name, used to find an existing index.
+	Table   string   // table to index, may be schema qualified, the index is created in its schema.
+	Columns []string // column names, quoted as identifiers.
+	Unique  bool     // create a unique index.
+	Method  string   // access method such as btree, gin or brin, defaults to btree.
+	Where   string   // optional predicate for a partial index, used as written.
+
+	// Progress is optional and called every ProgressInterval, defaulting to a second, while the
+	// index is built.
+	Progress         func(p IndexProgress)
+	ProgressInterval time.Duration
+}
+
+// IndexProgress is a snapshot of pg_stat_progress_create_index for an index being built.
+type IndexProgress struct {
+	Phase       string // such as "building index" or "waiting for old snapshots".
+	BlocksDone  int64
+	BlocksTotal int64
+	TuplesDone  int64
+	TuplesTotal int64
+}
+
+// EnsureIndex creates the index described by spec with CREATE INDEX CONCURRENTLY unless a
+// valid index with its name already exists, and reports whether it was created. An existing
+// index's definition is not compared with spec. A failed concurrent build leaves an INVALID
+// index behind, which is dropped and rebuilt here, and a build which fails now is cleaned up
+// the same way so a retry starts afresh. Concurrent builds can't run inside a transaction and
+// the call blocks until the build completes, which on large tables can take a long time.
+func (store *SqlStore) EnsureIndex(ctx context.Context, spec IndexSpec) (bool, error) {
+	if spec.Name == "" || spec.Table == "" || len(spec.Columns) == 0 {
+		return false, errors.New("godbm: error index spec needs a name, table and columns")
+	}
+	if _, inTx := TxFromContext(ctx); inTx {
+		return false, errors.New("godbm: error EnsureIndex can't run inside a transaction")
+	}
+
+	name := spec.Name
+	if i := strings.LastIndex(spec.Table, "."); i >= 0 {
+		name = spec.Table[:i+1] + name
+	}
+	valid, exists, err := store.indexValid(ctx, name)
+	if err != nil || valid {
+		return false, err
+	}
+	if exists {
+		if err := store.dropIndex(ctx, name); err != nil {
+			return false, err
+		}
+	}
+
+	query, err := spec.create()
+	if err != nil {
+		return false, err
+	}
+	stop := store.reportIndexProgress(ctx, spec)
+	_, err = store.ExecContext(ctx, query)
+	stop()
+	if err != nil {
+		// the build may have left an invalid index, which would make the next attempt fail.
+		store.dropIndex(context.Background(), name)
+		return false, err
+	}
+	return true, nil
+}
+
+// create returns the CREATE INDEX CONCURRENTLY statement for spec.
+func (spec IndexSpec) create() (string, error) {
+	method := spec.Method
+	if method == "" {
+		method = "btree"
+	}
+	columns := make([]string, len(spec.Columns))
+	for i, column := range spec.Columns {
+		columns[i] = QuoteIdentifier(column)
+	}
+
+	template := "create index concurrently %I on %I using %I (" + strings.Join(columns, ", ") + ")"
+	if spec.Unique {
+		template = "create unique" + strings.TrimPrefix(template, "create")
+	}
+	query, err := Format(template, spec.Name, spec.Table, method)
+	if err != nil || spec.Where == "" {
+		return query, err
+	}
+	return query + " where " + spec.Where, nil
+}
+
+// indexValid reports whether the index name exists and whether it is valid.
+func (store *SqlStore) indexValid(ctx context.Context, name string) (valid, exists bool, err error) {
+	rows, err := store.QueryContext(ctx, "select indisvalid from pg_index where indexrelid = to_regclass($1)", QuoteQualified(name))
+	if err != nil {
+		return false, false, err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		exists = true
+		err = rows.Scan(&valid)
+	}
+	if err == nil {
+		err = rows.Err()
+	}
+	return valid, exists, err
+}
+
+// dropIndex drops the index name concurrently if it exists.
+func (store *SqlStore) dropIndex(ctx context.Context, name string) error {
+	query, err := Format("drop index concurrently if exists %I", name)
+	if err != nil {
+		return err
+	}
+	_, err = store.ExecContext(ctx, query)
+	return err
+}
+
+// reportIndexProgress calls spec.Progress with the progress of index builds on spec.Table until
+// the returned function is called.
+func (store *SqlStore) reportIndexProgress(ctx context.Context, spec IndexSpec) (stop func()) {
+	if spec.Progress == nil {
+		return func() {}
+	}
+	interval := spec.ProgressInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if p, ok := store.indexProgress(ctx, spec.Table); ok {
+					spec.Progress(p)
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// indexProgress returns the progress of an index build on table, if one is running. It queries
+// the pool directly so polling doesn't show up in hooks and stats.
+func (store *SqlStore) indexProgress(ctx context.Context, table string) (p IndexProgress, ok bool) {
+	rows, err := store.db.QueryContext(ctx, "select phase, blocks_done, blocks_total, tuples_done, tuples_total "+
+		"from pg_stat_progress_create_index where relid = to_regclass($1)", QuoteQualified(table))
+	if err != nil {
+		return p, false
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return p, false
+	}
+	if err := rows.Scan(&p.Phase, &p.BlocksDone, &p.BlocksTotal, &p.TuplesDone, &p.TuplesTotal); err != nil {
+		return p, false
+	}
+	return p, true
+}
@@ -0,0 +1,27 @@
+package godbm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestIndexSpecCreate(t *testing.T) {
+	spec := IndexSpec{Name: "events_user_idx", Table: "app.events", Columns: []string{"user_id", "created"}}
+	query, err := spec.create()
+	if err != nil || query != `create index concurrently "events_user_idx" on "app"."events" using "btree" ("user_id", "created")` {
+		t.Fatalf("unexpected create: %s %v\n", query, err)
+	}
+
+	spec = IndexSpec{Name: "events_key", Table: "events", Columns: []string{"key"}, Unique: true, Method: "hash", Where: "deleted is null"}
+	query, err = spec.create()
+	if err != nil || query != `create unique index concurrently "events_key" on "events" using "hash" ("key") where deleted is null` {
+		t.Fatalf("unexpected create: %s %v\n", query, err)
+	}
+}
+
+func TestEnsureIndexSpec(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	if _, err := dbm.EnsureIndex(context.Background(), IndexSpec{Name: "idx", Table: "events"}); err == nil {
+		t.Fatalf("expected an error for a spec without columns\n")
+	}
+}
@@ -0,0 +1,79 @@
+package godbm
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+)
+
+// JSONColumn scans a json or jsonb column into a value of type T and encodes it back to json
+// when used as an argument. Valid is false when the column is NULL.
+//
+//	var payload godbm.JSONColumn[Event]
+//	err := row.Scan(&payload)
+type JSONColumn[T any] struct {
+	V     T
+	Valid bool
+}
+
+// Scan implements sql.Scanner.
+func (j *JSONColumn[T]) Scan(src interface{}) error {
+	var zero T
+	j.V, j.Valid = zero, false
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		j.Valid = true
+		return json.Unmarshal(v, &j.V)
+	case string:
+		j.Valid = true
+		return json.Unmarshal([]byte(v), &j.V)
+	}
+	return errors.New("godbm: error cannot scan " + reflect.TypeOf(src).String() + " into a JSONColumn")
+}
+
+// Value implements driver.Valuer.
+func (j JSONColumn[T]) Value() (driver.Value, error) {
+	if !j.Valid {
+		return nil, nil
+	}
+	b, err := json.Marshal(j.V)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// isJSONArg reports whether arg is a struct or map, or a pointer to one, which should be sent
+// as json. time.Time and types implementing driver.Valuer are left to the driver.
+func isJSONArg(arg interface{}) bool {
+	if arg == nil {
+		return false
+	}
+	if _, ok := arg.(driver.Valuer); ok {
+		return false
+	}
+	t := reflect.TypeOf(arg)
+	if t.Kind() == reflect.Ptr {
+		if reflect.ValueOf(arg).IsNil() {
+			return false
+		}
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return false
+	}
+	return t.Kind() == reflect.Struct || t.Kind() == reflect.Map
+}
+
+// jsonArg encodes arg as json text for a json or jsonb parameter.
+func jsonArg(arg interface{}) (interface{}, error) {
+	b, err := json.Marshal(arg)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
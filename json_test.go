@@ -0,0 +1,65 @@
+package godbm
+
+import (
+	"testing"
+	"time"
+)
+
+type jsonEvent struct {
+	Kind  string `json:"kind"`
+	Count int    `json:"count"`
+}
+
+func TestConvertJSONArgs(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	now := time.Now()
+	var nilEvent *jsonEvent
+	args := []interface{}{jsonEvent{Kind: "a", Count: 1}, map[string]int{"b": 2}, &jsonEvent{Kind: "c"}, now, nilEvent, JSONColumn[int]{V: 3, Valid: true}}
+	converted, err := dbm.convertArgs(args)
+	if err != nil {
+		t.Fatalf("error converting args: %v\n", err)
+	}
+
+	if converted[0] != `{"kind":"a","count":1}` {
+		t.Fatalf("expected struct to be encoded as json, got %v\n", converted[0])
+	}
+	if converted[1] != `{"b":2}` {
+		t.Fatalf("expected map to be encoded as json, got %v\n", converted[1])
+	}
+	if converted[2] != `{"kind":"c","count":0}` {
+		t.Fatalf("expected struct pointer to be encoded as json, got %v\n", converted[2])
+	}
+	if _, ok := converted[3].(time.Time); !ok {
+		t.Fatalf("expected time.Time to be left alone, got %T\n", converted[3])
+	}
+	if converted[4] != nilEvent {
+		t.Fatalf("expected nil pointer to be left alone, got %v\n", converted[4])
+	}
+	if _, ok := converted[5].(JSONColumn[int]); !ok {
+		t.Fatalf("expected a Valuer to be left alone, got %T\n", converted[5])
+	}
+
+	if _, err := dbm.convertArgs([]interface{}{map[string]interface{}{"f": func() {}}}); err == nil {
+		t.Fatalf("expected an error encoding a func\n")
+	}
+}
+
+func TestJSONColumn(t *testing.T) {
+	var col JSONColumn[jsonEvent]
+	if err := col.Scan([]byte(`{"kind":"a","count":2}`)); err != nil || !col.Valid || col.V.Count != 2 {
+		t.Fatalf("unexpected scan: %+v %v\n", col, err)
+	}
+	if err := col.Scan(nil); err != nil || col.Valid || col.V.Kind != "" {
+		t.Fatalf("expected NULL to reset the column: %+v %v\n", col, err)
+	}
+	if err := col.Scan(1); err == nil {
+		t.Fatalf("expected an error scanning an int\n")
+	}
+
+	if v, err := (JSONColumn[jsonEvent]{}).Value(); v != nil || err != nil {
+		t.Fatalf("expected invalid column to be NULL: %v %v\n", v, err)
+	}
+	if v, _ := (JSONColumn[[]int]{V: []int{1}, Valid: true}).Value(); v != "[1]" {
+		t.Fatalf("unexpected value: %v\n", v)
+	}
+}
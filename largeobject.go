@@ -0,0 +1,131 @@
+package godbm
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrLargeObjectNeedsTx is returned by OpenLargeObject outside a transaction, large object
+// descriptors only last until the end of the transaction which opened them.
+var ErrLargeObjectNeedsTx = errors.New("godbm: error large objects require a transaction, use WithTransaction")
+
+// large object open modes, see INV_READ and INV_WRITE in libpq-fs.h.
+const (
+	loRead  = 0x40000
+	loWrite = 0x20000
+)
+
+// loChunk is the most read or written by a single statement, so memory stays flat however large
+// the object is.
+const loChunk = 256 << 10
+
+// LargeObject is an open postgres large object, read and written in chunks of a few hundred
+// kilobytes so objects of any size can be streamed. It implements io.Reader, io.Writer,
+// io.Seeker and io.Closer and is only usable within the transaction it was opened in.
+type LargeObject struct {
+	OID uint32
+	ctx context.Context
+	tx  *Tx
+	fd  int32
+}
+
+// CreateLargeObject creates a large object holding everything read from r and returns its oid.
+// It runs in a transaction, or joins the one in ctx, so a failure part way through leaves no
+// partial object behind.
+func (store *SqlStore) CreateLargeObject(ctx context.Context, r io.Reader) (oid uint32, err error) {
+	err = store.WithTransaction(ctx, func(ctx context.Context, tx *Tx) error {
+		if err := tx.QueryRowContext(ctx, "select lo_create(0)").Scan(&oid); err != nil {
+			return err
+		}
+		lo, err := openLargeObject(ctx, tx, oid, loWrite)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(lo, r); err != nil {
+			return err
+		}
+		return lo.Close()
+	})
+	return oid, err
+}
+
+// OpenLargeObject opens the large object oid for reading, and for writing if write is set.
+// Must be called with a transaction in ctx, see WithTransaction, and the object can only be
+// used until that transaction ends. Close it when done.
+func (store *SqlStore) OpenLargeObject(ctx context.Context, oid uint32, write bool) (*LargeObject, error) {
+	tx, ok := TxFromContext(ctx)
+	if !ok {
+		return nil, ErrLargeObjectNeedsTx
+	}
+	mode := loRead
+	if write {
+		mode |= loWrite
+	}
+	return openLargeObject(ctx, tx, oid, mode)
+}
+
+// UnlinkLargeObject deletes the large object oid.
+func (store *SqlStore) UnlinkLargeObject(ctx context.Context, oid uint32) error {
+	_, err := store.ExecContext(ctx, "select lo_unlink($1)", oid)
+	return err
+}
+
+// openLargeObject opens oid with mode in tx.
+func openLargeObject(ctx context.Context, tx *Tx, oid uint32, mode int) (*LargeObject, error) {
+	lo := &LargeObject{OID: oid, ctx: ctx, tx: tx}
+	if err := tx.QueryRowContext(ctx, "select lo_open($1, $2)", oid, mode).Scan(&lo.fd); err != nil {
+		return nil, err
+	}
+	return lo, nil
+}
+
+// Read implements io.Reader.
+func (lo *LargeObject) Read(p []byte) (int, error) {
+	if len(p) > loChunk {
+		p = p[:loChunk]
+	}
+	var data []byte
+	if err := lo.tx.QueryRowContext(lo.ctx, "select loread($1, $2)", lo.fd, len(p)).Scan(&data); err != nil {
+		return 0, err
+	}
+	if len(data) == 0 && len(p) > 0 {
+		return 0, io.EOF
+	}
+	return copy(p, data), nil
+}
+
+// Write implements io.Writer.
+func (lo *LargeObject) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > loChunk {
+			chunk = chunk[:loChunk]
+		}
+		var written int
+		if err := lo.tx.QueryRowContext(lo.ctx, "select lowrite($1, $2)", lo.fd, chunk).Scan(&written); err != nil {
+			return n, err
+		}
+		n += written
+		p = p[len(chunk):]
+	}
+	return n, nil
+}
+
+// Seek implements io.Seeker.
+func (lo *LargeObject) Seek(offset int64, whence int) (position int64, err error) {
+	err = lo.tx.QueryRowContext(lo.ctx, "select lo_lseek64($1, $2, $3)", lo.fd, offset, whence).Scan(&position)
+	return position, err
+}
+
+// Truncate truncates or zero extends the object to size bytes, it must be open for writing.
+func (lo *LargeObject) Truncate(size int64) error {
+	_, err := lo.tx.ExecContext(lo.ctx, "select lo_truncate64($1, $2)", lo.fd, size)
+	return err
+}
+
+// Close implements io.Closer. Objects still open are closed when the transaction ends.
+func (lo *LargeObject) Close() error {
+	_, err := lo.tx.ExecContext(lo.ctx, "select lo_close($1)", lo.fd)
+	return err
+}
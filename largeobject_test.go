@@ -0,0 +1,13 @@
+package godbm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestOpenLargeObjectNeedsTx(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	if _, err := dbm.OpenLargeObject(context.Background(), 1, false); err != ErrLargeObjectNeedsTx {
+		t.Fatalf("expected ErrLargeObjectNeedsTx got %v\n", err)
+	}
+}
@@ -0,0 +1,151 @@
+package godbm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// errPossibleLeak is logged for rows or transactions left open beyond the leak threshold.
+var errPossibleLeak = errors.New("godbm: error rows or transaction left open")
+
+// LeakOptions configures debug tracking of *sql.Rows and transactions which are not closed.
+type LeakOptions struct {
+	Threshold time.Duration // rows and transactions open at least this long are reported.
+	Interval  time.Duration // how often open rows and transactions are checked, defaults to Threshold.
+	Callback  func(Leak)    // optional, called for each leak, otherwise leaks are logged.
+}
+
+// Leak describes rows or a transaction which has been left open.
+type Leak struct {
+	Kind   string        // "rows" or "tx".
+	Key    string        // the statement key for rows, empty for ad-hoc queries and transactions.
+	Opened time.Time     // when the rows were returned or the transaction began.
+	Age    time.Duration // how long it had been open when reported.
+	Stack  string        // the stack of the goroutine which opened it.
+}
+
+// SetLeakDetection enables tracking of the rows returned by Query and QueryPrepared calls and
+// of transactions started with BeginTx and WithTransaction, reporting any left open beyond the
+// threshold along with the stack which opened them. Each is reported once. Capturing stacks is
+// expensive so this is meant for debugging. Passing nil disables it. Must be called before
+// Connect.
+func (store *SqlStore) SetLeakDetection(opts *LeakOptions) {
+	store.leakOpts = opts
+}
+
+// leakTracker holds the rows and transactions being tracked.
+type leakTracker struct {
+	mu   sync.Mutex
+	open map[*tracked]struct{}
+	stop chan struct{}
+}
+
+// tracked is an open rows or transaction.
+type tracked struct {
+	leak     Leak
+	closed   func() bool
+	reported bool
+}
+
+// startLeakDetection starts checking for leaks if enabled.
+func (store *SqlStore) startLeakDetection() {
+	if store.leakOpts == nil || store.leakOpts.Threshold <= 0 {
+		return
+	}
+	interval := store.leakOpts.Interval
+	if interval <= 0 {
+		interval = store.leakOpts.Threshold
+	}
+	store.leaks = &leakTracker{open: make(map[*tracked]struct{}), stop: make(chan struct{})}
+	go store.checkLeaks(store.leaks, interval)
+}
+
+// stopLeakDetection stops checking for leaks.
+func (store *SqlStore) stopLeakDetection() {
+	if store.leaks != nil {
+		close(store.leaks.stop)
+		store.leaks = nil
+	}
+}
+
+// track starts tracking rows or a transaction, closed reports whether it has been closed.
+func (store *SqlStore) track(kind, key string, closed func() bool) {
+	leaks := store.leaks
+	if leaks == nil {
+		return
+	}
+	t := &tracked{leak: Leak{Kind: kind, Key: key, Opened: time.Now(), Stack: string(debug.Stack())}, closed: closed}
+	leaks.mu.Lock()
+	leaks.open[t] = struct{}{}
+	leaks.mu.Unlock()
+}
+
+// trackRows tracks rows returned for the statement key.
+func (store *SqlStore) trackRows(key string, rows *sql.Rows) {
+	store.track("rows", key, func() bool {
+		// Columns only fails once the rows are closed.
+		_, err := rows.Columns()
+		return err != nil
+	})
+}
+
+// trackTx tracks a transaction until it is committed, rolled back or its context is done.
+func (store *SqlStore) trackTx(ctx context.Context, tx *Tx) {
+	store.track("tx", "", func() bool {
+		return tx.done.Load() || ctx.Err() != nil
+	})
+}
+
+// checkLeaks reports tracked rows and transactions left open beyond the threshold, every
+// interval until stopped.
+func (store *SqlStore) checkLeaks(leaks *leakTracker, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-leaks.stop:
+			return
+		case <-ticker.C:
+			for _, leak := range leaks.check(store.leakOpts.Threshold) {
+				store.reportLeak(leak)
+			}
+		}
+	}
+}
+
+// check forgets closed rows and transactions and returns those newly past the threshold.
+func (leaks *leakTracker) check(threshold time.Duration) []Leak {
+	leaks.mu.Lock()
+	defer leaks.mu.Unlock()
+
+	var found []Leak
+	now := time.Now()
+	for t := range leaks.open {
+		if t.closed() {
+			delete(leaks.open, t)
+			continue
+		}
+		if age := now.Sub(t.leak.Opened); age >= threshold && !t.reported {
+			t.reported = true
+			leak := t.leak
+			leak.Age = age
+			found = append(found, leak)
+		}
+	}
+	return found
+}
+
+// reportLeak passes leak to the callback or logs it.
+func (store *SqlStore) reportLeak(leak Leak) {
+	if store.leakOpts.Callback != nil {
+		store.leakOpts.Callback(leak)
+		return
+	}
+	store.logEvent(context.Background(), "godbm: possible leak", errPossibleLeak,
+		slog.String("kind", leak.Kind), slog.String("key", leak.Key), slog.Duration("age", leak.Age), slog.String("stack", leak.Stack))
+}
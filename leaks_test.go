@@ -0,0 +1,39 @@
+package godbm
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLeakTracker(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.SetLeakDetection(&LeakOptions{Threshold: time.Hour})
+	dbm.startLeakDetection()
+	defer dbm.stopLeakDetection()
+
+	closed := false
+	dbm.track("rows", "get", func() bool { return closed })
+	tx := &Tx{}
+	dbm.trackTx(context.Background(), tx)
+
+	if leaks := dbm.leaks.check(time.Hour); len(leaks) != 0 {
+		t.Fatalf("expected nothing to be reported before the threshold, got %v\n", leaks)
+	}
+
+	leaks := dbm.leaks.check(0)
+	if len(leaks) != 2 || !strings.Contains(leaks[0].Stack, "TestLeakTracker") {
+		t.Fatalf("expected both to be reported with their stacks, got %+v\n", leaks)
+	}
+	if leaks := dbm.leaks.check(0); len(leaks) != 0 {
+		t.Fatalf("expected leaks to be reported once, got %v\n", leaks)
+	}
+
+	closed = true
+	tx.done.Store(true)
+	dbm.leaks.check(0)
+	if len(dbm.leaks.open) != 0 {
+		t.Fatalf("expected closed rows and transactions to be forgotten\n")
+	}
+}
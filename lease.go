@@ -0,0 +1,134 @@
+package godbm
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrLeaseHeld is returned by AcquireLease while another owner holds an unexpired lease.
+var ErrLeaseHeld = errors.New("godbm: error lease is held by another owner")
+
+// leaseTable holds every lease, it is created by the first AcquireLease.
+const leaseTable = `create table if not exists godbm_leases (
+	name text primary key, owner text not null, token bigint not null, expires timestamptz not null)`
+
+// Lease is a named lock held in the godbm_leases table until it expires, which unlike an
+// advisory lock doesn't depend on a connection staying open. It is renewed in the background
+// while held. Pass Token to the resources the lease protects as a fencing token, they should
+// reject writes carrying a lower token than one they have already seen.
+type Lease struct {
+	Name  string
+	Token int64 // increases every time the lease changes owner.
+
+	store *SqlStore
+	owner string
+	lost  chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+// AcquireLease acquires the lease name for ttl, returning ErrLeaseHeld if another owner holds
+// it and it hasn't expired. The lease is renewed every quarter of ttl until Release is called or
+// renewal keeps failing, in which case Lost is closed. Expiry is decided with database time so
+// clock skew between processes doesn't matter.
+func (store *SqlStore) AcquireLease(ctx context.Context, name string, ttl time.Duration) (*Lease, error) {
+	if ttl <= 0 {
+		return nil, errors.New("godbm: error lease ttl must be positive")
+	}
+	if _, err := store.ExecContext(ctx, leaseTable); err != nil {
+		return nil, err
+	}
+
+	owner := make([]byte, 16)
+	if _, err := rand.Read(owner); err != nil {
+		return nil, err
+	}
+	sent := time.Now()
+	l := &Lease{Name: name, store: store, owner: hex.EncodeToString(owner),
+		lost: make(chan struct{}), stop: make(chan struct{}), done: make(chan struct{})}
+
+	rows, err := store.QueryContext(ctx, `insert into godbm_leases (name, owner, token, expires) values ($1, $2, 1, now() + $3::interval)
+		on conflict (name) do update set owner = excluded.owner, token = godbm_leases.token + 1, expires = excluded.expires
+		where godbm_leases.expires < now() returning token`, name, l.owner, Interval(ttl))
+	if err != nil {
+		return nil, err
+	}
+	acquired := rows.Next()
+	if acquired {
+		err = rows.Scan(&l.Token)
+	}
+	if cerr := rows.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !acquired {
+		return nil, ErrLeaseHeld
+	}
+
+	go l.renew(ttl, sent.Add(ttl))
+	return l, nil
+}
+
+// Lost is closed if the lease could not be renewed and less than a quarter of ttl is left before
+// it may expire, or it was taken by another owner. Stop work protected by the lease when it is.
+func (l *Lease) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Release stops renewing the lease and gives it up so another owner can acquire it at once. The
+// row is expired rather than deleted so the next owner's token is still higher.
+func (l *Lease) Release(ctx context.Context) error {
+	l.once.Do(func() { close(l.stop) })
+	<-l.done
+	_, err := l.store.ExecContext(ctx, "update godbm_leases set expires = '-infinity' where name = $1 and owner = $2", l.Name, l.owner)
+	return err
+}
+
+// renew extends the lease every quarter of ttl until it is released or lost. The lease expires
+// ttl after the last successful renewal was sent, at the latest, and is given up as lost once a
+// renewal fails with less than a quarter of ttl left so its holder has time to stop.
+func (l *Lease) renew(ttl time.Duration, expires time.Time) {
+	defer close(l.done)
+	margin := ttl / 4
+	ticker := time.NewTicker(margin)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+
+		sent := time.Now()
+		ctx, cancel := context.WithDeadline(context.Background(), expires.Add(-margin))
+		result, err := l.store.ExecContext(ctx, "update godbm_leases set expires = now() + $3::interval where name = $1 and owner = $2",
+			l.Name, l.owner, Interval(ttl))
+		cancel()
+		var renewed int64
+		if err == nil {
+			renewed, err = result.RowsAffected()
+		}
+		switch {
+		case err == nil && renewed == 0:
+			err = ErrLeaseHeld
+		case err == nil:
+			expires = sent.Add(ttl)
+			continue
+		case time.Until(expires) > margin:
+			// keep trying while the lease may still be ours.
+			l.store.logEvent(context.Background(), "godbm: lease renewal", err, slog.String("lease", l.Name))
+			continue
+		}
+		l.store.logEvent(context.Background(), "godbm: lease lost", err, slog.String("lease", l.Name))
+		close(l.lost)
+		return
+	}
+}
@@ -0,0 +1,33 @@
+package godbm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAcquireLeaseTTL(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	if _, err := dbm.AcquireLease(context.Background(), "reports", 0); err == nil {
+		t.Fatalf("expected an error for a zero ttl\n")
+	}
+}
+
+func TestLeaseLostBeforeExpiry(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	l := &Lease{Name: "reports", store: dbm, lost: make(chan struct{}), stop: make(chan struct{}), done: make(chan struct{})}
+
+	// every renewal fails as the store isn't connected.
+	ttl := 800 * time.Millisecond
+	start := time.Now()
+	go l.renew(ttl, start.Add(ttl))
+	select {
+	case <-l.Lost():
+	case <-time.After(2 * ttl):
+		t.Fatalf("expected the lease to be lost\n")
+	}
+	if left := ttl - time.Since(start); left < ttl/8 {
+		t.Fatalf("expected the lease to be lost well before it expires, %v was left\n", left)
+	}
+	<-l.done
+}
@@ -0,0 +1,63 @@
+package godbm
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+)
+
+// ConnectionHooks are called as the store's connections change state. Any may be nil.
+type ConnectionHooks struct {
+	OnConnect         func(ctx context.Context, conn *Conn) error // called for every new connection before it is used, an error discards the connection
+	OnDisconnect      func()                                      // called when Disconnect closes the store
+	OnConnectionError func(host string, err error)                // called when opening a connection to host fails
+}
+
+// SetConnectionHooks sets the callbacks run as connections are opened, fail or the store is
+// disconnected, for running per connection SET statements, warming caches or alerting on
+// flapping. Must be called before Connect.
+func (store *SqlStore) SetConnectionHooks(h ConnectionHooks) {
+	store.connHooks = h
+}
+
+// Conn is a newly opened connection passed to ConnectionHooks.OnConnect.
+type Conn struct {
+	Host string // the host entry the connection was opened to
+	conn driver.Conn
+}
+
+// Exec executes query on the connection, args must be valid driver values.
+func (c *Conn) Exec(ctx context.Context, query string, args ...interface{}) error {
+	execer, ok := c.conn.(driver.ExecerContext)
+	if !ok {
+		return errors.New("godbm: error driver does not support exec")
+	}
+
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		v, err := driver.DefaultParameterConverter.ConvertValue(arg)
+		if err != nil {
+			return err
+		}
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	_, err := execer.ExecContext(ctx, query, named)
+	return err
+}
+
+// onConnect runs the OnConnect hook for a new connection to host.
+func (store *SqlStore) onConnect(ctx context.Context, host string, conn driver.Conn) error {
+	if store.connHooks.OnConnect == nil {
+		return nil
+	}
+	return store.connHooks.OnConnect(ctx, &Conn{Host: host, conn: conn})
+}
+
+// onConnectionError logs a failed connection to host and runs the OnConnectionError hook.
+func (store *SqlStore) onConnectionError(host string, err error) {
+	store.logEvent(context.Background(), "godbm: connection failed", err, slog.String("host", host))
+	if store.connHooks.OnConnectionError != nil {
+		store.connHooks.OnConnectionError(host, err)
+	}
+}
@@ -0,0 +1,94 @@
+package godbm
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// execConn is a driver connection recording the statements executed on it.
+type execConn struct {
+	driver.Conn
+	execs  []string
+	closed bool
+}
+
+func (c *execConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.execs = append(c.execs, query)
+	return driver.RowsAffected(0), nil
+}
+
+func (c *execConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+// execDriver opens execConns.
+type execDriver struct {
+	conns []*execConn
+}
+
+func (d *execDriver) Open(dsn string) (driver.Conn, error) {
+	conn := &execConn{}
+	d.conns = append(d.conns, conn)
+	return conn, nil
+}
+
+func TestConnectionHooks(t *testing.T) {
+	drv := &execDriver{}
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.SetDriver(drv)
+
+	hookErr := errors.New("warm up failed")
+	fail := false
+	dbm.SetConnectionHooks(ConnectionHooks{
+		OnConnect: func(ctx context.Context, conn *Conn) error {
+			if err := conn.Exec(ctx, "set role app"); err != nil {
+				return err
+			}
+			if fail {
+				return hookErr
+			}
+			return nil
+		},
+	})
+
+	c := dbm.newConnector(dbm.host)
+	if _, err := c.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(drv.conns[0].execs) != 1 || drv.conns[0].execs[0] != "set role app" {
+		t.Fatalf("expected OnConnect to run on the new connection, got %v\n", drv.conns[0].execs)
+	}
+
+	fail = true
+	if _, err := c.Connect(context.Background()); err != hookErr || !drv.conns[1].closed {
+		t.Fatalf("expected a failing hook to discard the connection, got %v\n", err)
+	}
+}
+
+func TestConnectionErrorHook(t *testing.T) {
+	dbm := New(username, password, dbname, "db1,db2", "disable", "")
+	dbm.SetDriver(&dsnDriver{})
+	var buf bytes.Buffer
+	dbm.SetLogger(slog.New(slog.NewTextHandler(&buf, nil)), nil)
+
+	var failed []string
+	dbm.SetConnectionHooks(ConnectionHooks{
+		OnConnectionError: func(host string, err error) {
+			failed = append(failed, host)
+		},
+	})
+
+	dbm.newConnector(dbm.host).Connect(context.Background())
+	if len(failed) != 2 || failed[0] != "db1" || failed[1] != "db2" {
+		t.Fatalf("expected a connection error per host, got %v\n", failed)
+	}
+	if out := buf.String(); strings.Count(out, "godbm: connection failed") != 2 || !strings.Contains(out, "host=db2") {
+		t.Fatalf("expected every failed connection to be logged, got: %s\n", out)
+	}
+}
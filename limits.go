@@ -0,0 +1,166 @@
+package godbm
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// MaxConcurrent limits the statement to n calls running at once, further calls wait for a slot
+// or for their context to be done. For queries a slot is held until the query returns, not
+// while its rows are read. Zero leaves the statement unlimited.
+func MaxConcurrent(n int) StmtOption {
+	return func(o *stmtOptions) {
+		if n <= 0 {
+			o.concurrency = nil
+			return
+		}
+		o.concurrency = make(semaphore, n)
+	}
+}
+
+// RateLimit limits the statement to perSecond calls on average with bursts of up to burst
+// calls, further calls wait for their turn or for their context to be done. A rate of zero
+// leaves the statement unlimited.
+func RateLimit(perSecond float64, burst int) StmtOption {
+	return func(o *stmtOptions) {
+		if perSecond <= 0 {
+			o.rate = nil
+			return
+		}
+		o.rate = newTokenBucket(perSecond, burst)
+	}
+}
+
+// SetConcurrencyLimit limits the number of calls running at once across every statement and
+// ad-hoc query, applied in addition to MaxConcurrent. Keep it below the pool's MaxOpenConns to
+// leave connections free for transactions. Zero removes the limit. Must be called before the
+// store is in use.
+func (store *SqlStore) SetConcurrencyLimit(n int) {
+	if n <= 0 {
+		store.concurrency = nil
+		return
+	}
+	store.concurrency = make(semaphore, n)
+}
+
+// limit waits for st's rate limit and for a slot in the call's pool class, unless the query or
+// transaction it runs in holds one, and the store's and st's concurrency limits, returning a
+// function releasing the slots.
+func (store *SqlStore) limit(ctx context.Context, st *statement) (release func(), err error) {
+	var class semaphore
+	if !classHeld(ctx) {
+		if class, err = store.poolClass(ctx, st); err != nil {
+			return nil, err
+		}
+	}
+	if st.opts.rate != nil {
+		if err := st.opts.rate.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	waitCtx, cancel := waitContext(ctx)
+	defer cancel()
+	sems := []semaphore{class, store.concurrency, st.opts.concurrency}
+	for i, sem := range sems {
+		if err := sem.acquire(waitCtx); err != nil {
+			for j := i - 1; j >= 0; j-- {
+				sems[j].release()
+			}
+			return nil, poolWaitErr(ctx, waitCtx, err)
+		}
+	}
+	return func() {
+		for i := len(sems) - 1; i >= 0; i-- {
+			sems[i].release()
+		}
+	}, nil
+}
+
+// limited reports whether calls of st are subject to any limit.
+func (store *SqlStore) limited(st *statement) bool {
+	return store.concurrency != nil || store.poolClasses != nil || st.opts.concurrency != nil || st.opts.rate != nil
+}
+
+// semaphore limits concurrency to its capacity, a nil semaphore is unlimited.
+type semaphore chan struct{}
+
+// acquire waits for a slot or for ctx to be done.
+func (s semaphore) acquire(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees a slot taken by acquire.
+func (s semaphore) release() {
+	if s != nil {
+		<-s
+	}
+}
+
+// tokenBucket is a token bucket rate limiter.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64   // tokens added per second
+	burst  float64   // maximum number of tokens
+	tokens float64   // tokens available at last, may be negative when callers are queued
+	last   time.Time // when tokens was last updated
+	now    func() time.Time
+}
+
+// newTokenBucket creates a full bucket.
+func newTokenBucket(perSecond float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{rate: perSecond, burst: float64(burst), tokens: float64(burst), now: time.Now}
+}
+
+// reserve takes a token and returns how long the caller must wait before using it.
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	if !b.last.IsZero() {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel returns a token taken by reserve which won't be used.
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = math.Min(b.burst, b.tokens+1)
+}
+
+// wait waits for a token or for ctx to be done.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	delay := b.reserve()
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,186 @@
+package godbm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+func TestLimitConcurrency(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.SetConcurrencyLimit(2)
+	st := &statement{key: "report"}
+	MaxConcurrent(1)(&st.opts)
+
+	release, err := dbm.limit(context.Background(), st)
+	if err != nil {
+		t.Fatalf("error acquiring a slot: %v\n", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := dbm.limit(ctx, st); err != context.DeadlineExceeded {
+		t.Fatalf("expected the statement limit to block got %v\n", err)
+	}
+	if len(dbm.concurrency) != 1 {
+		t.Fatalf("expected the store slot to be released when the statement limit times out\n")
+	}
+
+	other, err := dbm.limit(context.Background(), &statement{})
+	if err != nil {
+		t.Fatalf("expected other statements to use the store's remaining slot: %v\n", err)
+	}
+	release()
+	other()
+	if len(dbm.concurrency) != 0 || len(st.opts.concurrency) != 0 {
+		t.Fatalf("expected every slot to be released\n")
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	b := newTokenBucket(10, 2)
+	b.now = func() time.Time { return now }
+
+	if b.reserve() != 0 || b.reserve() != 0 {
+		t.Fatalf("expected the burst to be available immediately\n")
+	}
+	if delay := b.reserve(); delay != 100*time.Millisecond {
+		t.Fatalf("expected to wait for the next token got %v\n", delay)
+	}
+	if delay := b.reserve(); delay != 200*time.Millisecond {
+		t.Fatalf("expected queued callers to wait in turn got %v\n", delay)
+	}
+
+	now = now.Add(time.Second)
+	if b.reserve() != 0 {
+		t.Fatalf("expected tokens to refill\n")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b.tokens = -1
+	if err := b.wait(ctx); err != context.Canceled || b.tokens != -1 {
+		t.Fatalf("expected a cancelled wait to return its token: %v %v\n", err, b.tokens)
+	}
+}
+
+func TestPoolClasses(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.SetPoolClasses(map[string]int{"interactive": 2, "batch": 1})
+	st := &statement{key: "export"}
+	PoolClass("batch")(&st.opts)
+
+	release, err := dbm.limit(context.Background(), st)
+	if err != nil {
+		t.Fatalf("error acquiring a batch slot: %v\n", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := dbm.limit(ctx, st); err != context.DeadlineExceeded {
+		t.Fatalf("expected the batch budget to be exhausted got %v\n", err)
+	}
+
+	interactive, err := dbm.limit(WithPoolClass(context.Background(), "interactive"), st)
+	if err != nil {
+		t.Fatalf("expected the context class to take precedence: %v\n", err)
+	}
+	interactive()
+
+	if _, err := dbm.limit(WithPoolClass(context.Background(), "nightly"), st); err == nil {
+		t.Fatalf("expected an error for an unknown class\n")
+	}
+}
+
+func TestLimitsZeroUnlimited(t *testing.T) {
+	st := &statement{key: "report"}
+	MaxConcurrent(0)(&st.opts)
+	RateLimit(0, 5)(&st.opts)
+	if st.opts.concurrency != nil || st.opts.rate != nil {
+		t.Fatalf("expected zero limits to leave the statement unlimited\n")
+	}
+
+	dbm := New(username, password, dbname, host, "disable", "")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	release, err := dbm.limit(ctx, st)
+	if err != nil {
+		t.Fatalf("expected an unlimited statement not to wait got %v\n", err)
+	}
+	release()
+}
+
+func TestPoolClassZeroUnlimited(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.SetPoolClasses(map[string]int{"batch": 0})
+	ctx, cancel := context.WithTimeout(WithPoolClass(context.Background(), "batch"), time.Second)
+	defer cancel()
+	release, err := dbm.limit(ctx, &statement{key: "report"})
+	if err != nil {
+		t.Fatalf("expected a zero budget class not to wait got %v\n", err)
+	}
+	release()
+}
+
+func TestPoolClassHeld(t *testing.T) {
+	sql.Register("godbm-class-rows", &rowsDriver{columns: []string{"id"}, values: [][]driver.Value{{int64(1)}}})
+	db, err := sql.Open("godbm-class-rows", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.SetPoolClasses(map[string]int{"batch": 1})
+	st := &statement{key: "export"}
+	PoolClass("batch")(&st.opts)
+	batch := dbm.poolClasses["batch"]
+
+	ctx, release, err := dbm.holdClass(context.Background(), st)
+	if err != nil || release == nil {
+		t.Fatalf("error holding a batch slot: %v\n", err)
+	}
+	limited, err := dbm.limit(ctx, st)
+	if err != nil {
+		t.Fatalf("expected calls of a query holding its slot not to take another: %v\n", err)
+	}
+	limited()
+
+	rows, err := db.Query("select")
+	if err != nil {
+		t.Fatal(err)
+	}
+	releaseOnClose(rows, release)
+	time.Sleep(3 * rowsPollInterval)
+	if len(batch) != 1 {
+		t.Fatalf("expected the slot to be held while the rows are open\n")
+	}
+	rows.Close()
+	for deadline := time.Now().Add(time.Second); len(batch) != 0; time.Sleep(rowsPollInterval) {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the slot to be released once the rows are closed\n")
+		}
+	}
+
+	tx := &Tx{}
+	_, tx.release, err = dbm.holdClass(context.Background(), st)
+	if err != nil {
+		t.Fatalf("error holding a batch slot for the transaction: %v\n", err)
+	}
+	inTx, err := dbm.limit(withTx(context.Background(), tx), st)
+	if err != nil {
+		t.Fatalf("expected statements in the transaction to run in its slot: %v\n", err)
+	}
+	inTx()
+	if len(batch) != 1 {
+		t.Fatalf("expected the transaction to hold its slot between statements\n")
+	}
+	tx.end()
+	if len(batch) != 0 {
+		t.Fatalf("expected the slot to be released when the transaction ends\n")
+	}
+}
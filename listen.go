@@ -0,0 +1,69 @@
+package godbm
+
+import (
+	"context"
+	"errors"
+	"github.com/lib/pq"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Notification is a message sent with NOTIFY, or pg_notify, on a channel being listened to.
+type Notification struct {
+	Channel     string // the channel the notification was sent on.
+	Payload     string // the payload, empty if none was given.
+	Reconnected bool   // the connection was re-established, notifications may have been missed.
+}
+
+// Listen calls fn for each notification sent on any of channels, until ctx is done. It uses a
+// dedicated connection to the store's first host, which is re-established if it drops, in which
+// case fn is called with Reconnected set. The credentials are fetched once when Listen is called.
+// Only supported with lib/pq.
+func (store *SqlStore) Listen(ctx context.Context, fn func(n Notification), channels ...string) error {
+	if !store.isPQ() {
+		return errors.New("godbm: error Listen requires lib/pq")
+	}
+	host := strings.Split(store.host, ",")[0]
+	user, password, err := store.credentialsFor(ctx)
+	if err != nil {
+		return err
+	}
+	if password, err = store.passwordFor(ctx, host, user, password); err != nil {
+		return err
+	}
+
+	listener := pq.NewDialListener(&pqDialer{store: store}, store.dsnWith(host, user, password), 100*time.Millisecond, time.Minute,
+		func(event pq.ListenerEventType, err error) {
+			if err != nil {
+				store.logEvent(ctx, "godbm: listener", err, slog.String("host", host))
+			}
+		})
+	defer listener.Close()
+
+	for _, channel := range channels {
+		if err := listener.Listen(channel); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case n := <-listener.Notify:
+			// lib/pq sends nil after reconnecting.
+			if n == nil {
+				fn(Notification{Reconnected: true})
+				continue
+			}
+			fn(Notification{Channel: n.Channel, Payload: n.Extra})
+		}
+	}
+}
+
+// Notify sends a notification with payload on channel.
+func (store *SqlStore) Notify(ctx context.Context, channel, payload string) error {
+	_, err := store.ExecContext(ctx, "select pg_notify($1, $2)", channel, payload)
+	return err
+}
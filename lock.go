@@ -0,0 +1,28 @@
+package godbm
+
+import "errors"
+
+// ErrRegistryLocked is returned when sql which isn't registered is run, or the registry is
+// changed, after LockRegistry.
+var ErrRegistryLocked = errors.New("godbm: error statement registry is locked")
+
+// LockRegistry freezes the registered statements, typically once startup registration is done.
+// Afterwards PrepareAdd, PrepareDel, PrepareStatement, Exec, Query and Explain with sql instead
+// of a key return ErrRegistryLocked, so only the registered statements can ever run and sql
+// built from strings at runtime can't reach the database. The registry can't be unlocked.
+func (store *SqlStore) LockRegistry() {
+	store.registryLocked.Store(true)
+}
+
+// RegistryLocked reports whether LockRegistry has been called.
+func (store *SqlStore) RegistryLocked() bool {
+	return store.registryLocked.Load()
+}
+
+// checkUnlocked returns ErrRegistryLocked if the registry is locked.
+func (store *SqlStore) checkUnlocked() error {
+	if store.registryLocked.Load() {
+		return ErrRegistryLocked
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package godbm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLockRegistry(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.Connected = true
+	dbm.queries = map[string]*statement{"get": {key: "get", query: "select 1"}}
+	dbm.LockRegistry()
+
+	if !dbm.RegistryLocked() {
+		t.Fatalf("expected the registry to be locked\n")
+	}
+	if _, err := dbm.Exec("delete from users"); err != ErrRegistryLocked {
+		t.Fatalf("expected Exec to be refused, got %v\n", err)
+	}
+	if _, err := dbm.Query("select * from users"); err != ErrRegistryLocked {
+		t.Fatalf("expected Query to be refused, got %v\n", err)
+	}
+	if err := dbm.PrepareAdd("new", "select 2"); err != ErrRegistryLocked {
+		t.Fatalf("expected PrepareAdd to be refused, got %v\n", err)
+	}
+	if err := dbm.PrepareDel("get"); err != ErrRegistryLocked {
+		t.Fatalf("expected PrepareDel to be refused, got %v\n", err)
+	}
+	if _, err := dbm.Explain(context.Background(), "select * from users"); err != ErrRegistryLocked {
+		t.Fatalf("expected Explain of sql to be refused, got %v\n", err)
+	}
+	if query, err := dbm.resolveQuery("get"); err != nil || query != "select 1" {
+		t.Fatalf("expected registered statements to resolve, got %q %v\n", query, err)
+	}
+}
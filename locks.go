@@ -0,0 +1,126 @@
+package godbm
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// blockedQuery lists each backend of the current database waiting on a lock along with each
+// backend holding or queued ahead for it, longest waiting first.
+const blockedQuery = `select a.pid, a.query, coalesce(a.wait_event, ''),
+	extract(epoch from clock_timestamp() - a.query_start),
+	b.pid, b.query, coalesce(b.state, ''), coalesce(extract(epoch from clock_timestamp() - b.xact_start), 0)
+	from pg_stat_activity a
+	cross join lateral unnest(pg_blocking_pids(a.pid)) blocker(pid)
+	join pg_stat_activity b on b.pid = blocker.pid
+	where a.datname = current_database()
+	order by a.query_start, b.pid`
+
+// BlockedQuery is a backend waiting on a lock and one of the backends blocking it. A backend
+// blocked by several others is reported once for each.
+type BlockedQuery struct {
+	Pid      int           // the waiting backend.
+	Query    string        // the waiting query.
+	LockType string        // what is waited on such as "relation", "tuple" or "transactionid".
+	Waiting  time.Duration // how long the waiting query has run.
+
+	BlockingPid   int           // the blocking backend.
+	BlockingQuery string        // the blocking backend's current or last query.
+	BlockingState string        // such as "active" or "idle in transaction".
+	BlockingXact  time.Duration // how long the blocking backend's transaction has been open.
+}
+
+// BlockedQueries returns who blocks whom in the current database, using pg_blocking_pids. As
+// with ActiveQueries, other users' queries are hidden without the pg_read_all_stats role.
+func (store *SqlStore) BlockedQueries(ctx context.Context) ([]BlockedQuery, error) {
+	rows, err := store.QueryContext(ctx, blockedQuery)
+	if err != nil {
+		return nil, err
+	}
+	return scanBlocked(rows)
+}
+
+// scanBlocked reads the rows of blockedQuery and closes them.
+func scanBlocked(rows *sql.Rows) ([]BlockedQuery, error) {
+	defer rows.Close()
+
+	var blocked []BlockedQuery
+	for rows.Next() {
+		var b BlockedQuery
+		var waiting, xact float64
+		err := rows.Scan(&b.Pid, &b.Query, &b.LockType, &waiting, &b.BlockingPid, &b.BlockingQuery, &b.BlockingState, &xact)
+		if err != nil {
+			return nil, err
+		}
+		b.Waiting = time.Duration(waiting * float64(time.Second))
+		b.BlockingXact = time.Duration(xact * float64(time.Second))
+		blocked = append(blocked, b)
+	}
+	return blocked, rows.Err()
+}
+
+// SlowLock describes a call which has waited on a lock beyond the lock wait threshold.
+type SlowLock struct {
+	Key      string         // statement key, empty for ad-hoc queries.
+	Query    string         // the sql text.
+	Waited   time.Duration  // how long the call had run when the wait was found.
+	Blockers []BlockedQuery // the backends blocking it.
+}
+
+// SlowLockOptions configures lock wait detection.
+type SlowLockOptions struct {
+	Threshold time.Duration       // calls still running after this long are checked for lock waits.
+	Callback  func(lock SlowLock) // called at most once per call found waiting on a lock.
+}
+
+// SetSlowLockOptions enables lock wait detection, passing nil disables it. Each call still
+// running after the threshold is looked up in pg_stat_activity by its sql text and reported
+// if it is waiting on a lock, so two calls of the same statement can't be told apart. This
+// should be called before the store is in use.
+func (store *SqlStore) SetSlowLockOptions(opts *SlowLockOptions) {
+	store.slowLock = opts
+}
+
+// watchSlowLock checks for st waiting on a lock once the lock wait threshold passes, until the
+// returned function is called.
+func (store *SqlStore) watchSlowLock(st *statement, start time.Time) (stop func()) {
+	opts := store.slowLock
+	if opts == nil || opts.Callback == nil {
+		return func() {}
+	}
+	timer := time.AfterFunc(opts.Threshold, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		// the pool is queried directly so the check isn't itself hooked, counted or watched.
+		rows, err := store.db.QueryContext(ctx, blockedQuery)
+		if err != nil {
+			return
+		}
+		blocked, err := scanBlocked(rows)
+		if err != nil {
+			return
+		}
+
+		lock := SlowLock{Key: st.key, Query: st.query, Waited: time.Since(start)}
+		lock.Blockers = blockedFor(blocked, st.query)
+		if len(lock.Blockers) > 0 {
+			opts.Callback(lock)
+		}
+	})
+	return func() { timer.Stop() }
+}
+
+// blockedFor returns the entries of blocked running query. They are compared by fingerprint,
+// since the server reports the sql as sent, with any statement label or sqlcommenter comment.
+func blockedFor(blocked []BlockedQuery, query string) []BlockedQuery {
+	want := fingerprint(query)
+	var found []BlockedQuery
+	for _, b := range blocked {
+		if strings.HasPrefix(fingerprint(b.Query), want) {
+			found = append(found, b)
+		}
+	}
+	return found
+}
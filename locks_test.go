@@ -0,0 +1,31 @@
+package godbm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWatchSlowLockDisabled(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.watchSlowLock(&statement{query: "select 1"}, time.Now())()
+
+	dbm.SetSlowLockOptions(&SlowLockOptions{Threshold: time.Hour, Callback: func(SlowLock) {
+		t.Fatalf("unexpected lock wait\n")
+	}})
+	dbm.watchSlowLock(&statement{query: "select 1"}, time.Now())()
+}
+
+func TestBlockedForLabeled(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.SetStatementLabels(func(key string) string { return "billing." + key })
+	query := "update accounts set balance = $1 where id = $2"
+	blocked := []BlockedQuery{
+		{Pid: 1, Query: dbm.labeled("debit", query) + " /*application='api'*/"},
+		{Pid: 2, Query: "update accounts set owner = $1 where id = $2"},
+	}
+
+	found := blockedFor(blocked, query)
+	if len(found) != 1 || found[0].Pid != 1 {
+		t.Fatalf("expected the labeled query to match got %+v\n", found)
+	}
+}
@@ -0,0 +1,87 @@
+package godbm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// LogOptions controls what the store logs and at which levels.
+type LogOptions struct {
+	EventLevel slog.Level // connection and statement registration events.
+	QueryLevel slog.Level // successful queries along with their duration.
+	ErrorLevel slog.Level // failed connections, registrations and queries.
+	LogArgs    bool       // log argument values after redaction, see SetRedactor, otherwise only their types are logged.
+}
+
+// DefaultLogOptions logs events at info, queries at debug and errors at error level without
+// argument values.
+var DefaultLogOptions = LogOptions{
+	EventLevel: slog.LevelInfo,
+	QueryLevel: slog.LevelDebug,
+	ErrorLevel: slog.LevelError,
+}
+
+// SetLogger sets a structured logger for the store. If opts is nil DefaultLogOptions is used.
+// Passing a nil logger disables logging. This should be called before the store is in use.
+func (store *SqlStore) SetLogger(logger *slog.Logger, opts *LogOptions) {
+	if opts == nil {
+		opts = &DefaultLogOptions
+	}
+	store.logger = logger
+	store.logOpts = *opts
+}
+
+// logEvent logs a connection or registration event, or an error if err is set.
+func (store *SqlStore) logEvent(ctx context.Context, msg string, err error, attrs ...slog.Attr) {
+	if store.logger == nil {
+		return
+	}
+	level := store.logOpts.EventLevel
+	if err != nil {
+		level = store.logOpts.ErrorLevel
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	store.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// logQuery logs a finished query.
+func (store *SqlStore) logQuery(ctx context.Context, st *statement, args []interface{}, duration time.Duration, err error) {
+	if store.logger == nil {
+		return
+	}
+
+	level := store.logOpts.QueryLevel
+	if err != nil {
+		level = store.logOpts.ErrorLevel
+	}
+	if !store.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("key", st.key),
+		slog.Duration("duration", duration),
+		slog.Any("args", store.logArgs(args)),
+	}
+	if st.key == "" {
+		attrs = append(attrs, slog.String("query", st.query))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	store.logger.LogAttrs(ctx, level, "godbm: query", attrs...)
+}
+
+// logArgs returns the arguments as they should appear in the log.
+func (store *SqlStore) logArgs(args []interface{}) []interface{} {
+	if store.logOpts.LogArgs {
+		return args
+	}
+	redacted := make([]interface{}, len(args))
+	for i, arg := range args {
+		redacted[i] = fmt.Sprintf("<%T>", arg)
+	}
+	return redacted
+}
@@ -0,0 +1,30 @@
+package godbm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogQuery(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	var buf bytes.Buffer
+	dbm.SetLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})), nil)
+
+	st := &statement{key: "login", query: "select * from users where password = $1"}
+	dbm.run(context.Background(), st, []interface{}{"hunter2"}, func(ctx context.Context) error {
+		return errors.New("boom")
+	})
+
+	out := buf.String()
+	if !strings.Contains(out, "level=ERROR") || !strings.Contains(out, "key=login") {
+		t.Fatalf("expected failed query to be logged as an error, got: %s\n", out)
+	}
+
+	if strings.Contains(out, "hunter2") {
+		t.Fatalf("expected argument values to be left out of the log, got: %s\n", out)
+	}
+}
@@ -0,0 +1,132 @@
+package godbm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// UnknownStoreError holds the name which was attempted in a Manager look up.
+type UnknownStoreError struct {
+	Name string // name of the store
+}
+
+// Returned when the supplied name for looking up a store does not exist.
+func (e *UnknownStoreError) Error() string {
+	return "godbm: error store " + e.Name + " was not found"
+}
+
+// Manager holds several named stores, such as one per postgres cluster, so they can be
+// connected, disconnected and health checked together.
+type Manager struct {
+	mu     sync.RWMutex
+	stores map[string]*SqlStore
+	names  []string // names in the order they were added
+}
+
+// NewManager creates an empty Manager.
+func NewManager() *Manager {
+	return &Manager{stores: make(map[string]*SqlStore)}
+}
+
+// Add registers store under name, returns an error if the name is taken.
+func (m *Manager) Add(name string, store *SqlStore) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.stores[name]; exists {
+		return errors.New("godbm: error store " + name + " already exists")
+	}
+	m.stores[name] = store
+	m.names = append(m.names, name)
+	return nil
+}
+
+// Store returns the store registered under name or an UnknownStoreError.
+func (m *Manager) Store(name string) (*SqlStore, error) {
+	m.mu.RLock()
+	store, ok := m.stores[name]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, &UnknownStoreError{Name: name}
+	}
+	return store, nil
+}
+
+// Names returns the names of the registered stores in the order they were added.
+func (m *Manager) Names() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]string(nil), m.names...)
+}
+
+// ConnectAll connects every store in the order they were added. If one fails the stores
+// already connected are disconnected and the error is returned.
+func (m *Manager) ConnectAll() error {
+	names := m.Names()
+	for i, name := range names {
+		store, _ := m.Store(name)
+		if err := store.Connect(); err != nil {
+			for _, connected := range names[:i] {
+				s, _ := m.Store(connected)
+				s.Disconnect()
+			}
+			return fmt.Errorf("godbm: error connecting store %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// DisconnectAll disconnects every connected store in the reverse order they were added,
+// returning the errors of any which failed.
+func (m *Manager) DisconnectAll() error {
+	names := m.Names()
+	var errs []error
+	for i := len(names) - 1; i >= 0; i-- {
+		store, _ := m.Store(names[i])
+		if !store.Connected {
+			continue
+		}
+		if err := store.Disconnect(); err != nil {
+			errs = append(errs, fmt.Errorf("godbm: error disconnecting store %s: %w", names[i], err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Health pings every store concurrently and returns the error for each by name, nil for
+// healthy stores.
+func (m *Manager) Health(ctx context.Context) map[string]error {
+	names := m.Names()
+	results := make(map[string]error, len(names))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, name := range names {
+		store, _ := m.Store(name)
+		wg.Add(1)
+		go func(name string, store *SqlStore) {
+			defer wg.Done()
+			err := error(&ConnectionError{})
+			if store.Connected {
+				err = store.db.PingContext(ctx)
+			}
+			mu.Lock()
+			results[name] = err
+			mu.Unlock()
+		}(name, store)
+	}
+	wg.Wait()
+	return results
+}
+
+// Healthy returns nil if every store is healthy, otherwise the errors of those which aren't.
+func (m *Manager) Healthy(ctx context.Context) error {
+	health := m.Health(ctx)
+	var errs []error
+	for _, name := range m.Names() {
+		if err := health[name]; err != nil {
+			errs = append(errs, fmt.Errorf("godbm: error store %s is unhealthy: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
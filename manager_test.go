@@ -0,0 +1,44 @@
+package godbm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestManager(t *testing.T) {
+	m := NewManager()
+	users := New(username, password, dbname, host, "disable", "")
+	if err := m.Add("users", users); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add("billing", New(username, password, dbname, host, "disable", "")); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add("users", users); err == nil {
+		t.Fatalf("expected an error adding a duplicate name\n")
+	}
+
+	if s, err := m.Store("users"); err != nil || s != users {
+		t.Fatalf("expected the users store, got %v %v\n", s, err)
+	}
+	var unknown *UnknownStoreError
+	if _, err := m.Store("analytics"); !errors.As(err, &unknown) {
+		t.Fatalf("expected an UnknownStoreError, got %v\n", err)
+	}
+
+	if names := m.Names(); len(names) != 2 || names[0] != "users" || names[1] != "billing" {
+		t.Fatalf("expected names in the order added, got %v\n", names)
+	}
+
+	health := m.Health(context.Background())
+	if len(health) != 2 || health["billing"] == nil {
+		t.Fatalf("expected unconnected stores to be unhealthy, got %v\n", health)
+	}
+	if err := m.Healthy(context.Background()); err == nil {
+		t.Fatalf("expected an error for unhealthy stores\n")
+	}
+	if err := m.DisconnectAll(); err != nil {
+		t.Fatalf("expected unconnected stores to be skipped, got %v\n", err)
+	}
+}
@@ -0,0 +1,125 @@
+package godbm
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// ErrRefreshInProgress is returned by RefreshMaterializedView when another caller, in this or
+// any other process, is already refreshing the view.
+var ErrRefreshInProgress = errors.New("godbm: error materialized view refresh already in progress")
+
+// scheduledRefresh is a materialized view refreshed every interval, see ScheduleRefresh.
+type scheduledRefresh struct {
+	name         string
+	concurrently bool
+	interval     time.Duration
+}
+
+// RefreshMaterializedView refreshes the materialized view name. The refresh runs in a
+// transaction holding an advisory lock on the view's name, so refreshes from several processes
+// never pile up: if the lock is held ErrRefreshInProgress is returned without waiting. With
+// concurrently the view stays readable during the refresh, which requires a unique index on it.
+// The time of each successful refresh is recorded, see LastRefreshed.
+func (store *SqlStore) RefreshMaterializedView(ctx context.Context, name string, concurrently bool) error {
+	template := "refresh materialized view %I"
+	if concurrently {
+		template = "refresh materialized view concurrently %I"
+	}
+	query, err := Format(template, name)
+	if err != nil {
+		return err
+	}
+
+	err = store.WithTransaction(ctx, func(ctx context.Context, tx *Tx) error {
+		locked, err := store.tryXactLock(ctx, "godbm.matview", name)
+		if err != nil {
+			return err
+		}
+		if !locked {
+			return ErrRefreshInProgress
+		}
+		_, err = store.ExecContext(ctx, query)
+		return err
+	})
+	if err == nil {
+		store.refreshed.Store(name, time.Now())
+	}
+	return err
+}
+
+// tryXactLock takes the advisory lock for name within namespace until the transaction carried by
+// ctx ends, reporting false without waiting if another session holds it.
+func (store *SqlStore) tryXactLock(ctx context.Context, namespace, name string) (locked bool, err error) {
+	rows, err := store.QueryContext(ctx, "select pg_try_advisory_xact_lock(hashtext($1), hashtext($2))", namespace, name)
+	if err != nil {
+		return false, err
+	}
+	if rows.Next() {
+		err = rows.Scan(&locked)
+	}
+	if cerr := rows.Close(); err == nil {
+		err = cerr
+	}
+	return locked, err
+}
+
+// LastRefreshed returns when the materialized view name was last refreshed successfully by
+// RefreshMaterializedView on this store, or false if it hasn't been.
+func (store *SqlStore) LastRefreshed(name string) (time.Time, bool) {
+	t, ok := store.refreshed.Load(name)
+	if !ok {
+		return time.Time{}, false
+	}
+	return t.(time.Time), true
+}
+
+// ScheduleRefresh refreshes the materialized view name every interval while the store is
+// connected. Refreshes skipped because another process is refreshing the view are ignored,
+// other errors are logged. Must be called before Connect.
+func (store *SqlStore) ScheduleRefresh(name string, concurrently bool, interval time.Duration) {
+	store.refreshes = append(store.refreshes, scheduledRefresh{name: name, concurrently: concurrently, interval: interval})
+}
+
+// startRefreshes starts refreshing the scheduled materialized views.
+func (store *SqlStore) startRefreshes() {
+	if len(store.refreshes) == 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	store.stopRefreshes = cancel
+	for _, r := range store.refreshes {
+		if r.interval > 0 {
+			go store.refreshEvery(ctx, r)
+		}
+	}
+}
+
+// refreshEvery refreshes r's view every interval until ctx is done.
+func (store *SqlStore) refreshEvery(ctx context.Context, r scheduledRefresh) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			start := time.Now()
+			err := store.RefreshMaterializedView(ctx, r.name, r.concurrently)
+			if errors.Is(err, ErrRefreshInProgress) || ctx.Err() != nil {
+				continue
+			}
+			store.logEvent(ctx, "godbm: refresh materialized view", err, slog.String("view", r.name), slog.Duration("duration", time.Since(start)))
+		}
+	}
+}
+
+// endRefreshes stops refreshing the scheduled materialized views.
+func (store *SqlStore) endRefreshes() {
+	if store.stopRefreshes != nil {
+		store.stopRefreshes()
+		store.stopRefreshes = nil
+	}
+}
@@ -0,0 +1,23 @@
+package godbm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleRefresh(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.ScheduleRefresh("totals", true, time.Minute)
+	if len(dbm.refreshes) != 1 || dbm.refreshes[0].name != "totals" || !dbm.refreshes[0].concurrently {
+		t.Fatalf("unexpected scheduled refreshes: %v\n", dbm.refreshes)
+	}
+
+	dbm.startRefreshes()
+	if dbm.stopRefreshes == nil {
+		t.Fatalf("expected the scheduled refreshes to start\n")
+	}
+	dbm.endRefreshes()
+	if dbm.stopRefreshes != nil {
+		t.Fatalf("expected the scheduled refreshes to stop\n")
+	}
+}
@@ -0,0 +1,134 @@
+// Package metrics exposes godbm statement and pool metrics as a prometheus.Collector.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/wirepair/godbm"
+	"time"
+)
+
+// adhocKey is the key label used for ad-hoc Exec and Query calls.
+const adhocKey = "adhoc"
+
+// Collector records per statement key counters and latencies through a godbm.Hook and reports
+// them along with the connection pool stats of the store.
+type Collector struct {
+	store   *godbm.SqlStore
+	queries *prometheus.CounterVec   // queries by key
+	errors  *prometheus.CounterVec   // errors by key and class
+	latency *prometheus.HistogramVec // latency by key
+
+	open         *prometheus.Desc
+	idle         *prometheus.Desc
+	inUse        *prometheus.Desc
+	waitCount    *prometheus.Desc
+	waitDuration *prometheus.Desc
+	purged       *prometheus.Desc
+}
+
+// New creates a Collector for the store and registers it as a hook. The collector still needs
+// to be registered with a prometheus.Registerer.
+func New(store *godbm.SqlStore, namespace string) *Collector {
+	c := &Collector{
+		store: store,
+		queries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "godbm",
+			Name:      "queries_total",
+			Help:      "Number of queries executed by statement key.",
+		}, []string{"key"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "godbm",
+			Name:      "errors_total",
+			Help:      "Number of failed queries by statement key and error class.",
+		}, []string{"key", "class"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "godbm",
+			Name:      "query_duration_seconds",
+			Help:      "Query latency by statement key.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"key"}),
+		open:         poolDesc(namespace, "open_connections", "Number of established connections, both in use and idle."),
+		idle:         poolDesc(namespace, "idle_connections", "Number of idle connections."),
+		inUse:        poolDesc(namespace, "in_use_connections", "Number of connections currently in use."),
+		waitCount:    poolDesc(namespace, "wait_count_total", "Total number of connections waited for."),
+		waitDuration: poolDesc(namespace, "wait_duration_seconds_total", "Total time spent waiting for a connection."),
+		purged: prometheus.NewDesc(prometheus.BuildFQName(namespace, "godbm", "retention_purged_rows_total"),
+			"Number of rows deleted by retention policies.", nil, nil),
+	}
+	store.Use(c)
+	return c
+}
+
+func poolDesc(namespace, name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "godbm_pool", name), help, nil, nil)
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.queries.Describe(ch)
+	c.errors.Describe(ch)
+	c.latency.Describe(ch)
+	ch <- c.open
+	ch <- c.idle
+	ch <- c.inUse
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.purged
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.queries.Collect(ch)
+	c.errors.Collect(ch)
+	c.latency.Collect(ch)
+	ch <- prometheus.MustNewConstMetric(c.purged, prometheus.CounterValue, float64(c.store.Stats().Purged))
+
+	if db := c.store.Db(); db != nil {
+		stats := db.Stats()
+		ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, float64(stats.OpenConnections))
+		ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.Idle))
+		ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(stats.InUse))
+		ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(stats.WaitCount))
+		ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+	}
+}
+
+// BeforeQuery implements godbm.Hook.
+func (c *Collector) BeforeQuery(ctx context.Context, key, query string, args []interface{}) context.Context {
+	return ctx
+}
+
+// AfterQuery implements godbm.Hook.
+func (c *Collector) AfterQuery(ctx context.Context, key string, duration time.Duration, err error) {
+	if key == "" {
+		key = adhocKey
+	}
+	c.queries.WithLabelValues(key).Inc()
+	c.latency.WithLabelValues(key).Observe(duration.Seconds())
+	if err != nil {
+		c.errors.WithLabelValues(key, ErrorClass(err)).Inc()
+	}
+}
+
+// ErrorClass buckets an error into a low cardinality class suitable for a metric label. Postgres
+// errors are reported by their SQLSTATE class, e.g. "sqlstate_23" for integrity violations.
+func ErrorClass(err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, godbm.ErrCircuitOpen):
+		return "circuit_open"
+	case len(godbm.SQLState(err)) == 5:
+		return "sqlstate_" + godbm.SQLState(err)[:2]
+	case godbm.IsConnectionError(err):
+		return "connection"
+	}
+	return "other"
+}
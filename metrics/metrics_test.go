@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"github.com/lib/pq"
+	"github.com/wirepair/godbm"
+	"testing"
+)
+
+func TestErrorClass(t *testing.T) {
+	cases := map[error]string{
+		context.DeadlineExceeded:        "timeout",
+		godbm.ErrCircuitOpen:            "circuit_open",
+		driver.ErrBadConn:               "connection",
+		&pq.Error{Code: "23505"}:        "sqlstate_23",
+		errors.New("something strange"): "other",
+	}
+
+	for err, expected := range cases {
+		if class := ErrorClass(err); class != expected {
+			t.Fatalf("expected %v to be classed as %s, got %s\n", err, expected, class)
+		}
+	}
+}
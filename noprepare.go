@@ -0,0 +1,61 @@
+package godbm
+
+import (
+	"context"
+	"database/sql"
+)
+
+// SetNoPrepare enables a mode for running behind transaction pooling proxies such as pgbouncer,
+// which break named server side prepared statements. PrepareAdd only records the sql and every
+// call sends it along with the arguments in a single round trip. With lib/pq this sets
+// binary_parameters=yes, with pgx use default_query_exec_mode=simple_protocol in the options.
+// Must be called before Connect.
+func (store *SqlStore) SetNoPrepare(enabled bool) {
+	store.noPrepare = enabled
+}
+
+// runner executes a statement, either prepared or not.
+type runner interface {
+	QueryContext(ctx context.Context, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, args ...interface{}) (sql.Result, error)
+}
+
+// conn is implemented by *sql.DB, *sql.Tx and *sql.Conn.
+type conn interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// unprepared runs a query directly on a connection without preparing it first.
+type unprepared struct {
+	conn  conn
+	query string
+}
+
+func (u unprepared) QueryContext(ctx context.Context, args ...interface{}) (*sql.Rows, error) {
+	return u.conn.QueryContext(ctx, u.query, args...)
+}
+
+func (u unprepared) ExecContext(ctx context.Context, args ...interface{}) (sql.Result, error) {
+	return u.conn.ExecContext(ctx, u.query, args...)
+}
+
+// runnerOn returns a runner for st on c, which is either a pool or a transaction.
+func (store *SqlStore) runnerOn(ctx context.Context, c conn, st *statement) runner {
+	if st.stmt == nil {
+		return unprepared{conn: c, query: store.comment(ctx, store.labeled(st.key, st.query))}
+	}
+	if txn, ok := c.(*sql.Tx); ok {
+		return txn.StmtContext(ctx, st.stmt)
+	}
+	return st.stmt
+}
+
+// prepare prepares query for the statement key, which is empty for ad-hoc queries, unless the
+// store is in no prepare mode, in which case it returns nil.
+func (store *SqlStore) prepare(key, query string) (*sql.Stmt, error) {
+	if store.noPrepare {
+		return nil, nil
+	}
+	return store.prepareStatement(store.labeled(key, query))
+}
@@ -0,0 +1,20 @@
+package godbm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNoPrepareDSN(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.SetNoPrepare(true)
+	if dsn := dbm.dsn(); !strings.Contains(dsn, "binary_parameters=yes") {
+		t.Fatalf("expected binary_parameters in dsn for lib/pq, got: %s\n", dsn)
+	}
+
+	st := &statement{query: "select 1"}
+	if _, ok := dbm.runnerOn(context.Background(), nil, st).(unprepared); !ok {
+		t.Fatalf("expected statements without a prepared statement to run unprepared\n")
+	}
+}
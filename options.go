@@ -0,0 +1,37 @@
+package godbm
+
+import "time"
+
+// stmtOptions holds the per statement settings supplied to PrepareAddWithOptions.
+type stmtOptions struct {
+	idempotent  bool           // statement is safe to retry on transient errors.
+	timeout     time.Duration  // default deadline applied to each call, zero for none.
+	pagination  *Pagination    // how Paginate pages the statement.
+	sensitive   map[int]bool   // parameters whose arguments are redacted, see Sensitive.
+	encrypted   map[int]string // parameters whose arguments are encrypted, by column, see Encrypted.
+	cacheTTL    time.Duration  // how long results are cached, zero to not cache, see Cached.
+	coalesced   bool           // concurrent identical calls share a query, see Coalesced.
+	concurrency semaphore      // limits calls running at once, see MaxConcurrent.
+	rate        *tokenBucket   // limits the rate of calls, see RateLimit.
+	poolClass   string         // the pool class calls run in, see PoolClass.
+}
+
+// StmtOption configures a statement registered with PrepareAddWithOptions.
+type StmtOption func(*stmtOptions)
+
+// Idempotent marks the statement as safe to execute more than once, allowing the store's
+// RetryPolicy to retry it on transient errors.
+func Idempotent() StmtOption {
+	return func(o *stmtOptions) {
+		o.idempotent = true
+	}
+}
+
+// WithTimeout sets a default timeout for every call of the statement, enforced with a context
+// deadline. A shorter deadline on the caller's context still takes precedence. For queries the
+// deadline also covers reading the returned rows.
+func WithTimeout(timeout time.Duration) StmtOption {
+	return func(o *stmtOptions) {
+		o.timeout = timeout
+	}
+}
@@ -0,0 +1,226 @@
+package godbm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"strconv"
+)
+
+// ErrInvalidCursor is returned by Paginate when the cursor can not be decoded.
+var ErrInvalidCursor = errors.New("godbm: error invalid pagination cursor")
+
+// Pagination describes how a statement registered with the Paginated option is paged.
+type Pagination struct {
+	KeyColumn  string // unique, sortable column used for keyset pagination. Empty for offset pagination.
+	Descending bool   // page through KeyColumn in descending order.
+}
+
+// Paginated registers how the statement is paged by Paginate. Keyset pagination is preferred
+// as it stays fast deep into a result set. For offset pagination the statement should have its
+// own ORDER BY for pages to be stable.
+func Paginated(p Pagination) StmtOption {
+	return func(o *stmtOptions) {
+		o.pagination = &p
+	}
+}
+
+// PageRequest asks for a page of results.
+type PageRequest struct {
+	Limit  int    // maximum number of rows in the page.
+	Cursor string // cursor from the previous Page, empty for the first page.
+}
+
+// Page holds a page of results.
+type Page struct {
+	Rows       []map[string]interface{} // the rows, as returned by QueryPreparedMaps.
+	NextCursor string                   // cursor for the next page, empty if this is the last one.
+}
+
+// pageCursor is the decoded form of a cursor.
+type pageCursor struct {
+	Key    interface{} `json:"k,omitempty"`
+	Offset int64       `json:"o,omitempty"`
+}
+
+// pageStatements holds the statements derived from a paginated statement.
+type pageStatements struct {
+	first *statement // keyset pagination without a cursor, or offset pagination.
+	next  *statement // keyset pagination after a cursor.
+}
+
+func (p *pageStatements) close() {
+	p.first.close()
+	if p.next != nil {
+		p.next.close()
+	}
+}
+
+// Paginate executes the statement registered under key, which should not have its own LIMIT,
+// and returns a page of at most req.Limit rows along with the cursor for the next page. The
+// args are passed to the statement on every page. Statements without the Paginated option use
+// offset pagination.
+func (store *SqlStore) Paginate(ctx context.Context, key string, req PageRequest, args ...interface{}) (*Page, error) {
+	if !store.Connected {
+		return nil, &ConnectionError{}
+	}
+	if req.Limit <= 0 {
+		return nil, errors.New("godbm: error page limit must be positive")
+	}
+
+	st, err := store.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := st.checkParams(args); err != nil {
+		return nil, err
+	}
+
+	pages, err := store.pageStatements(st)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor pageCursor
+	if req.Cursor != "" {
+		if cursor, err = decodeCursor(req.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	// fetch an extra row to find out if there is another page.
+	paged := pages.first
+	data := append(args[:len(args):len(args)], req.Limit+1)
+	keyset := st.opts.pagination != nil && st.opts.pagination.KeyColumn != ""
+	switch {
+	case keyset && req.Cursor != "":
+		paged = pages.next
+		data = append(args[:len(args):len(args)], cursor.Key, req.Limit+1)
+	case !keyset:
+		data = append(data, cursor.Offset)
+	}
+
+	rows, err := store.queryStatement(ctx, paged, data...)
+	if err != nil {
+		return nil, err
+	}
+	results, err := store.collectMaps(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page{Rows: results}
+	if len(results) > req.Limit {
+		page.Rows = results[:req.Limit]
+		next := pageCursor{Offset: cursor.Offset + int64(req.Limit)}
+		if keyset {
+			col := st.opts.pagination.KeyColumn
+			if next.Key = page.Rows[req.Limit-1][col]; next.Key == nil {
+				return nil, fmt.Errorf("godbm: error pagination key column %q of %s is missing from its results or null", col, key)
+			}
+		}
+		if page.NextCursor, err = encodeCursor(next); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// pageStatements returns the statements derived from st for pagination, preparing them on
+// first use.
+func (store *SqlStore) pageStatements(st *statement) (*pageStatements, error) {
+	if st.in != nil {
+		return nil, ErrNeedsInList
+	}
+	store.RLock()
+	pages := st.pages
+	store.RUnlock()
+	if pages != nil {
+		return pages, nil
+	}
+
+	// prepared outside the lock so a slow prepare doesn't hold up every other call.
+	pages, err := store.preparePages(st)
+	if err != nil {
+		return nil, err
+	}
+
+	store.Lock()
+	defer store.Unlock()
+	if st.pages != nil {
+		pages.close()
+		return st.pages, nil
+	}
+	st.pages = pages
+	return pages, nil
+}
+
+// preparePages prepares the statements derived from st for pagination.
+func (store *SqlStore) preparePages(st *statement) (*pageStatements, error) {
+	n := st.params
+	param := func(i int) string { return "$" + strconv.Itoa(n+i) }
+
+	p := st.opts.pagination
+	if p == nil || p.KeyColumn == "" {
+		query := st.query + " LIMIT " + param(1) + " OFFSET " + param(2)
+		first, err := store.derive(st, query, n+2)
+		if err != nil {
+			return nil, err
+		}
+		return &pageStatements{first: first}, nil
+	}
+
+	col, order, cmp := pq.QuoteIdentifier(p.KeyColumn), "ASC", ">"
+	if p.Descending {
+		order, cmp = "DESC", "<"
+	}
+	base := "SELECT * FROM (" + st.query + ") AS godbm_page"
+
+	first, err := store.derive(st, base+" ORDER BY "+col+" "+order+" LIMIT "+param(1), n+1)
+	if err != nil {
+		return nil, err
+	}
+	next, err := store.derive(st, base+" WHERE "+col+" "+cmp+" "+param(1)+" ORDER BY "+col+" "+order+" LIMIT "+param(2), n+2)
+	if err != nil {
+		first.close()
+		return nil, err
+	}
+	return &pageStatements{first: first, next: next}, nil
+}
+
+// derive prepares a statement derived from st which shares its key and options.
+func (store *SqlStore) derive(st *statement, query string, params int) (*statement, error) {
+	stmt, err := store.prepare(st.key, query)
+	if err != nil {
+		return nil, err
+	}
+	return &statement{key: st.key, stmt: stmt, query: query, opts: st.opts, params: params}, nil
+}
+
+func encodeCursor(c pageCursor) (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeCursor(s string) (c pageCursor, err error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	if n, ok := c.Key.(json.Number); ok {
+		c.Key = string(n)
+	}
+	return c, nil
+}
@@ -0,0 +1,22 @@
+package godbm
+
+import "testing"
+
+func TestCursor(t *testing.T) {
+	encoded, err := encodeCursor(pageCursor{Key: int64(9007199254740993)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cursor, err := decodeCursor(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor.Key != "9007199254740993" {
+		t.Fatalf("expected large integer keys to survive the round trip, got %v\n", cursor.Key)
+	}
+
+	if _, err := decodeCursor("not a cursor"); err != ErrInvalidCursor {
+		t.Fatalf("expected ErrInvalidCursor, got %v\n", err)
+	}
+}
@@ -0,0 +1,119 @@
+package godbm
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ParamCountError is returned when a prepared statement is called with the wrong number of
+// arguments.
+type ParamCountError struct {
+	StmtKey  string // the statement key
+	Expected int    // number of parameters in the statement
+	Got      int    // number of arguments supplied
+}
+
+func (e *ParamCountError) Error() string {
+	return "godbm: error " + e.StmtKey + " expects " + strconv.Itoa(e.Expected) + " arguments but got " + strconv.Itoa(e.Got)
+}
+
+// checkParams returns a ParamCountError if args doesn't match the statement's parameters, or
+// ErrNeedsInList for a PrepareAddIn statement called without its list.
+func (st *statement) checkParams(args []interface{}) error {
+	if st.in != nil {
+		return ErrNeedsInList
+	}
+	if st.params != len(args) {
+		return &ParamCountError{StmtKey: st.key, Expected: st.params, Got: len(args)}
+	}
+	return nil
+}
+
+// countParams returns the highest $N placeholder in query, skipping string literals, quoted
+// identifiers, dollar quoted strings and comments.
+func countParams(query string) int {
+	max := 0
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '\'' || c == '"':
+			i = skipQuoted(query, i, c)
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(query)
+			}
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			i = skipBlockComment(query, i)
+		case c == '$':
+			j := i + 1
+			for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			if j > i+1 {
+				if n, err := strconv.Atoi(query[i+1 : j]); err == nil && n > max {
+					max = n
+				}
+				i = j - 1
+				continue
+			}
+			if tag, ok := dollarTag(query, i); ok {
+				if end := strings.Index(query[i+len(tag):], tag); end >= 0 {
+					i += len(tag) + end + len(tag) - 1
+				} else {
+					i = len(query)
+				}
+			}
+		}
+	}
+	return max
+}
+
+// skipQuoted returns the index of the quote closing the string starting at i. Doubled quotes
+// are treated as escapes.
+func skipQuoted(query string, i int, quote byte) int {
+	for j := i + 1; j < len(query); j++ {
+		if query[j] == quote {
+			if j+1 < len(query) && query[j+1] == quote {
+				j++
+				continue
+			}
+			return j
+		}
+	}
+	return len(query)
+}
+
+// skipBlockComment returns the index of the last character of the, possibly nested, comment
+// starting at i.
+func skipBlockComment(query string, i int) int {
+	depth := 0
+	for j := i; j < len(query)-1; j++ {
+		switch {
+		case query[j] == '/' && query[j+1] == '*':
+			depth++
+			j++
+		case query[j] == '*' && query[j+1] == '/':
+			depth--
+			j++
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return len(query)
+}
+
+// dollarTag returns the dollar quote tag, such as $$ or $body$, starting at i.
+func dollarTag(query string, i int) (string, bool) {
+	for j := i + 1; j < len(query); j++ {
+		c := query[j]
+		if c == '$' {
+			return query[i : j+1], true
+		}
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || j > i+1 && c >= '0' && c <= '9') {
+			return "", false
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,30 @@
+package godbm
+
+import "testing"
+
+func TestCountParams(t *testing.T) {
+	cases := map[string]int{
+		"select * from user": 0,
+		"insert into test (val1, val2, val3) values ($1, $2, $3)":     3,
+		"select * from test where val3 = $2 or val3 = $1":             2,
+		"select '$4', \"$5\" from test where val1 = $1":               1,
+		"select $1 -- and $2\n":                                       1,
+		"select /* $3 /* nested $4 */ */ $1":                          1,
+		"select $body$ where x = $9 $body$, $$ $8 $$ from t where $2": 2,
+		"select 'it''s $3' where a = $1":                              1,
+	}
+
+	for query, expected := range cases {
+		if got := countParams(query); got != expected {
+			t.Fatalf("expected %d params for %q, got %d\n", expected, query, got)
+		}
+	}
+}
+
+func TestCheckParams(t *testing.T) {
+	st := &statement{key: "insert", params: 3}
+	err := st.checkParams([]interface{}{"boop", "zoop"})
+	if perr, ok := err.(*ParamCountError); !ok || perr.Expected != 3 || perr.Got != 2 {
+		t.Fatalf("expected ParamCountError, got: %v\n", err)
+	}
+}
@@ -0,0 +1,220 @@
+package godbm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// PartitionPeriod is the span of time covered by each partition of a range partitioned table.
+type PartitionPeriod int
+
+const (
+	PartitionDaily   PartitionPeriod = iota + 1 // one partition per day, named table_pYYYYMMDD.
+	PartitionWeekly                             // one partition per week starting on monday, named table_pYYYYMMDD.
+	PartitionMonthly                            // one partition per month, named table_pYYYYMM.
+	PartitionYearly                             // one partition per year, named table_pYYYY.
+)
+
+// boundLayout is the format of partition bounds, with an offset so timestamptz bounds don't
+// depend on the session time zone.
+const boundLayout = "2006-01-02 15:04:05-07:00"
+
+// start returns the start of the period containing t.
+func (p PartitionPeriod) start(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	switch p {
+	case PartitionWeekly:
+		day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case PartitionMonthly:
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	case PartitionYearly:
+		return time.Date(y, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// next returns the start of the period after the one starting at start.
+func (p PartitionPeriod) next(start time.Time) time.Time {
+	switch p {
+	case PartitionWeekly:
+		return start.AddDate(0, 0, 7)
+	case PartitionMonthly:
+		return start.AddDate(0, 1, 0)
+	case PartitionYearly:
+		return start.AddDate(1, 0, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// suffix returns the partition name suffix for the period starting at start.
+func (p PartitionPeriod) suffix(start time.Time) string {
+	switch p {
+	case PartitionMonthly:
+		return start.Format("_p200601")
+	case PartitionYearly:
+		return start.Format("_p2006")
+	}
+	return start.Format("_p20060102")
+}
+
+// valid reports whether p is one of the defined periods.
+func (p PartitionPeriod) valid() bool {
+	return p >= PartitionDaily && p <= PartitionYearly
+}
+
+// Partition is a partition of a partitioned table.
+type Partition struct {
+	Name    string    // as postgres prints it, quoted and schema qualified where needed.
+	From    time.Time // inclusive lower bound, zero for MINVALUE or non range partitions.
+	To      time.Time // exclusive upper bound, zero for MAXVALUE or non range partitions.
+	Default bool      // whether this is the default partition.
+}
+
+// Partitions returns the partitions of table with the bounds of those range partitioned on a
+// single date, timestamp or timestamptz column.
+func (store *SqlStore) Partitions(ctx context.Context, table string) ([]Partition, error) {
+	rows, err := store.QueryContext(ctx, "select c.oid::regclass::text, pg_get_expr(c.relpartbound, c.oid) "+
+		"from pg_inherits i join pg_class c on c.oid = i.inhrelid where i.inhparent = $1::regclass order by 1", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var partitions []Partition
+	for rows.Next() {
+		var p Partition
+		var bound string
+		if err := rows.Scan(&p.Name, &bound); err != nil {
+			return nil, err
+		}
+		p.Default = bound == "DEFAULT"
+		p.From, p.To = parseBounds(bound)
+		partitions = append(partitions, p)
+	}
+	return partitions, rows.Err()
+}
+
+// parseBounds returns the times of a "FOR VALUES FROM (...) TO (...)" partition bound, leaving
+// either zero if it isn't a single time.
+func parseBounds(bound string) (from, to time.Time) {
+	rest, ok := strings.CutPrefix(bound, "FOR VALUES FROM (")
+	if !ok {
+		return from, to
+	}
+	lower, upper, ok := strings.Cut(rest, ") TO (")
+	if !ok {
+		return from, to
+	}
+	return parseBound(lower), parseBound(strings.TrimSuffix(upper, ")"))
+}
+
+// parseBound parses a single quoted time bound.
+func parseBound(s string) time.Time {
+	if len(s) < 2 || s[0] != '\'' || s[len(s)-1] != '\'' {
+		return time.Time{}
+	}
+	t, err := parseTime(s[1 : len(s)-1])
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// CreatePartitions creates the partitions of the range partitioned table for the current
+// period and the ahead periods after it, skipping any which already exist, and returns the
+// names of those created. Partitions are named after the table with a suffix for the period
+// they start, see PartitionPeriod, and created in the table's schema. Periods are computed in
+// UTC. Run it regularly, for example from a daily job, so inserts never run out of partitions.
+func (store *SqlStore) CreatePartitions(ctx context.Context, table string, period PartitionPeriod, ahead int) ([]string, error) {
+	if !period.valid() {
+		return nil, errors.New("godbm: error invalid partition period")
+	}
+	existing, err := store.Partitions(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	exists := make(map[string]bool, len(existing))
+	for _, p := range existing {
+		exists[baseName(p.Name)] = true
+	}
+
+	var created []string
+	start := period.start(time.Now())
+	for i := 0; i <= ahead; i++ {
+		end := period.next(start)
+		name := table + period.suffix(start)
+		if !exists[baseName(name)] {
+			query, err := Format("create table %I partition of %I for values from (%L) to (%L)",
+				name, table, start.Format(boundLayout), end.Format(boundLayout))
+			if err != nil {
+				return created, err
+			}
+			if _, err := store.ExecContext(ctx, query); err != nil {
+				return created, err
+			}
+			created = append(created, name)
+		}
+		start = end
+	}
+	return created, nil
+}
+
+// baseName returns name without its schema or quotes.
+func baseName(name string) string {
+	return strings.Trim(name[strings.LastIndex(name, ".")+1:], `"`)
+}
+
+// AttachPartition attaches an existing table to the range partitioned table as the partition
+// for [from, to). Postgres scans the partition to validate its rows unless it has a matching
+// check constraint.
+func (store *SqlStore) AttachPartition(ctx context.Context, table, partition string, from, to time.Time) error {
+	query, err := Format("alter table %I attach partition %I for values from (%L) to (%L)",
+		table, partition, from.UTC().Format(boundLayout), to.UTC().Format(boundLayout))
+	if err != nil {
+		return err
+	}
+	_, err = store.ExecContext(ctx, query)
+	return err
+}
+
+// DetachPartition detaches partition from table, leaving it as a standalone table. With
+// concurrently, only a SHARE UPDATE EXCLUSIVE lock is taken on table so reads and writes
+// continue, this can't be done inside a transaction.
+func (store *SqlStore) DetachPartition(ctx context.Context, table, partition string, concurrently bool) error {
+	template := "alter table %I detach partition %I"
+	if concurrently {
+		template += " concurrently"
+	}
+	query, err := Format(template, table, partition)
+	if err != nil {
+		return err
+	}
+	_, err = store.ExecContext(ctx, query)
+	return err
+}
+
+// DropPartitionsOlderThan drops the partitions of table whose upper bound is at or before
+// cutoff, so every row they hold is older than it, and returns their names. The default
+// partition and partitions without a time bound are never dropped.
+func (store *SqlStore) DropPartitionsOlderThan(ctx context.Context, table string, cutoff time.Time) ([]string, error) {
+	partitions, err := store.Partitions(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+
+	var dropped []string
+	for _, p := range partitions {
+		if p.Default || p.To.IsZero() || p.To.After(cutoff) {
+			continue
+		}
+		// the name comes from regclass so is already quoted where needed.
+		if _, err := store.ExecContext(ctx, "drop table "+p.Name); err != nil {
+			return dropped, err
+		}
+		dropped = append(dropped, p.Name)
+	}
+	return dropped, nil
+}
@@ -0,0 +1,43 @@
+package godbm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPartitionPeriod(t *testing.T) {
+	now := time.Date(2026, 10, 16, 13, 30, 0, 0, time.UTC) // a friday
+	cases := []struct {
+		period PartitionPeriod
+		start  string
+		next   string
+		suffix string
+	}{
+		{PartitionDaily, "2026-10-16", "2026-10-17", "_p20261016"},
+		{PartitionWeekly, "2026-10-12", "2026-10-19", "_p20261012"},
+		{PartitionMonthly, "2026-10-01", "2026-11-01", "_p202610"},
+		{PartitionYearly, "2026-01-01", "2027-01-01", "_p2026"},
+	}
+	for _, c := range cases {
+		start := c.period.start(now)
+		if start.Format("2006-01-02") != c.start || c.period.next(start).Format("2006-01-02") != c.next || c.period.suffix(start) != c.suffix {
+			t.Fatalf("unexpected period %d: %v %v %v\n", c.period, start, c.period.next(start), c.period.suffix(start))
+		}
+	}
+	if PartitionPeriod(0).valid() {
+		t.Fatalf("expected the zero period to be invalid\n")
+	}
+}
+
+func TestParseBounds(t *testing.T) {
+	from, to := parseBounds("FOR VALUES FROM ('2026-10-01 00:00:00+00') TO ('2026-11-01 00:00:00+00')")
+	if !from.Equal(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)) || !to.Equal(time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected bounds: %v %v\n", from, to)
+	}
+	if from, to := parseBounds("FOR VALUES FROM (MINVALUE) TO ('2026-11-01')"); !from.IsZero() || to.IsZero() {
+		t.Fatalf("unexpected unbounded bounds: %v %v\n", from, to)
+	}
+	if from, to := parseBounds("FOR VALUES IN ('eu')"); !from.IsZero() || !to.IsZero() {
+		t.Fatalf("expected list bounds to be ignored: %v %v\n", from, to)
+	}
+}
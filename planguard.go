@@ -0,0 +1,138 @@
+package godbm
+
+import (
+	"context"
+	"hash/fnv"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PlanChange describes a statement which started running with a plan not seen before.
+type PlanChange struct {
+	Key      string // statement key.
+	Query    string // the sql text.
+	Hash     string // hash of the new plan.
+	Plan     string // the new plan without costs or estimates, as in golden files.
+	Previous string // the plan last seen before it.
+}
+
+// PlanGuardOptions configures detecting plan changes, see SetPlanGuard.
+type PlanGuardOptions struct {
+	Keys     []string                // statements watched, every registered statement if empty.
+	Interval time.Duration           // least time between captures of a statement's plan, defaults to a minute.
+	Callback func(change PlanChange) // called from a background goroutine for every new plan.
+}
+
+// planState holds the plans seen for a statement.
+type planState struct {
+	mu   sync.Mutex
+	next time.Time       // when the plan may next be captured
+	seen map[string]bool // hashes of every plan seen
+	last string          // the plan last captured
+}
+
+// SetPlanGuard enables detecting plan flips in production, such as a statement falling back to
+// a sequential scan after ANALYZE, passing nil disables it. At most once per Interval a call of
+// each watched statement is sampled and its plan captured in the background with the call's
+// arguments. The first plan is the baseline, after which Callback is called whenever a plan
+// appears which hasn't been seen for the statement before. Plans which legitimately vary with
+// their arguments are only reported the first time each is seen. Costs and estimates are left
+// out, so only a change of shape counts. This should be called before the store is in use.
+func (store *SqlStore) SetPlanGuard(opts *PlanGuardOptions) {
+	store.planGuard = opts
+}
+
+// samplePlan captures the plan of a successful call in the background if the statement is
+// watched and its interval has passed.
+func (store *SqlStore) samplePlan(st *statement, args []interface{}, err error) {
+	opts := store.planGuard
+	if opts == nil || opts.Callback == nil || err != nil || st.key == "" || !opts.watches(st.key) {
+		return
+	}
+	if ps, due := store.planDue(st.key, opts.Interval); due {
+		go store.capturePlan(st, args, ps, opts.Callback)
+	}
+}
+
+// planDue returns the plans seen for key and whether interval has passed since its plan was
+// last captured, in which case the next capture is scheduled.
+func (store *SqlStore) planDue(key string, interval time.Duration) (*planState, bool) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	v, _ := store.plans.LoadOrStore(key, &planState{})
+	ps := v.(*planState)
+	now := time.Now()
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if now.Before(ps.next) {
+		return ps, false
+	}
+	ps.next = now.Add(interval)
+	return ps, true
+}
+
+// watches reports whether key is one of the watched statements.
+func (opts *PlanGuardOptions) watches(key string) bool {
+	if len(opts.Keys) == 0 {
+		return true
+	}
+	for _, k := range opts.Keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// capturePlan explains st with args and reports the plan if it is new.
+func (store *SqlStore) capturePlan(st *statement, args []interface{}, ps *planState, callback func(PlanChange)) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	raw, err := store.explainJSON(ctx, st.query, args...)
+	if err != nil {
+		store.logEvent(ctx, "godbm: plan guard", err, slog.String("key", st.key))
+		return
+	}
+	plan, err := parsePlan(raw)
+	if err != nil {
+		store.logEvent(ctx, "godbm: plan guard", err, slog.String("key", st.key))
+		return
+	}
+
+	var b strings.Builder
+	writePlan(&b, &plan.Plan, 0)
+	text := b.String()
+	hash := planHash(text)
+	if previous, changed := ps.observe(hash, text); changed {
+		callback(PlanChange{Key: st.key, Query: st.query, Hash: hash, Plan: text, Previous: previous})
+	}
+}
+
+// observe records a captured plan, returning the previous plan and whether this one is new. The
+// first plan is the baseline and isn't a change.
+func (ps *planState) observe(hash, text string) (previous string, changed bool) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	previous = ps.last
+	ps.last = text
+	if ps.seen == nil {
+		ps.seen = map[string]bool{hash: true}
+		return previous, false
+	}
+	if ps.seen[hash] {
+		return previous, false
+	}
+	ps.seen[hash] = true
+	return previous, true
+}
+
+// planHash returns a short hash identifying a normalized plan.
+func planHash(plan string) string {
+	h := fnv.New64a()
+	h.Write([]byte(plan))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
@@ -0,0 +1,38 @@
+package godbm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlanStateObserve(t *testing.T) {
+	ps := &planState{}
+	if _, changed := ps.observe("a", "Seq Scan on test\n"); changed {
+		t.Fatalf("expected the first plan to be the baseline\n")
+	}
+	previous, changed := ps.observe("b", "Index Scan using test_val3 on test\n")
+	if !changed || previous != "Seq Scan on test\n" {
+		t.Fatalf("expected a change from the seq scan got %v %q\n", changed, previous)
+	}
+	if _, changed := ps.observe("a", "Seq Scan on test\n"); changed {
+		t.Fatalf("expected a plan seen before not to be a change\n")
+	}
+}
+
+func TestPlanDue(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	if _, due := dbm.planDue("get", time.Hour); !due {
+		t.Fatalf("expected the first call to be sampled\n")
+	}
+	if _, due := dbm.planDue("get", time.Hour); due {
+		t.Fatalf("expected no sample within the interval\n")
+	}
+	if _, due := dbm.planDue("other", time.Hour); !due {
+		t.Fatalf("expected each key to have its own interval\n")
+	}
+
+	opts := &PlanGuardOptions{Keys: []string{"get"}}
+	if !opts.watches("get") || opts.watches("other") {
+		t.Fatalf("expected only get to be watched\n")
+	}
+}
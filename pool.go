@@ -0,0 +1,28 @@
+package godbm
+
+import (
+	"database/sql"
+	"time"
+)
+
+// PoolOptions sizes the connection pool, zero values leave the database/sql defaults in place.
+type PoolOptions struct {
+	MaxOpenConns    int           // maximum open connections, zero for unlimited
+	MaxIdleConns    int           // maximum idle connections, zero for the default of 2
+	ConnMaxLifetime time.Duration // maximum time a connection may be reused, zero for forever
+	ConnMaxIdleTime time.Duration // maximum time a connection may sit idle, zero for forever
+}
+
+// SetPoolOptions sizes the connection pools the store opens, including those of replicas.
+// Must be called before Connect.
+func (store *SqlStore) SetPoolOptions(p PoolOptions) {
+	store.pool = p
+}
+
+// applyPool applies the pool options to db.
+func (store *SqlStore) applyPool(db *sql.DB) {
+	db.SetMaxOpenConns(store.pool.MaxOpenConns)
+	db.SetMaxIdleConns(store.idleConns())
+	db.SetConnMaxLifetime(store.pool.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(store.pool.ConnMaxIdleTime)
+}
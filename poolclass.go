@@ -0,0 +1,115 @@
+package godbm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SetPoolClasses splits the pool into named classes, such as "interactive" and "batch", each
+// allowed at most the given number of calls running at once, so one class can't take every
+// connection from the others. A call's class comes from WithPoolClass, or else the statement's
+// PoolClass option, calls without one are only subject to SetConcurrencyLimit. A budget of zero
+// leaves its class unlimited. A query holds its slot until its rows are closed and a
+// transaction holds one from BeginTx until it is committed or rolled back, its statements run in
+// the transaction's class. The budgets should add up to no more than the pool's MaxOpenConns.
+// Must be called before the store is in use.
+func (store *SqlStore) SetPoolClasses(budgets map[string]int) {
+	store.poolClasses = make(map[string]semaphore, len(budgets))
+	for class, n := range budgets {
+		if n <= 0 {
+			store.poolClasses[class] = nil
+			continue
+		}
+		store.poolClasses[class] = make(semaphore, n)
+	}
+}
+
+// PoolClass sets the pool class calls of the statement run in, unless the context sets one
+// with WithPoolClass. See SetPoolClasses.
+func PoolClass(class string) StmtOption {
+	return func(o *stmtOptions) {
+		o.poolClass = class
+	}
+}
+
+type poolClassKey struct{}
+
+// WithPoolClass returns a context whose calls run in the given pool class, taking precedence
+// over the statement's PoolClass option. See SetPoolClasses.
+func WithPoolClass(ctx context.Context, class string) context.Context {
+	return context.WithValue(ctx, poolClassKey{}, class)
+}
+
+// poolClass returns the semaphore of the class the call runs in, nil if it has none.
+func (store *SqlStore) poolClass(ctx context.Context, st *statement) (semaphore, error) {
+	if store.poolClasses == nil {
+		return nil, nil
+	}
+	class, ok := ctx.Value(poolClassKey{}).(string)
+	if !ok {
+		class = st.opts.poolClass
+	}
+	if class == "" {
+		return nil, nil
+	}
+	sem, ok := store.poolClasses[class]
+	if !ok {
+		return nil, fmt.Errorf("godbm: error unknown pool class %q", class)
+	}
+	return sem, nil
+}
+
+type classHeldKey struct{}
+
+// classHeld reports whether the call runs in a query or transaction already holding a slot in
+// its pool class, whose calls don't take another.
+func classHeld(ctx context.Context) bool {
+	_, inTx := TxFromContext(ctx)
+	return inTx || ctx.Value(classHeldKey{}) != nil
+}
+
+// holdClass takes a slot in the call's pool class for the caller to hold beyond the call, until
+// its rows are closed or its transaction ends. It returns a context whose calls don't take
+// another slot and a function releasing it, which is nil if no slot was taken.
+func (store *SqlStore) holdClass(ctx context.Context, st *statement) (context.Context, func(), error) {
+	if classHeld(ctx) {
+		return ctx, nil, nil
+	}
+	class, err := store.poolClass(ctx, st)
+	if err != nil || class == nil {
+		return ctx, nil, err
+	}
+
+	waitCtx, cancel := waitContext(ctx)
+	defer cancel()
+	if err := class.acquire(waitCtx); err != nil {
+		return ctx, nil, poolWaitErr(ctx, waitCtx, err)
+	}
+	var once sync.Once
+	return context.WithValue(ctx, classHeldKey{}, true), func() { once.Do(class.release) }, nil
+}
+
+// rowsPollInterval is how often releaseOnClose checks whether rows are closed.
+const rowsPollInterval = 10 * time.Millisecond
+
+// releaseOnClose calls release once rows are closed, which *sql.Rows has no hook for so it is
+// checked every rowsPollInterval. A nil release does nothing.
+func releaseOnClose(rows *sql.Rows, release func()) {
+	if release == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(rowsPollInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			// Columns only fails once the rows are closed.
+			if _, err := rows.Columns(); err != nil {
+				release()
+				return
+			}
+		}
+	}()
+}
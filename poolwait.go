@@ -0,0 +1,89 @@
+package godbm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrPoolTimeout is returned when a call made with WithPoolWait waits longer than allowed for a
+// connection, or for a slot under a concurrency limit or pool class.
+var ErrPoolTimeout = errors.New("godbm: error timed out waiting for a connection")
+
+type poolWaitKey struct{}
+
+// WithPoolWait returns a context whose calls wait at most d for a free connection from the
+// pool, and for a slot under SetConcurrencyLimit, SetPoolClasses or MaxConcurrent, before
+// failing with ErrPoolTimeout. Under saturation this sheds load quickly instead of queueing,
+// separately from how long the query itself may run, which the context's own deadline still
+// bounds.
+//
+// Calls outside a transaction take a connection before running, so their sql is sent
+// unprepared on it, see SetNoPrepare. Calls in a transaction already hold their connection.
+func WithPoolWait(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, poolWaitKey{}, d)
+}
+
+// poolWait returns the wait set with WithPoolWait, if any.
+func poolWait(ctx context.Context) (time.Duration, bool) {
+	d, ok := ctx.Value(poolWaitKey{}).(time.Duration)
+	return d, ok
+}
+
+// waitContext returns a context for waiting on the pool, bounded by the call's pool wait if it
+// has one.
+func waitContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d, ok := poolWait(ctx); ok {
+		return context.WithTimeout(ctx, d)
+	}
+	return ctx, func() {}
+}
+
+// poolWaitErr returns ErrPoolTimeout if err is from the pool wait expiring rather than the
+// call's own context.
+func poolWaitErr(ctx, waitCtx context.Context, err error) error {
+	if err != nil && ctx.Err() == nil && errors.Is(waitCtx.Err(), context.DeadlineExceeded) {
+		return ErrPoolTimeout
+	}
+	return err
+}
+
+// waitingRunner runs a statement on a connection taken from the pool within the call's pool
+// wait.
+type waitingRunner struct {
+	db    *sql.DB
+	query string
+}
+
+// conn takes a connection from the pool.
+func (w waitingRunner) conn(ctx context.Context) (*sql.Conn, error) {
+	waitCtx, cancel := waitContext(ctx)
+	defer cancel()
+	conn, err := w.db.Conn(waitCtx)
+	return conn, poolWaitErr(ctx, waitCtx, err)
+}
+
+func (w waitingRunner) QueryContext(ctx context.Context, args ...interface{}) (*sql.Rows, error) {
+	conn, err := w.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := conn.QueryContext(ctx, w.query, args...)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// Close blocks until the rows are closed, then returns the connection to the pool.
+	go conn.Close()
+	return rows, nil
+}
+
+func (w waitingRunner) ExecContext(ctx context.Context, args ...interface{}) (sql.Result, error) {
+	conn, err := w.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.ExecContext(ctx, w.query, args...)
+}
@@ -0,0 +1,32 @@
+package godbm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPoolWaitLimit(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.SetConcurrencyLimit(1)
+	st := &statement{key: "get"}
+	release, err := dbm.limit(context.Background(), st)
+	if err != nil {
+		t.Fatalf("error taking the only slot: %v\n", err)
+	}
+	defer release()
+
+	start := time.Now()
+	if _, err := dbm.limit(WithPoolWait(context.Background(), 20*time.Millisecond), st); err != ErrPoolTimeout {
+		t.Fatalf("expected ErrPoolTimeout got %v\n", err)
+	}
+	if waited := time.Since(start); waited > time.Second {
+		t.Fatalf("expected to give up quickly, waited %v\n", waited)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := dbm.limit(WithPoolWait(ctx, time.Minute), st); err != context.DeadlineExceeded {
+		t.Fatalf("expected the call's own deadline got %v\n", err)
+	}
+}
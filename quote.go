@@ -0,0 +1,74 @@
+package godbm
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"strings"
+)
+
+// QuoteIdentifier quotes name for use as an identifier, such as a table or column name, in
+// sql. Dots are quoted as part of the name, use QuoteQualified for schema qualified names.
+func QuoteIdentifier(name string) string {
+	return pq.QuoteIdentifier(name)
+}
+
+// QuoteQualified quotes each part of a dot separated name such as schema.table.
+func QuoteQualified(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = pq.QuoteIdentifier(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// QuoteLiteral quotes value for use as a string literal in sql, for the few places such as
+// DDL which can't take parameters. Prefer parameters everywhere else.
+func QuoteLiteral(value string) string {
+	return pq.QuoteLiteral(value)
+}
+
+// Format builds sql with dynamic identifiers and literals, like postgres' format function.
+// %I is replaced with the next argument quoted with QuoteQualified, %L with the next argument
+// quoted with QuoteLiteral and %% with a percent sign. Arguments are formatted with fmt's %v
+// first, and using any other verb, or the wrong number of arguments, is an error, so values
+// can't reach the sql unquoted:
+//
+//	query, err := godbm.Format("create table %I partition of %I for values in (%L)", partition, "events", region)
+func Format(template string, args ...interface{}) (string, error) {
+	var b strings.Builder
+	next := 0
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		if c != '%' {
+			b.WriteByte(c)
+			continue
+		}
+		if i+1 >= len(template) {
+			return "", errors.New("godbm: error format ends with %")
+		}
+		i++
+		verb := template[i]
+		if verb == '%' {
+			b.WriteByte('%')
+			continue
+		}
+		if next >= len(args) {
+			return "", fmt.Errorf("godbm: error format has more verbs than the %d arguments", len(args))
+		}
+		value := fmt.Sprint(args[next])
+		next++
+		switch verb {
+		case 'I':
+			b.WriteString(QuoteQualified(value))
+		case 'L':
+			b.WriteString(QuoteLiteral(value))
+		default:
+			return "", fmt.Errorf("godbm: error unknown format verb %%%c", verb)
+		}
+	}
+	if next != len(args) {
+		return "", fmt.Errorf("godbm: error format used %d of the %d arguments", next, len(args))
+	}
+	return b.String(), nil
+}
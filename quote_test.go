@@ -0,0 +1,36 @@
+package godbm
+
+import "testing"
+
+func TestQuote(t *testing.T) {
+	if q := QuoteQualified(`audit.log"s`); q != `"audit"."log""s"` {
+		t.Fatalf("unexpected qualified identifier: %s\n", q)
+	}
+	if q := QuoteLiteral(`it's`); q != `'it''s'` {
+		t.Fatalf("unexpected literal: %s\n", q)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	query, err := Format("create table %I partition of %I for values in (%L) -- 100%%", "events_eu; drop table users", "public.events", "eu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `create table "events_eu; drop table users" partition of "public"."events" for values in ('eu') -- 100%`; query != want {
+		t.Fatalf("unexpected sql:\n%s\n%s\n", query, want)
+	}
+
+	for _, bad := range []struct {
+		template string
+		args     []interface{}
+	}{
+		{"select %s", []interface{}{"x"}},
+		{"select %I", nil},
+		{"select 1", []interface{}{"x"}},
+		{"select 100%", nil},
+	} {
+		if _, err := Format(bad.template, bad.args...); err == nil {
+			t.Fatalf("expected an error for %q\n", bad.template)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package godbm
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Range is a postgres range such as int4range, numrange, daterange or tstzrange. Lower and
+// Upper are only meaningful when the bound is not infinite and the range is not empty.
+//
+//	var booked godbm.Range[time.Time]
+//	err := row.Scan(&booked)
+type Range[T any] struct {
+	Lower, Upper       T
+	LowerInc, UpperInc bool // the bound is inclusive, [ or ], rather than exclusive.
+	LowerInf, UpperInf bool // the bound is unbounded, -infinity or infinity.
+	Empty              bool // the range contains no values.
+	Valid              bool // false when the column is NULL.
+}
+
+// Scan implements sql.Scanner.
+func (r *Range[T]) Scan(src interface{}) error {
+	text, ok := asText(src)
+	if !ok {
+		return fmt.Errorf("godbm: error cannot scan %T into a Range", src)
+	}
+	*r = Range[T]{}
+	if src == nil {
+		return nil
+	}
+	r.Valid = true
+	if text == "empty" {
+		r.Empty = true
+		return nil
+	}
+
+	bounds, err := parseElements(text, 0, 0)
+	if err != nil {
+		return err
+	}
+	if len(bounds) != 2 || !strings.ContainsRune("[(", rune(text[0])) || !strings.ContainsRune("])", rune(text[len(text)-1])) {
+		return fmt.Errorf("godbm: error invalid range %q", text)
+	}
+	r.LowerInc, r.UpperInc = text[0] == '[', text[len(text)-1] == ']'
+	r.LowerInf, r.UpperInf = bounds[0] == nil, bounds[1] == nil
+	if !r.LowerInf {
+		if err := assignText(reflect.ValueOf(&r.Lower).Elem(), bounds[0]); err != nil {
+			return err
+		}
+	}
+	if !r.UpperInf {
+		if err := assignText(reflect.ValueOf(&r.Upper).Elem(), bounds[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (r Range[T]) Value() (driver.Value, error) {
+	if !r.Valid {
+		return nil, nil
+	}
+	if r.Empty {
+		return "empty", nil
+	}
+
+	var b strings.Builder
+	if r.LowerInc && !r.LowerInf {
+		b.WriteByte('[')
+	} else {
+		b.WriteByte('(')
+	}
+	if !r.LowerInf {
+		if err := writeBound(&b, reflect.ValueOf(r.Lower)); err != nil {
+			return nil, err
+		}
+	}
+	b.WriteByte(',')
+	if !r.UpperInf {
+		if err := writeBound(&b, reflect.ValueOf(r.Upper)); err != nil {
+			return nil, err
+		}
+	}
+	if r.UpperInc && !r.UpperInf {
+		b.WriteByte(']')
+	} else {
+		b.WriteByte(')')
+	}
+	return b.String(), nil
+}
+
+// writeBound writes the text of a range bound.
+func writeBound(b *strings.Builder, v reflect.Value) error {
+	text, _, err := formatText(v)
+	if err != nil {
+		return err
+	}
+	b.WriteString(quoteElement(text))
+	return nil
+}
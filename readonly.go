@@ -0,0 +1,66 @@
+package godbm
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrReadOnly is returned when a read only store is asked to write.
+var ErrReadOnly = errors.New("godbm: error store is read only")
+
+// readKeywords are the statements a read only store accepts.
+var readKeywords = map[string]bool{"select": true, "with": true, "values": true, "table": true, "show": true, "explain": true}
+
+// SetReadOnly makes the store refuse to write. Exec, ExecPrepared and CopyStart return
+// ErrReadOnly, as do PrepareAdd and Query with anything but a query, and every connection is
+// opened with default_transaction_read_only so the server rejects writes the checks can't see,
+// such as data modifying WITH queries or writes through a Tx. Must be called before Connect.
+func (store *SqlStore) SetReadOnly(readOnly bool) {
+	store.readOnly = readOnly
+	value := ""
+	if readOnly {
+		value = "on"
+	}
+	store.SetSessionParams(map[string]string{"default_transaction_read_only": value})
+}
+
+// checkWrite returns ErrReadOnly if the store is read only.
+func (store *SqlStore) checkWrite() error {
+	if store.readOnly {
+		return ErrReadOnly
+	}
+	return nil
+}
+
+// checkRead returns ErrReadOnly if the store is read only and query is not a query.
+func (store *SqlStore) checkRead(query string) error {
+	if store.readOnly && !readKeywords[firstKeyword(query)] {
+		return ErrReadOnly
+	}
+	return nil
+}
+
+// firstKeyword returns the first word of query in lower case, skipping leading whitespace,
+// comments and parentheses.
+func firstKeyword(query string) string {
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '(':
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				return ""
+			}
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			i = skipBlockComment(query, i)
+		default:
+			j := i
+			for j < len(query) && (query[j] >= 'a' && query[j] <= 'z' || query[j] >= 'A' && query[j] <= 'Z') {
+				j++
+			}
+			return strings.ToLower(query[i:j])
+		}
+	}
+	return ""
+}
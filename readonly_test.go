@@ -0,0 +1,48 @@
+package godbm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestFirstKeyword(t *testing.T) {
+	for query, want := range map[string]string{
+		"select 1": "select",
+		"  -- lookup\n/* a /* nested */ */ (SELECT 1)": "select",
+		"WITH x AS (select 1) select * from x":         "with",
+		"delete from users":                            "delete",
+		"-- only a comment":                            "",
+	} {
+		if got := firstKeyword(query); got != want {
+			t.Fatalf("expected %q for %q, got %q\n", want, query, got)
+		}
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.SetReadOnly(true)
+	dbm.Connected = true
+
+	if !strings.Contains(dbm.dsn(), "default_transaction_read_only=on") {
+		t.Fatalf("expected sessions to default to read only, got: %s\n", dbm.dsn())
+	}
+	if _, err := dbm.Exec("select 1"); err != ErrReadOnly {
+		t.Fatalf("expected Exec to be refused, got %v\n", err)
+	}
+	if _, err := dbm.Query("update users set admin = true"); err != ErrReadOnly {
+		t.Fatalf("expected a write through Query to be refused, got %v\n", err)
+	}
+	if err := dbm.PrepareAdd("promote", "update users set admin = true"); err != ErrReadOnly {
+		t.Fatalf("expected registering a write to be refused, got %v\n", err)
+	}
+	if _, err := dbm.execStatement(context.Background(), &statement{query: "select 1"}); err != ErrReadOnly {
+		t.Fatalf("expected ExecPrepared to be refused, got %v\n", err)
+	}
+
+	dbm.SetReadOnly(false)
+	if strings.Contains(dbm.dsn(), "default_transaction_read_only") {
+		t.Fatalf("expected the session param to be removed\n")
+	}
+}
@@ -0,0 +1,44 @@
+package godbm
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// WaitUntilReady connects if needed and pings the database until it accepts connections,
+// retrying connection errors, such as the server refusing connections or still starting up,
+// with the delays given by backoff until ctx is done. backoff may be nil for
+// ExponentialBackoff(100ms, 5s). Other errors, such as bad credentials, are returned at once.
+// Use it in place of Connect where the application may start before postgres.
+func (store *SqlStore) WaitUntilReady(ctx context.Context, backoff func(attempt int) time.Duration) error {
+	if backoff == nil {
+		backoff = ExponentialBackoff(100*time.Millisecond, 5*time.Second)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := store.ready(ctx)
+		if err == nil || !IsConnectionError(err) {
+			return err
+		}
+		store.logEvent(ctx, "godbm: waiting for database", err, slog.String("host", store.host), slog.Int("attempt", attempt))
+
+		timer := time.NewTimer(backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// ready connects if not connected and pings the database.
+func (store *SqlStore) ready(ctx context.Context) error {
+	if !store.Connected {
+		if err := store.Connect(); err != nil {
+			return err
+		}
+	}
+	return store.db.PingContext(ctx)
+}
@@ -0,0 +1,24 @@
+package godbm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWaitUntilReadyTimeout(t *testing.T) {
+	// nothing listens on port 1, so every attempt is refused.
+	dbm := New(username, password, dbname, "127.0.0.1:1", "disable", "")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	attempts := 0
+	err := dbm.WaitUntilReady(ctx, func(attempt int) time.Duration {
+		attempts = attempt
+		return 10 * time.Millisecond
+	})
+	if err != context.DeadlineExceeded || attempts < 2 {
+		t.Fatalf("expected retries until the deadline: %v %d\n", err, attempts)
+	}
+	dbm.Disconnect()
+}
@@ -0,0 +1,62 @@
+package godbm
+
+// RedactedValue replaces the arguments of sensitive parameters in hooks, logs and slow query
+// reports.
+const RedactedValue = "<redacted>"
+
+// Redactor decides how arguments appear to hooks, logs and slow query reports. param is the 1
+// based position of the argument, key is empty for ad-hoc queries. Return value unchanged to
+// leave it visible.
+type Redactor interface {
+	Redact(key string, param int, value interface{}) interface{}
+}
+
+// RedactorFunc adapts a function to a Redactor.
+type RedactorFunc func(key string, param int, value interface{}) interface{}
+
+// Redact implements Redactor.
+func (f RedactorFunc) Redact(key string, param int, value interface{}) interface{} {
+	return f(key, param, value)
+}
+
+// SetRedactor sets a Redactor applied to the arguments of every call before they are passed
+// to hooks, the logger or the slow query callback. Arguments of parameters marked with
+// Sensitive are always replaced with RedactedValue. Should be called before the store is in
+// use.
+func (store *SqlStore) SetRedactor(r Redactor) {
+	store.redactor = r
+}
+
+// Sensitive marks the statement's parameters at the given 1 based positions as sensitive, such
+// as passwords or personal data, so their arguments are replaced with RedactedValue wherever
+// they would be seen outside of the database.
+func Sensitive(params ...int) StmtOption {
+	return func(o *stmtOptions) {
+		if o.sensitive == nil {
+			o.sensitive = make(map[int]bool, len(params))
+		}
+		for _, p := range params {
+			o.sensitive[p] = true
+		}
+	}
+}
+
+// redact returns the arguments of a call to st as hooks and logs should see them.
+func (store *SqlStore) redact(st *statement, args []interface{}) []interface{} {
+	if store.redactor == nil && len(st.opts.sensitive) == 0 {
+		return args
+	}
+
+	redacted := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch {
+		case st.opts.sensitive[i+1]:
+			redacted[i] = RedactedValue
+		case store.redactor != nil:
+			redacted[i] = store.redactor.Redact(st.key, i+1, arg)
+		default:
+			redacted[i] = arg
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,49 @@
+package godbm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRedact(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	st := &statement{key: "login", query: "select id from users where email = $1 and password = crypt($2, password)"}
+	Sensitive(2)(&st.opts)
+
+	var seen []interface{}
+	dbm.Use(HookFuncs{
+		Before: func(ctx context.Context, key, query string, args []interface{}) context.Context {
+			seen = args
+			return ctx
+		},
+	})
+
+	args := []interface{}{"user@example.com", "hunter2"}
+	var ran []interface{}
+	dbm.run(context.Background(), st, args, func(ctx context.Context) error {
+		ran = args
+		return nil
+	})
+	if seen[0] != "user@example.com" || seen[1] != RedactedValue || ran[1] != "hunter2" {
+		t.Fatalf("expected only hooks to see the redacted password, got %v %v\n", seen, ran)
+	}
+
+	dbm.SetRedactor(RedactorFunc(func(key string, param int, value interface{}) interface{} {
+		if _, ok := value.(string); ok && param == 1 {
+			return "<email>"
+		}
+		return value
+	}))
+	if redacted := dbm.redact(st, args); redacted[0] != "<email>" || redacted[1] != RedactedValue {
+		t.Fatalf("expected the redactor and sensitive params to apply, got %v\n", redacted)
+	}
+
+	var slow SlowQuery
+	dbm.SetLogger(nil, &LogOptions{LogArgs: true})
+	dbm.SetSlowQueryOptions(&SlowQueryOptions{Callback: func(s SlowQuery) { slow = s }})
+	dbm.checkSlow(st, args, time.Second)
+	if slow.Args[1] != RedactedValue {
+		t.Fatalf("expected slow queries to be redacted, got %v\n", slow.Args)
+	}
+}
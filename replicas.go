@@ -0,0 +1,289 @@
+package godbm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// replica is a read only standby which queries may be routed to.
+type replica struct {
+	host    string      // the replica host
+	db      *sql.DB     // the replica connection pool
+	healthy atomic.Bool // false while the replica fails health checks
+	lagging atomic.Bool // true while the replica is further behind than the max acceptable lag
+}
+
+// ReplicaLag is how far a replica is behind the primary.
+type ReplicaLag struct {
+	Host        string        // the replica host
+	Bytes       int64         // WAL bytes the replica has yet to replay
+	ReplayDelay time.Duration // time since the last replayed transaction was committed on the primary
+}
+
+// NewWithReplicas creates a new *SqlStore which sends writes and transactions to the primary
+// host and spreads QueryPrepared calls across the healthy replicas, falling back to the primary
+// when none are available. Use ForcePrimary for reads which must see the latest writes.
+func NewWithReplicas(username, password, dbname, primary string, replicas []string, sslmode, opts string) *SqlStore {
+	s := New(username, password, dbname, primary, sslmode, opts)
+	for _, host := range replicas {
+		s.replicas = append(s.replicas, &replica{host: host})
+	}
+	return s
+}
+
+// SetReplicaHealthCheck sets how often replicas are pinged to update their health, defaults
+// to every 5 seconds. Must be called before Connect.
+func (store *SqlStore) SetReplicaHealthCheck(interval time.Duration) {
+	store.replicaCheck = interval
+}
+
+// SetMaxAcceptableLag sets how many bytes of WAL a replica may be behind the primary before
+// reads fall back to other replicas or the primary. Lag is measured with each health check, zero
+// disables the check. Must be called before Connect.
+func (store *SqlStore) SetMaxAcceptableLag(bytes int64) {
+	store.maxLag = bytes
+}
+
+type forcePrimaryKey struct{}
+
+// ForcePrimary returns a context which routes queries made with it to the primary.
+func ForcePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forcePrimaryKey{}, true)
+}
+
+// connectReplicas opens the replica pools and starts health checking them.
+func (store *SqlStore) connectReplicas() error {
+	if len(store.replicas) == 0 {
+		return nil
+	}
+
+	for _, r := range store.replicas {
+		r.db = sql.OpenDB(store.newConnector(r.host))
+		store.applyPool(r.db)
+		store.logEvent(context.Background(), "godbm: connect replica", nil, slog.String("host", r.host), slog.String("dbname", store.dbname))
+		r.healthy.Store(true)
+	}
+
+	interval := store.replicaCheck
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	store.stopReplicaCheck = make(chan struct{})
+	store.replicaCheckDone = make(chan struct{})
+	go store.checkReplicas(interval, store.stopReplicaCheck, store.replicaCheckDone)
+	return nil
+}
+
+// disconnectReplicas stops health checking and closes the replica pools once a check in
+// progress has finished.
+func (store *SqlStore) disconnectReplicas() {
+	if store.stopReplicaCheck != nil {
+		close(store.stopReplicaCheck)
+		<-store.replicaCheckDone
+		store.stopReplicaCheck, store.replicaCheckDone = nil, nil
+	}
+	for _, r := range store.replicas {
+		if r.db != nil {
+			r.db.Close()
+			r.db = nil
+		}
+		r.healthy.Store(false)
+	}
+}
+
+// checkReplicas pings every replica at the interval until stop is closed, then closes done.
+func (store *SqlStore) checkReplicas(interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		for _, r := range store.replicas {
+			store.setReplicaHealth(r, r.db.PingContext(ctx))
+		}
+		if store.maxLag > 0 {
+			store.checkLag(ctx)
+		}
+		cancel()
+	}
+}
+
+// checkLag measures the lag of every healthy replica and flags those beyond the max
+// acceptable lag.
+func (store *SqlStore) checkLag(ctx context.Context) {
+	primary, err := store.primaryLSN(ctx)
+	if err != nil {
+		store.logEvent(ctx, "godbm: measuring primary wal position", err)
+		return
+	}
+
+	for _, r := range store.replicas {
+		if !r.healthy.Load() {
+			continue
+		}
+		lag, err := store.replicaLag(ctx, r, primary)
+		lagging := err != nil || lag.Bytes > store.maxLag
+		if r.lagging.Swap(lagging) != lagging {
+			store.logEvent(ctx, "godbm: replica lag changed", err, slog.String("host", r.host), slog.Int64("bytes", lag.Bytes), slog.Bool("lagging", lagging))
+		}
+	}
+}
+
+// ReplicaLag measures how far behind the primary each replica is.
+func (store *SqlStore) ReplicaLag(ctx context.Context) ([]ReplicaLag, error) {
+	if !store.Connected {
+		return nil, &ConnectionError{}
+	}
+
+	primary, err := store.primaryLSN(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lags := make([]ReplicaLag, 0, len(store.replicas))
+	for _, r := range store.replicas {
+		lag, err := store.replicaLag(ctx, r, primary)
+		if err != nil {
+			return nil, err
+		}
+		lags = append(lags, lag)
+	}
+	return lags, nil
+}
+
+// primaryLSN returns the current WAL position of the primary.
+func (store *SqlStore) primaryLSN(ctx context.Context) (uint64, error) {
+	var lsn string
+	if err := store.db.QueryRowContext(ctx, "select pg_current_wal_lsn()::text").Scan(&lsn); err != nil {
+		return 0, err
+	}
+	return parseLSN(lsn)
+}
+
+// replicaLag measures the lag of r given the primary's WAL position.
+func (store *SqlStore) replicaLag(ctx context.Context, r *replica, primary uint64) (ReplicaLag, error) {
+	lag := ReplicaLag{Host: r.host}
+
+	var lsn sql.NullString
+	var delay float64
+	query := "select pg_last_wal_replay_lsn()::text, coalesce(extract(epoch from now() - pg_last_xact_replay_timestamp()), 0)"
+	if err := r.db.QueryRowContext(ctx, query).Scan(&lsn, &delay); err != nil {
+		return lag, err
+	}
+	if !lsn.Valid {
+		return lag, errors.New("godbm: error " + r.host + " is not a replica")
+	}
+
+	replayed, err := parseLSN(lsn.String)
+	if err != nil {
+		return lag, err
+	}
+	if primary > replayed {
+		lag.Bytes = int64(primary - replayed)
+	}
+	lag.ReplayDelay = time.Duration(delay * float64(time.Second))
+	return lag, nil
+}
+
+// parseLSN parses a postgres log sequence number in its X/Y hex text form.
+func parseLSN(lsn string) (uint64, error) {
+	hi, lo, found := strings.Cut(lsn, "/")
+	if !found {
+		return 0, errors.New("godbm: error invalid lsn " + lsn)
+	}
+	h, err := strconv.ParseUint(hi, 16, 32)
+	if err != nil {
+		return 0, err
+	}
+	l, err := strconv.ParseUint(lo, 16, 32)
+	if err != nil {
+		return 0, err
+	}
+	return h<<32 | l, nil
+}
+
+// setReplicaHealth marks the replica healthy if err is nil, logging changes.
+func (store *SqlStore) setReplicaHealth(r *replica, err error) {
+	healthy := err == nil
+	if r.healthy.Swap(healthy) != healthy {
+		store.logEvent(context.Background(), "godbm: replica health changed", err, slog.String("host", r.host), slog.Bool("healthy", healthy))
+	}
+}
+
+// pickReplica returns the next healthy replica which is not lagging in round robin order, or nil
+// if there are none.
+func (store *SqlStore) pickReplica() *replica {
+	n := len(store.replicas)
+	start := int(store.nextReplica.Add(1))
+	for i := 0; i < n; i++ {
+		r := store.replicas[(start+i)%n]
+		if r.healthy.Load() && !r.lagging.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+// replicaStmt returns st prepared on the replica, preparing it on first use.
+func (store *SqlStore) replicaStmt(ctx context.Context, st *statement, r *replica) (runner, error) {
+	if st.stmt == nil {
+		return unprepared{conn: r.db, query: store.comment(ctx, store.labeled(st.key, st.query))}, nil
+	}
+
+	store.RLock()
+	stmt := st.replicaStmts[r]
+	store.RUnlock()
+	if stmt != nil {
+		return stmt, nil
+	}
+
+	stmt, err := r.db.PrepareContext(ctx, store.commentWith(store.labeled(st.key, st.query), nil))
+	if err != nil {
+		return nil, err
+	}
+
+	store.Lock()
+	defer store.Unlock()
+	if existing := st.replicaStmts[r]; existing != nil {
+		stmt.Close()
+		return existing, nil
+	}
+	if st.replicaStmts == nil {
+		st.replicaStmts = make(map[*replica]*sql.Stmt)
+	}
+	st.replicaStmts[r] = stmt
+	return stmt, nil
+}
+
+// readStmt returns the runner to use for a read of st, on a replica if one is available and the
+// context allows it. The replica is nil if the primary was chosen.
+func (store *SqlStore) readStmt(ctx context.Context, st *statement) (runner, *replica) {
+	if len(store.replicas) == 0 || ctx.Value(forcePrimaryKey{}) != nil {
+		return store.stmtFor(ctx, st), nil
+	}
+	if _, inTx := TxFromContext(ctx); inTx {
+		return store.stmtFor(ctx, st), nil
+	}
+
+	r := store.pickReplica()
+	if r == nil {
+		return store.stmtFor(ctx, st), nil
+	}
+	stmt, err := store.replicaStmt(ctx, st, r)
+	if err != nil {
+		store.setReplicaHealth(r, err)
+		return store.stmtFor(ctx, st), nil
+	}
+	return stmt, r
+}
@@ -0,0 +1,56 @@
+package godbm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPickReplica(t *testing.T) {
+	dbm := NewWithReplicas(username, password, dbname, host, []string{"replica1", "replica2", "replica3"}, "disable", "")
+	dbm.replicas[0].healthy.Store(true)
+	dbm.replicas[2].healthy.Store(true)
+
+	seen := make(map[string]int)
+	for i := 0; i < 10; i++ {
+		seen[dbm.pickReplica().host]++
+	}
+	if seen["replica2"] != 0 || seen["replica1"] == 0 || seen["replica3"] == 0 {
+		t.Fatalf("expected reads spread across healthy replicas only, got: %v\n", seen)
+	}
+
+	dbm.replicas[0].healthy.Store(false)
+	dbm.replicas[2].healthy.Store(false)
+	if r := dbm.pickReplica(); r != nil {
+		t.Fatalf("expected no replica when all are unhealthy, got %s\n", r.host)
+	}
+}
+
+func TestDisconnectReplicasWaitsForCheck(t *testing.T) {
+	dbm := NewWithReplicas(username, password, dbname, host, []string{"replica1"}, "disable", "")
+	dbm.SetDriver(&dsnDriver{})
+	dbm.SetReplicaHealthCheck(time.Millisecond)
+	for i := 0; i < 20; i++ {
+		if err := dbm.connectReplicas(); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(2 * time.Millisecond)
+		dbm.disconnectReplicas()
+	}
+	if dbm.replicas[0].healthy.Load() {
+		t.Fatalf("expected the replica to be unhealthy once disconnected\n")
+	}
+}
+
+func TestParseLSN(t *testing.T) {
+	lsn, err := parseLSN("16/B374D848")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lsn != 0x16B374D848 {
+		t.Fatalf("expected %x got %x\n", uint64(0x16B374D848), lsn)
+	}
+
+	if _, err := parseLSN("garbage"); err == nil {
+		t.Fatalf("expected an error parsing an invalid lsn\n")
+	}
+}
@@ -0,0 +1,258 @@
+package godbm
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+func init() {
+	// column values decoded by the driver which gob needs to know about inside interface{}.
+	gob.Register(time.Time{})
+}
+
+// ResultCache stores encoded query results for statements registered with Cached. Get returns
+// ok false on a miss. Implementations must be safe for concurrent use, see NewMemoryCache for
+// an in process LRU, a Redis or memcached client can be adapted to it.
+type ResultCache interface {
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// SetResultCache sets the cache used for statements registered with the Cached option. Passing
+// nil disables caching. Should be called before the store is in use.
+func (store *SqlStore) SetResultCache(cache ResultCache) {
+	store.resultCache = cache
+}
+
+// Cached makes QueryPreparedMaps return the results of the statement from the store's
+// ResultCache for up to ttl after they were read, keyed by the statement key and arguments.
+// Calls made inside a transaction always read from the database. QueryPrepared isn't cached, as
+// the *sql.Rows it returns are read from the connection. See SetResultCache.
+func Cached(ttl time.Duration) StmtOption {
+	return func(o *stmtOptions) {
+		o.cacheTTL = ttl
+	}
+}
+
+// Invalidate removes the cached results of the statement registered under key for args, so the
+// next call reads from the database.
+func (store *SqlStore) Invalidate(ctx context.Context, key string, args ...interface{}) error {
+	if store.resultCache == nil {
+		return nil
+	}
+	return store.resultCache.Delete(ctx, store.resultKey(key, args))
+}
+
+// InvalidateAll makes every cached result of the statement registered under key stale, for any
+// arguments. Stale entries are left to expire from the cache.
+func (store *SqlStore) InvalidateAll(key string) {
+	store.cacheGeneration(key).Add(1)
+}
+
+// InvalidateOn invalidates every cached result of the statements registered under keys when a
+// notification is sent on channel, for example by a trigger on the tables they read, see
+// Notify. All of the keys are invalidated if the listening connection drops, as notifications
+// may have been missed. The channel is listened to from Connect until Disconnect. Must be
+// called before Connect.
+func (store *SqlStore) InvalidateOn(channel string, keys ...string) {
+	if store.invalidations == nil {
+		store.invalidations = make(map[string][]string)
+	}
+	store.invalidations[channel] = append(store.invalidations[channel], keys...)
+}
+
+// startInvalidation listens to the channels registered with InvalidateOn.
+func (store *SqlStore) startInvalidation() {
+	if len(store.invalidations) == 0 {
+		return
+	}
+	channels := make([]string, 0, len(store.invalidations))
+	for channel := range store.invalidations {
+		channels = append(channels, channel)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	store.stopListening = cancel
+	go func() {
+		if err := store.Listen(ctx, store.invalidate, channels...); err != nil && ctx.Err() == nil {
+			store.logEvent(ctx, "godbm: cache invalidation stopped", err)
+		}
+	}()
+}
+
+// stopInvalidation stops listening for invalidations.
+func (store *SqlStore) stopInvalidation() {
+	if store.stopListening != nil {
+		store.stopListening()
+		store.stopListening = nil
+	}
+}
+
+// invalidate invalidates the keys registered for the notification's channel, or every key if
+// the listener reconnected.
+func (store *SqlStore) invalidate(n Notification) {
+	for channel, keys := range store.invalidations {
+		if !n.Reconnected && channel != n.Channel {
+			continue
+		}
+		for _, key := range keys {
+			store.InvalidateAll(key)
+		}
+	}
+}
+
+// cacheGeneration returns the counter which InvalidateAll increments for key.
+func (store *SqlStore) cacheGeneration(key string) *atomic.Uint64 {
+	gen, _ := store.cacheGens.LoadOrStore(key, new(atomic.Uint64))
+	return gen.(*atomic.Uint64)
+}
+
+// resultKey returns the cache key for the results of key called with args. Arguments are
+// hashed as the values sent to the server, so a pointer hashes the same as the value it points
+// to.
+func (store *SqlStore) resultKey(key string, args []interface{}) string {
+	h := sha256.New()
+	for _, arg := range store.keyArgs(args) {
+		fmt.Fprintf(h, "%T:%#v\x00", arg, arg)
+	}
+	gen := strconv.FormatUint(store.cacheGeneration(key).Load(), 10)
+	return "godbm:" + key + ":" + gen + ":" + hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// keyArgs returns args converted to driver values, leaving any which can't be converted as
+// they are.
+func (store *SqlStore) keyArgs(args []interface{}) []interface{} {
+	converted, err := store.convertArgs(args)
+	if err != nil {
+		converted = args
+	}
+	values := make([]interface{}, len(converted))
+	for i, arg := range converted {
+		values[i] = arg
+		if v, err := driver.DefaultParameterConverter.ConvertValue(arg); err == nil {
+			values[i] = v
+		}
+	}
+	return values
+}
+
+// cachedMaps returns the results of st for args from the cache, or reads them with query and
+// caches them. Cache errors are logged and fall back to the database.
+func (store *SqlStore) cachedMaps(ctx context.Context, st *statement, args []interface{}, query func() ([]map[string]interface{}, error)) ([]map[string]interface{}, error) {
+	if _, inTx := TxFromContext(ctx); inTx || store.resultCache == nil || st.opts.cacheTTL <= 0 {
+		return query()
+	}
+
+	ck := store.resultKey(st.key, args)
+	if b, ok, err := store.resultCache.Get(ctx, ck); err != nil {
+		store.logEvent(ctx, "godbm: result cache get", err, slog.String("key", st.key))
+	} else if ok {
+		var results []map[string]interface{}
+		if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&results); err == nil {
+			return results, nil
+		}
+	}
+
+	results, err := query()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(results); err != nil {
+		store.logEvent(ctx, "godbm: result cache encode", err, slog.String("key", st.key))
+		return results, nil
+	}
+	if err := store.resultCache.Set(ctx, ck, buf.Bytes(), st.opts.cacheTTL); err != nil {
+		store.logEvent(ctx, "godbm: result cache set", err, slog.String("key", st.key))
+	}
+	return results, nil
+}
+
+// NewMemoryCache returns an in process ResultCache holding up to size entries, evicting the
+// least recently used.
+func NewMemoryCache(size int) ResultCache {
+	return &memoryCache{size: size, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// memoryCache is an LRU ResultCache with per entry expiry.
+type memoryCache struct {
+	mu    sync.Mutex
+	size  int                      // maximum number of entries
+	ll    *list.List               // most recently used at the front
+	items map[string]*list.Element // elements by key
+	now   func() time.Time         // current time, replaced in tests
+}
+
+// memoryEntry is an entry in a memoryCache.
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// Get implements ResultCache.
+func (c *memoryCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := e.Value.(*memoryEntry)
+	if !c.timeNow().Before(entry.expires) {
+		c.ll.Remove(e)
+		delete(c.items, key)
+		return nil, false, nil
+	}
+	c.ll.MoveToFront(e)
+	return entry.value, true, nil
+}
+
+// Set implements ResultCache.
+func (c *memoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires := c.timeNow().Add(ttl)
+	if e, ok := c.items[key]; ok {
+		entry := e.Value.(*memoryEntry)
+		entry.value, entry.expires = value, expires
+		c.ll.MoveToFront(e)
+		return nil
+	}
+	c.items[key] = c.ll.PushFront(&memoryEntry{key: key, value: value, expires: expires})
+	for c.ll.Len() > c.size {
+		delete(c.items, c.ll.Remove(c.ll.Back()).(*memoryEntry).key)
+	}
+	return nil
+}
+
+// Delete implements ResultCache.
+func (c *memoryCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		c.ll.Remove(e)
+		delete(c.items, key)
+	}
+	return nil
+}
+
+// timeNow returns the current time.
+func (c *memoryCache) timeNow() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,116 @@
+package godbm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryCache(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := NewMemoryCache(2).(*memoryCache)
+	c.now = func() time.Time { return now }
+
+	c.Set(ctx, "a", []byte("1"), time.Minute)
+	c.Set(ctx, "b", []byte("2"), time.Minute)
+	c.Get(ctx, "a")
+	c.Set(ctx, "c", []byte("3"), time.Minute)
+	if _, ok, _ := c.Get(ctx, "b"); ok {
+		t.Fatalf("expected the least recently used entry to be evicted\n")
+	}
+	if v, ok, _ := c.Get(ctx, "a"); !ok || string(v) != "1" {
+		t.Fatalf("unexpected entry: %s %v\n", v, ok)
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok, _ := c.Get(ctx, "a"); ok {
+		t.Fatalf("expected the entry to expire\n")
+	}
+}
+
+func TestCachedMaps(t *testing.T) {
+	ctx := context.Background()
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.SetResultCache(NewMemoryCache(10))
+	st := &statement{key: "dashboard"}
+	Cached(time.Minute)(&st.opts)
+
+	calls := 0
+	query := func() ([]map[string]interface{}, error) {
+		calls++
+		return []map[string]interface{}{{"n": int64(calls), "at": time.Unix(0, 0).UTC(), "none": nil}}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		results, err := dbm.cachedMaps(ctx, st, []interface{}{1}, query)
+		if err != nil || results[0]["n"] != int64(1) || results[0]["none"] != nil {
+			t.Fatalf("unexpected results: %v %v\n", results, err)
+		}
+		if at, ok := results[0]["at"].(time.Time); !ok || !at.Equal(time.Unix(0, 0)) {
+			t.Fatalf("unexpected time: %v\n", results[0]["at"])
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected a single query got %d\n", calls)
+	}
+
+	if results, _ := dbm.cachedMaps(ctx, st, []interface{}{2}, query); calls != 2 || results[0]["n"] != int64(2) {
+		t.Fatalf("expected different args to miss the cache\n")
+	}
+
+	if err := dbm.Invalidate(ctx, "dashboard", 1); err != nil {
+		t.Fatal(err)
+	}
+	if dbm.cachedMaps(ctx, st, []interface{}{1}, query); calls != 3 {
+		t.Fatalf("expected invalidated results to be read again\n")
+	}
+
+	if dbm.cachedMaps(withTx(ctx, &Tx{}), st, []interface{}{1}, query); calls != 4 {
+		t.Fatalf("expected calls in a transaction to skip the cache\n")
+	}
+}
+
+func TestInvalidateOn(t *testing.T) {
+	ctx := context.Background()
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.SetResultCache(NewMemoryCache(10))
+	dbm.InvalidateOn("orders_changed", "orders", "totals")
+	dbm.InvalidateOn("users_changed", "users")
+
+	st := &statement{key: "orders"}
+	Cached(time.Minute)(&st.opts)
+	calls := 0
+	query := func() ([]map[string]interface{}, error) {
+		calls++
+		return nil, nil
+	}
+
+	dbm.cachedMaps(ctx, st, nil, query)
+	dbm.invalidate(Notification{Channel: "users_changed"})
+	if dbm.cachedMaps(ctx, st, nil, query); calls != 1 {
+		t.Fatalf("expected other channels to leave the cache alone\n")
+	}
+	dbm.invalidate(Notification{Channel: "orders_changed"})
+	if dbm.cachedMaps(ctx, st, nil, query); calls != 2 {
+		t.Fatalf("expected the notification to invalidate the key\n")
+	}
+	dbm.invalidate(Notification{Reconnected: true})
+	if dbm.cachedMaps(ctx, st, nil, query); calls != 3 {
+		t.Fatalf("expected reconnecting to invalidate every key\n")
+	}
+}
+
+func TestResultKeyPointers(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	name, other := "alice", "alice"
+	if dbm.resultKey("get", []interface{}{&name}) != dbm.resultKey("get", []interface{}{&other}) {
+		t.Fatalf("expected pointers to equal values to share a key\n")
+	}
+	if dbm.resultKey("get", []interface{}{&name}) != dbm.resultKey("get", []interface{}{"alice"}) {
+		t.Fatalf("expected a pointer to share the key of its value\n")
+	}
+	if dbm.resultKey("get", []interface{}{"alice"}) == dbm.resultKey("get", []interface{}{"bob"}) {
+		t.Fatalf("expected different values to have different keys\n")
+	}
+}
@@ -0,0 +1,122 @@
+package godbm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// RetentionPolicy removes rows older than MaxAge from a table.
+type RetentionPolicy struct {
+	Table     string        // table to purge, may be schema qualified.
+	Column    string        // timestamp column compared against the cutoff.
+	MaxAge    time.Duration // rows with Column older than this are deleted.
+	BatchSize int           // rows deleted per statement, defaults to 1000.
+}
+
+// RetentionOptions configures the retention policies run by RunRetention.
+type RetentionOptions struct {
+	Policies     []RetentionPolicy
+	Interval     time.Duration // how often the policies run after Connect, zero to only run them with RunRetention.
+	SleepBetween time.Duration // pause between batches so replication and vacuum keep up.
+
+	// Callback is optional and called with the rows purged from each table after its policy runs.
+	Callback func(table string, purged int64, err error)
+}
+
+// defaultRetentionBatch is the batch size used by policies which don't set one.
+const defaultRetentionBatch = 1000
+
+// SetRetention sets the retention policies run by RunRetention and, if opts.Interval is set,
+// every interval while connected. Passing nil removes them. Must be called before Connect.
+func (store *SqlStore) SetRetention(opts *RetentionOptions) error {
+	if opts != nil {
+		for _, p := range opts.Policies {
+			if p.Table == "" || p.Column == "" || p.MaxAge <= 0 {
+				return errors.New("godbm: error retention policy needs a table, column and max age")
+			}
+		}
+	}
+	store.retention = opts
+	return nil
+}
+
+// RunRetention runs every retention policy now, deleting expired rows in batches, and returns
+// the number of rows purged per table. Every policy is run even if an earlier one fails, the
+// errors are joined. Purged rows are counted in Stats.Purged.
+func (store *SqlStore) RunRetention(ctx context.Context) (map[string]int64, error) {
+	opts := store.retention
+	if opts == nil {
+		return nil, nil
+	}
+	if !store.Connected {
+		return nil, &ConnectionError{}
+	}
+
+	purged := make(map[string]int64, len(opts.Policies))
+	var errs []error
+	for _, p := range opts.Policies {
+		n, err := store.purge(ctx, p, opts.SleepBetween)
+		purged[p.Table] += n
+		store.counters.purged.Add(n)
+		store.logEvent(ctx, "godbm: retention", err, slog.String("table", p.Table), slog.Int64("purged", n))
+		if opts.Callback != nil {
+			opts.Callback(p.Table, n, err)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return purged, errors.Join(errs...)
+}
+
+// purge deletes the rows of p's table older than its max age, batch by batch.
+func (store *SqlStore) purge(ctx context.Context, p RetentionPolicy, sleepBetween time.Duration) (int64, error) {
+	batchSize := p.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultRetentionBatch
+	}
+	// a ctid is only unique within a partition, so rows are matched on the partition as well.
+	table := QuoteQualified(p.Table)
+	query := "delete from " + table + " where (tableoid, ctid) in (select tableoid, ctid from " + table + " where " +
+		QuoteIdentifier(p.Column) + " < $1 limit $2)"
+	cutoff := time.Now().Add(-p.MaxAge)
+
+	return store.inBatches(ctx, sleepBetween, nil, func() (sql.Result, error) {
+		return store.ExecContext(ctx, query, cutoff, batchSize)
+	})
+}
+
+// startRetention runs the retention policies every interval if one is set.
+func (store *SqlStore) startRetention() {
+	if store.retention == nil || store.retention.Interval <= 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	store.stopRetention = cancel
+	go func() {
+		ticker := time.NewTicker(store.retention.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				store.RunRetention(ctx)
+			}
+		}
+	}()
+}
+
+// endRetention stops running the retention policies.
+func (store *SqlStore) endRetention() {
+	if store.stopRetention != nil {
+		store.stopRetention()
+		store.stopRetention = nil
+	}
+}
@@ -0,0 +1,24 @@
+package godbm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSetRetention(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	if err := dbm.SetRetention(&RetentionOptions{Policies: []RetentionPolicy{{Table: "events", MaxAge: time.Hour}}}); err == nil {
+		t.Fatalf("expected an error for a policy without a column\n")
+	}
+	if purged, err := dbm.RunRetention(context.Background()); purged != nil || err != nil {
+		t.Fatalf("expected nothing to run without policies: %v %v\n", purged, err)
+	}
+
+	if err := dbm.SetRetention(&RetentionOptions{Policies: []RetentionPolicy{{Table: "events", Column: "created", MaxAge: time.Hour}}}); err != nil {
+		t.Fatalf("error setting retention: %v\n", err)
+	}
+	if _, err := dbm.RunRetention(context.Background()); err == nil {
+		t.Fatalf("expected a connection error, got %v\n", err)
+	}
+}
@@ -0,0 +1,111 @@
+package godbm
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how prepared statements marked Idempotent are retried when they fail
+// with a transient error.
+type RetryPolicy struct {
+	MaxAttempts int                             // total number of attempts, including the first.
+	Backoff     func(attempt int) time.Duration // delay before the given retry attempt (starting at 1), nil for none.
+	Retryable   func(err error) bool            // classifies errors as retryable, defaults to IsTransientError.
+}
+
+// SetRetryPolicy sets the policy used for retrying idempotent prepared statements. Passing nil
+// disables retries. This should be called before the store is in use.
+func (store *SqlStore) SetRetryPolicy(policy *RetryPolicy) {
+	store.retry = policy
+}
+
+// ExponentialBackoff returns a backoff function which doubles the delay for every attempt
+// starting from base and never exceeding max.
+func ExponentialBackoff(base, max time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := base
+		for i := 1; i < attempt; i++ {
+			d *= 2
+			if d >= max {
+				return max
+			}
+		}
+		if d > max {
+			return max
+		}
+		return d
+	}
+}
+
+// IsTransientError reports whether err looks like a temporary failure, such as a dropped
+// connection, a server shutting down during failover or a serialization failure.
+func IsTransientError(err error) bool {
+	if IsConnectionError(err) {
+		return true
+	}
+
+	switch SQLState(err) {
+	case "40001", "40P01": // serialization_failure, deadlock_detected
+		return true
+	}
+	return false
+}
+
+// IsConnectionError reports whether err indicates the database could not be reached or the
+// connection to it was lost.
+func IsConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	if state := SQLState(err); state != "" {
+		switch state {
+		case "57P01", "57P02", "57P03": // admin_shutdown, crash_shutdown, cannot_connect_now
+			return true
+		}
+		return strings.HasPrefix(state, "08") // connection exception
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// do calls fn until it succeeds, returns a non retryable error, the attempts are exhausted or
+// the context is done.
+func (policy *RetryPolicy) do(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = IsTransientError
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && policy.Backoff != nil {
+			timer := time.NewTimer(policy.Backoff(attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+		}
+
+		err = fn(ctx)
+		if err == nil || attempt+1 >= policy.MaxAttempts || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package godbm
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := ExponentialBackoff(10*time.Millisecond, 50*time.Millisecond)
+	expected := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 50 * time.Millisecond, 50 * time.Millisecond}
+	for i, want := range expected {
+		if got := backoff(i + 1); got != want {
+			t.Fatalf("attempt %d: expected %v got %v\n", i+1, want, got)
+		}
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 3}
+
+	calls := 0
+	err := policy.do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return driver.ErrBadConn
+	})
+	if err != driver.ErrBadConn || calls != 3 {
+		t.Fatalf("expected 3 calls ending in ErrBadConn, got %d calls and %v\n", calls, err)
+	}
+
+	calls = 0
+	permanent := errors.New("syntax error")
+	err = policy.do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return permanent
+	})
+	if err != permanent || calls != 1 {
+		t.Fatalf("expected a single call for a permanent error, got %d calls and %v\n", calls, err)
+	}
+}
@@ -0,0 +1,111 @@
+package godbm
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrRowLockNeedsTx is returned when a row lock is requested outside of a transaction, where it
+// would be released as soon as the query finished.
+var ErrRowLockNeedsTx = errors.New("godbm: error row locks require a transaction, use WithTransaction")
+
+// LockStrength is the strength of a row lock.
+type LockStrength int
+
+const (
+	ForUpdate      LockStrength = iota + 1 // FOR UPDATE, blocks all other locks.
+	ForNoKeyUpdate                         // FOR NO KEY UPDATE, allows FOR KEY SHARE so foreign key checks aren't blocked.
+	ForShare                               // FOR SHARE, blocks updates but not other shares.
+	ForKeyShare                            // FOR KEY SHARE, only blocks deletes and key updates.
+)
+
+// LockWait is what a row lock does when rows are already locked.
+type LockWait int
+
+const (
+	Wait       LockWait = iota // wait for the other lock to be released.
+	NoWait                     // NOWAIT, fail with lock_not_available instead of waiting.
+	SkipLocked                 // SKIP LOCKED, leave locked rows out of the result, for queue consumers.
+)
+
+// RowLock is a locking clause added to a select, see WithRowLock.
+type RowLock struct {
+	Strength LockStrength
+	Wait     LockWait
+}
+
+// String returns the locking clause, such as FOR UPDATE SKIP LOCKED.
+func (l RowLock) String() string {
+	var clause string
+	switch l.Strength {
+	case ForUpdate:
+		clause = "FOR UPDATE"
+	case ForNoKeyUpdate:
+		clause = "FOR NO KEY UPDATE"
+	case ForShare:
+		clause = "FOR SHARE"
+	case ForKeyShare:
+		clause = "FOR KEY SHARE"
+	default:
+		return ""
+	}
+	switch l.Wait {
+	case NoWait:
+		clause += " NOWAIT"
+	case SkipLocked:
+		clause += " SKIP LOCKED"
+	}
+	return clause
+}
+
+type rowLockKey struct{}
+
+// WithRowLock returns a context which locks the rows selected by QueryPreparedContext calls
+// made with it, and the helpers built on it such as ForEachRowContext, by running a variant of
+// the registered statement with the locking clause appended. The context must carry a
+// transaction, otherwise ErrRowLockNeedsTx is returned:
+//
+//	store.WithTransaction(ctx, func(ctx context.Context, tx *godbm.Tx) error {
+//		rows, err := store.QueryPreparedContext(godbm.WithRowLock(ctx, godbm.RowLock{Strength: godbm.ForUpdate, Wait: godbm.SkipLocked}), "next_jobs")
+//		...
+//	})
+func WithRowLock(ctx context.Context, lock RowLock) context.Context {
+	return context.WithValue(ctx, rowLockKey{}, lock)
+}
+
+// rowLock returns the lock set with WithRowLock, if any.
+func rowLock(ctx context.Context) (RowLock, bool) {
+	lock, ok := ctx.Value(rowLockKey{}).(RowLock)
+	return lock, ok && lock.String() != ""
+}
+
+// lockedStmt returns the variant of st with lock's clause appended, preparing it on first use.
+func (store *SqlStore) lockedStmt(st *statement, lock RowLock) (*statement, error) {
+	if st.in != nil {
+		return nil, ErrNeedsInList
+	}
+	store.RLock()
+	locked := st.locked[lock]
+	store.RUnlock()
+	if locked != nil {
+		return locked, nil
+	}
+
+	// prepared outside the lock so a slow prepare doesn't hold up every other call.
+	locked, err := store.derive(st, st.query+" "+lock.String(), st.params)
+	if err != nil {
+		return nil, err
+	}
+
+	store.Lock()
+	defer store.Unlock()
+	if existing := st.locked[lock]; existing != nil {
+		locked.close()
+		return existing, nil
+	}
+	if st.locked == nil {
+		st.locked = make(map[RowLock]*statement)
+	}
+	st.locked[lock] = locked
+	return locked, nil
+}
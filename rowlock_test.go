@@ -0,0 +1,30 @@
+package godbm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRowLock(t *testing.T) {
+	for lock, want := range map[RowLock]string{
+		{Strength: ForUpdate}:                        "FOR UPDATE",
+		{Strength: ForShare, Wait: NoWait}:           "FOR SHARE NOWAIT",
+		{Strength: ForNoKeyUpdate, Wait: SkipLocked}: "FOR NO KEY UPDATE SKIP LOCKED",
+		{Wait: NoWait}:                               "",
+	} {
+		if got := lock.String(); got != want {
+			t.Fatalf("expected %q, got %q\n", want, got)
+		}
+	}
+
+	dbm := New(username, password, dbname, host, "disable", "")
+	ctx := WithRowLock(context.Background(), RowLock{Strength: ForUpdate})
+	if _, err := dbm.queryStatement(ctx, &statement{query: "select 1"}); err != ErrRowLockNeedsTx {
+		t.Fatalf("expected locking outside a transaction to be refused, got %v\n", err)
+	}
+
+	query, _ := Select("jobs").Limit(10).Lock(RowLock{Strength: ForUpdate, Wait: SkipLocked}).Build()
+	if query != `select * from "jobs" limit $1 FOR UPDATE SKIP LOCKED` {
+		t.Fatalf("unexpected sql: %s\n", query)
+	}
+}
@@ -0,0 +1,192 @@
+package godbm
+
+import (
+	"context"
+	"database/sql"
+)
+
+// ForEachRow executes the prepared statement registered under key and calls fn once per row
+// with a scan function for reading the row's columns. Iteration stops at the first error
+// returned by fn. The rows are always closed and any error from iterating them is returned.
+func (store *SqlStore) ForEachRow(key string, fn func(scan func(dest ...interface{}) error) error, args ...interface{}) error {
+	return store.ForEachRowContext(context.Background(), key, fn, args...)
+}
+
+// ForEachRowContext is the same as ForEachRow but takes a context which is passed to the
+// underlying statement.
+func (store *SqlStore) ForEachRowContext(ctx context.Context, key string, fn func(scan func(dest ...interface{}) error) error, args ...interface{}) (err error) {
+	rows, err := store.QueryPreparedContext(ctx, key, args...)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := rows.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	scan := func(dest ...interface{}) error {
+		return rows.Scan(store.scanDest(dest)...)
+	}
+	for rows.Next() {
+		if err := fn(scan); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// QueryPreparedMaps executes the prepared statement registered under key and returns every row
+// as a map of column name to value. Text and numeric columns are returned as strings, unless
+// SetDecimalType was called, bytea as []byte and other types as decoded by the driver. NULLs are
+// returned as nil. Results of statements registered with Cached may come from the cache, see
+// also Coalesced.
+func (store *SqlStore) QueryPreparedMaps(key string, args ...interface{}) ([]map[string]interface{}, error) {
+	return store.QueryPreparedMapsContext(context.Background(), key, args...)
+}
+
+// QueryPreparedMapsContext is the same as QueryPreparedMaps but takes a context which is passed
+// to the underlying statement.
+func (store *SqlStore) QueryPreparedMapsContext(ctx context.Context, key string, args ...interface{}) ([]map[string]interface{}, error) {
+	st, err := store.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	return store.cachedMaps(ctx, st, args, func() ([]map[string]interface{}, error) {
+		return store.coalescedMaps(ctx, st, args, func() ([]map[string]interface{}, error) {
+			rows, err := store.QueryPreparedContext(ctx, key, args...)
+			if err != nil {
+				return nil, err
+			}
+			return store.collectMaps(rows)
+		})
+	})
+}
+
+// collectMaps reads every row into a map of column name to value and closes the rows.
+func (store *SqlStore) collectMaps(rows *sql.Rows) (results []map[string]interface{}, err error) {
+	defer rows.Close()
+
+	reader, err := newRowReader(rows)
+	if err != nil {
+		return nil, err
+	}
+	reader.decimal = store.decimal
+
+	results = make([]map[string]interface{}, 0)
+	for rows.Next() {
+		values, err := reader.read(rows)
+		if err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{}, len(values))
+		for i, col := range reader.columns {
+			row[col] = values[i]
+		}
+		results = append(results, row)
+	}
+	return results, rows.Err()
+}
+
+// rowReader scans rows of unknown shape using the column metadata of the result set.
+type rowReader struct {
+	columns []string          // column names
+	types   []*sql.ColumnType // column types
+	values  []interface{}     // scan destinations
+	ptrs    []interface{}     // pointers to values, passed to Scan
+	decimal DecimalFunc       // optional conversion of numeric columns
+}
+
+// newRowReader creates a rowReader for the columns of rows.
+func newRowReader(rows *sql.Rows) (*rowReader, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &rowReader{
+		columns: columns,
+		types:   types,
+		values:  make([]interface{}, len(columns)),
+		ptrs:    make([]interface{}, len(columns)),
+	}
+	for i := range r.values {
+		r.ptrs[i] = &r.values[i]
+	}
+	return r, nil
+}
+
+// read scans the current row and returns its values. The returned slice is only valid until
+// the next call to read.
+func (r *rowReader) read(rows *sql.Rows) ([]interface{}, error) {
+	if err := rows.Scan(r.ptrs...); err != nil {
+		return nil, err
+	}
+	for i, v := range r.values {
+		b, ok := v.([]byte)
+		if !ok || r.types[i].DatabaseTypeName() == "BYTEA" {
+			continue
+		}
+		if r.decimal != nil && r.types[i].DatabaseTypeName() == "NUMERIC" {
+			d, err := r.decimal(string(b))
+			if err != nil {
+				return nil, err
+			}
+			r.values[i] = d
+			continue
+		}
+		r.values[i] = string(b)
+	}
+	return r.values, nil
+}
+
+// CountPrepared executes the prepared statement registered under key, which should return a
+// single count such as "select count(*) from ...", and returns the value.
+func (store *SqlStore) CountPrepared(key string, args ...interface{}) (int64, error) {
+	return store.CountPreparedContext(context.Background(), key, args...)
+}
+
+// CountPreparedContext is the same as CountPrepared but takes a context which is passed to the
+// underlying statement.
+func (store *SqlStore) CountPreparedContext(ctx context.Context, key string, args ...interface{}) (count int64, err error) {
+	err = store.scanOne(ctx, key, &count, args...)
+	return count, err
+}
+
+// ExistsPrepared executes the prepared statement registered under key, which should return a
+// single boolean such as "select exists(select 1 from ...)", and returns the value.
+func (store *SqlStore) ExistsPrepared(key string, args ...interface{}) (bool, error) {
+	return store.ExistsPreparedContext(context.Background(), key, args...)
+}
+
+// ExistsPreparedContext is the same as ExistsPrepared but takes a context which is passed to the
+// underlying statement.
+func (store *SqlStore) ExistsPreparedContext(ctx context.Context, key string, args ...interface{}) (exists bool, err error) {
+	err = store.scanOne(ctx, key, &exists, args...)
+	return exists, err
+}
+
+// scanOne executes the prepared statement registered under key and scans the first row into
+// dest, returning sql.ErrNoRows if there are none.
+func (store *SqlStore) scanOne(ctx context.Context, key string, dest interface{}, args ...interface{}) error {
+	rows, err := store.QueryPreparedContext(ctx, key, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	if err := rows.Scan(store.scanDest([]interface{}{dest})...); err != nil {
+		return err
+	}
+	return rows.Close()
+}
@@ -0,0 +1,217 @@
+package godbm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Optional holds a value which may be NULL. It can be scanned into, passed as an argument and
+// used for struct fields, as an alternative to sql.NullString and friends for any type.
+type Optional[T any] struct {
+	V     T
+	Valid bool
+}
+
+// Some returns a valid Optional holding v.
+func Some[T any](v T) Optional[T] {
+	return Optional[T]{V: v, Valid: true}
+}
+
+// Get returns the value and whether it is valid.
+func (o Optional[T]) Get() (T, bool) {
+	return o.V, o.Valid
+}
+
+// Or returns the value if it is valid, otherwise def.
+func (o Optional[T]) Or(def T) T {
+	if o.Valid {
+		return o.V
+	}
+	return def
+}
+
+// Ptr returns a pointer to a copy of the value, or nil if it is not valid.
+func (o Optional[T]) Ptr() *T {
+	if !o.Valid {
+		return nil
+	}
+	v := o.V
+	return &v
+}
+
+// Scan implements sql.Scanner.
+func (o *Optional[T]) Scan(src interface{}) error {
+	var n sql.Null[T]
+	err := n.Scan(src)
+	o.V, o.Valid = n.V, n.Valid
+	return err
+}
+
+// Value implements driver.Valuer.
+func (o Optional[T]) Value() (driver.Value, error) {
+	return sql.Null[T]{V: o.V, Valid: o.Valid}.Value()
+}
+
+// ScanStruct scans the current row of rows into the struct v points to, matching columns to
+// fields with db tags, see fieldsOf. NULL columns set pointer fields to nil and other fields to
+// their zero value, fields implementing sql.Scanner such as Optional handle NULL themselves.
+// Returns an error if a column has no matching field, or maps to a field tagged encrypted which
+// needs QueryPreparedStructs to be decrypted.
+func ScanStruct(rows *sql.Rows, v interface{}) error {
+	rv, err := structValue(v)
+	if err != nil {
+		return err
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	s, err := newStructScanner(rv.Type(), columns, nil)
+	if err != nil {
+		return err
+	}
+	return s.scan(rows, rv)
+}
+
+// QueryPreparedStructs executes the prepared statement registered under key and appends every
+// row to the slice dest points to, which may be a slice of structs or of struct pointers. See
+// ScanStruct for how columns are matched to fields, fields of types registered with
+// RegisterType are scanned with their ScannerFunc and fields tagged encrypted are decrypted.
+func (store *SqlStore) QueryPreparedStructs(key string, dest interface{}, args ...interface{}) error {
+	return store.QueryPreparedStructsContext(context.Background(), key, dest, args...)
+}
+
+// QueryPreparedStructsContext is the same as QueryPreparedStructs but takes a context which is
+// passed to the underlying statement.
+func (store *SqlStore) QueryPreparedStructsContext(ctx context.Context, key string, dest interface{}, args ...interface{}) error {
+	return store.queryStructs(dest, func() (*sql.Rows, error) {
+		return store.QueryPreparedContext(ctx, key, args...)
+	})
+}
+
+// queryStructs checks dest is a pointer to a slice of structs, then runs query and appends
+// every row to the slice.
+func (store *SqlStore) queryStructs(dest interface{}, query func() (*sql.Rows, error)) error {
+	slice := reflect.ValueOf(dest)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return errors.New("godbm: error expected a pointer to a slice")
+	}
+	slice = slice.Elem()
+	elem := slice.Type().Elem()
+	isPtr := elem.Kind() == reflect.Ptr
+	if isPtr {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return errors.New("godbm: error expected a slice of structs")
+	}
+
+	rows, err := query()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	s, err := newStructScanner(elem, columns, store)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		item := reflect.New(elem)
+		if err := s.scan(rows, item.Elem()); err != nil {
+			return err
+		}
+		if isPtr {
+			slice.Set(reflect.Append(slice, item))
+		} else {
+			slice.Set(reflect.Append(slice, item.Elem()))
+		}
+	}
+	return rows.Err()
+}
+
+// structScanner scans rows with a fixed set of columns into structs of one type.
+type structScanner struct {
+	fields   [][]int       // field index for each column
+	nullable []bool        // columns scanned through a pointer so NULL becomes the zero value
+	scanners []ScannerFunc // registered scanners or decryption for columns, see RegisterType
+}
+
+// newStructScanner matches columns to the fields of the struct type t. store may be nil, otherwise
+// its registered scanners are used.
+func newStructScanner(t reflect.Type, columns []string, store *SqlStore) (*structScanner, error) {
+	byColumn := make(map[string]structField)
+	for _, f := range fieldsOf(t) {
+		byColumn[f.column] = f
+	}
+
+	scannerType := reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	s := &structScanner{
+		fields:   make([][]int, len(columns)),
+		nullable: make([]bool, len(columns)),
+		scanners: make([]ScannerFunc, len(columns)),
+	}
+	for i, col := range columns {
+		f, ok := byColumn[col]
+		if !ok {
+			return nil, fmt.Errorf("godbm: error column %q has no matching field in %s", col, t)
+		}
+		ft := t.FieldByIndex(f.index).Type
+		s.fields[i] = f.index
+		if f.encrypted {
+			if store == nil {
+				return nil, ErrNoEncryption
+			}
+			s.scanners[i] = store.decryptFunc(col)
+			continue
+		}
+		if store != nil {
+			if s.scanners[i] = store.scannerFor(ft); s.scanners[i] != nil {
+				continue
+			}
+		}
+		s.nullable[i] = ft.Kind() != reflect.Ptr && !reflect.PointerTo(ft).Implements(scannerType)
+	}
+	return s, nil
+}
+
+// scan scans the current row into the struct rv. Plain fields are scanned through a new
+// pointer, which database/sql leaves nil for NULL, and copied over afterwards.
+func (s *structScanner) scan(rows *sql.Rows, rv reflect.Value) error {
+	dest := make([]interface{}, len(s.fields))
+	for i, index := range s.fields {
+		field := rv.FieldByIndex(index)
+		switch {
+		case s.scanners[i] != nil:
+			dest[i] = &funcScanner{scanner: s.scanners[i], dest: field.Addr().Interface()}
+		case s.nullable[i]:
+			dest[i] = reflect.New(reflect.PointerTo(field.Type())).Interface()
+		default:
+			dest[i] = field.Addr().Interface()
+		}
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return err
+	}
+
+	for i, index := range s.fields {
+		if !s.nullable[i] {
+			continue
+		}
+		field := rv.FieldByIndex(index)
+		if ptr := reflect.ValueOf(dest[i]).Elem(); ptr.IsNil() {
+			field.Set(reflect.Zero(field.Type()))
+		} else {
+			field.Set(ptr.Elem())
+		}
+	}
+	return nil
+}
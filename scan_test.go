@@ -0,0 +1,117 @@
+package godbm
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// rowsDriver returns the same fixed rows for every query.
+type rowsDriver struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (d *rowsDriver) Open(dsn string) (driver.Conn, error) {
+	return &rowsConn{d}, nil
+}
+
+type rowsConn struct{ d *rowsDriver }
+
+func (c *rowsConn) Prepare(query string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *rowsConn) Close() error                              { return nil }
+func (c *rowsConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+func (c *rowsConn) Query(query string, args []driver.Value) (driver.Rows, error) {
+	return &fixedRows{d: c.d}, nil
+}
+
+type fixedRows struct {
+	d   *rowsDriver
+	pos int
+}
+
+func (r *fixedRows) Columns() []string { return r.d.columns }
+func (r *fixedRows) Close() error      { return nil }
+func (r *fixedRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.d.values) {
+		return io.EOF
+	}
+	copy(dest, r.d.values[r.pos])
+	r.pos++
+	return nil
+}
+
+type nullableUser struct {
+	ID       int64            `db:"id"`
+	Email    string           `db:"email"`
+	Nickname *string          `db:"nickname"`
+	Age      Optional[int64]  `db:"age"`
+	Tags     Optional[string] `db:"tags"`
+}
+
+func TestScanStruct(t *testing.T) {
+	sql.Register("godbm-rows", &rowsDriver{
+		columns: []string{"id", "email", "nickname", "age", "tags"},
+		values: [][]driver.Value{
+			{int64(1), "a@example.com", "a", int64(30), nil},
+			{int64(2), nil, nil, nil, "x"},
+		},
+	})
+	db, err := sql.Open("godbm-rows", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("select")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var users []nullableUser
+	for rows.Next() {
+		var u nullableUser
+		if err := ScanStruct(rows, &u); err != nil {
+			t.Fatalf("error scanning struct: %v\n", err)
+		}
+		users = append(users, u)
+	}
+
+	if len(users) != 2 {
+		t.Fatalf("expected 2 users got %d\n", len(users))
+	}
+	first, second := users[0], users[1]
+	if first.Email != "a@example.com" || first.Nickname == nil || *first.Nickname != "a" || first.Age.Or(0) != 30 || first.Tags.Valid {
+		t.Fatalf("unexpected first user: %+v\n", first)
+	}
+	if second.Email != "" || second.Nickname != nil || second.Age.Valid || second.Tags.V != "x" {
+		t.Fatalf("unexpected second user: %+v\n", second)
+	}
+}
+
+func TestStructScannerUnknownColumn(t *testing.T) {
+	if _, err := newStructScanner(reflect.TypeOf(nullableUser{}), []string{"id", "missing"}, nil); err == nil {
+		t.Fatalf("expected an error for a column without a field\n")
+	}
+}
+
+func TestOptional(t *testing.T) {
+	o := Some("a")
+	if v, ok := o.Get(); !ok || v != "a" || *o.Ptr() != "a" {
+		t.Fatalf("unexpected optional: %+v\n", o)
+	}
+	if v, _ := o.Value(); v != "a" {
+		t.Fatalf("unexpected value: %v\n", v)
+	}
+
+	var none Optional[string]
+	if none.Ptr() != nil || none.Or("b") != "b" {
+		t.Fatalf("unexpected empty optional: %+v\n", none)
+	}
+	if v, err := none.Value(); v != nil || err != nil {
+		t.Fatalf("expected NULL value: %v %v\n", v, err)
+	}
+}
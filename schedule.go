@@ -0,0 +1,151 @@
+package godbm
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// errScheduleNeverFires is logged when a job's schedule has no next run, such as the 31st of
+// february, and the job is stopped.
+var errScheduleNeverFires = errors.New("godbm: error schedule has no next run")
+
+// scheduleHistory holds a row per scheduled run, it is created by the first run.
+const scheduleHistory = `create table if not exists godbm_schedule_runs (
+	name text not null, started timestamptz not null, finished timestamptz not null,
+	rows_affected bigint not null, error text, primary key (name, started))`
+
+// scheduledJob is a registered statement executed on a cron schedule, see Schedule.
+type scheduledJob struct {
+	name     string
+	schedule *cronSchedule
+	key      string
+	args     []interface{}
+}
+
+// ScheduledRun is a run of a scheduled statement recorded in the godbm_schedule_runs table.
+type ScheduledRun struct {
+	Name         string
+	Started      time.Time
+	Finished     time.Time
+	RowsAffected int64
+	Err          string // the error the run failed with, empty if it succeeded.
+}
+
+// Schedule executes the statement registered under key with args on the cron schedule spec
+// while the store is connected. spec is a five field cron expression such as "*/15 * * * *",
+// a macro such as @hourly or @daily, or "@every 10m", evaluated in the local time zone.
+//
+// Each run takes an advisory lock on name in a transaction around the statement, so when
+// several processes schedule the same job only one runs it each time and the others skip. Runs
+// are recorded in the godbm_schedule_runs table, see ScheduleHistory. Must be called before
+// Connect.
+func (store *SqlStore) Schedule(name, spec, key string, args ...interface{}) error {
+	schedule, err := parseCron(spec)
+	if err != nil {
+		return err
+	}
+	store.schedules = append(store.schedules, scheduledJob{name: name, schedule: schedule, key: key, args: args})
+	return nil
+}
+
+// ScheduleHistory returns up to limit of the most recent runs of the scheduled job name, from
+// every process, newest first.
+func (store *SqlStore) ScheduleHistory(ctx context.Context, name string, limit int) ([]ScheduledRun, error) {
+	if _, err := store.ExecContext(ctx, scheduleHistory); err != nil {
+		return nil, err
+	}
+	rows, err := store.QueryContext(ctx, "select name, started, finished, rows_affected, coalesce(error, '') "+
+		"from godbm_schedule_runs where name = $1 order by started desc limit $2", name, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []ScheduledRun
+	for rows.Next() {
+		var run ScheduledRun
+		if err := rows.Scan(&run.Name, &run.Started, &run.Finished, &run.RowsAffected, &run.Err); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// startSchedules starts running the scheduled statements.
+func (store *SqlStore) startSchedules() {
+	if len(store.schedules) == 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	store.stopSchedules = cancel
+	for _, job := range store.schedules {
+		go store.runSchedule(ctx, job)
+	}
+}
+
+// runSchedule runs job each time its schedule fires until ctx is done.
+func (store *SqlStore) runSchedule(ctx context.Context, job scheduledJob) {
+	for {
+		next := job.schedule.next(time.Now())
+		if next.IsZero() {
+			store.logEvent(ctx, "godbm: schedule never fires", errScheduleNeverFires, slog.String("name", job.name))
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		store.runScheduled(ctx, job)
+	}
+}
+
+// runScheduled executes job once unless another process holds its lock, recording the run.
+func (store *SqlStore) runScheduled(ctx context.Context, job scheduledJob) (ran bool, err error) {
+	started := time.Now()
+	var affected int64
+	err = store.WithTransaction(ctx, func(ctx context.Context, tx *Tx) error {
+		locked, err := store.tryXactLock(ctx, "godbm.schedule", job.name)
+		if err != nil || !locked {
+			return err
+		}
+		ran = true
+		result, err := store.ExecPreparedContext(ctx, job.key, job.args...)
+		if err != nil {
+			return err
+		}
+		affected, err = result.RowsAffected()
+		return err
+	})
+	if !ran && err == nil {
+		return false, nil
+	}
+	store.logEvent(ctx, "godbm: scheduled run", err, slog.String("name", job.name), slog.Int64("rows", affected))
+
+	var msg interface{}
+	if err != nil {
+		msg = err.Error()
+	}
+	if _, herr := store.ExecContext(ctx, scheduleHistory); herr != nil {
+		return true, err
+	}
+	_, herr := store.ExecContext(ctx, "insert into godbm_schedule_runs (name, started, finished, rows_affected, error) values ($1, $2, $3, $4, $5)",
+		job.name, started, time.Now(), affected, msg)
+	if herr != nil {
+		store.logEvent(ctx, "godbm: scheduled run history", herr, slog.String("name", job.name))
+	}
+	return true, err
+}
+
+// endSchedules stops running the scheduled statements.
+func (store *SqlStore) endSchedules() {
+	if store.stopSchedules != nil {
+		store.stopSchedules()
+		store.stopSchedules = nil
+	}
+}
@@ -0,0 +1,18 @@
+package godbm
+
+import (
+	"testing"
+)
+
+func TestSchedule(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	if err := dbm.Schedule("purge", "every hour", "purge_sessions"); err == nil {
+		t.Fatalf("expected an error for an invalid schedule\n")
+	}
+	if err := dbm.Schedule("purge", "@hourly", "purge_sessions", 30); err != nil {
+		t.Fatalf("error scheduling: %v\n", err)
+	}
+	if len(dbm.schedules) != 1 || dbm.schedules[0].key != "purge_sessions" || len(dbm.schedules[0].args) != 1 {
+		t.Fatalf("unexpected schedules: %v\n", dbm.schedules)
+	}
+}
@@ -0,0 +1,195 @@
+package godbm
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SchemaSpec is the schema the database is expected to have, see VerifySchema. It can be
+// declared in code or loaded from a json, yaml or toml spec file.
+type SchemaSpec struct {
+	Tables []TableSpec `json:"tables" yaml:"tables" toml:"tables"`
+}
+
+// TableSpec is a table expected by a SchemaSpec. Columns and indexes the table has beyond
+// those listed are not reported.
+type TableSpec struct {
+	Name    string       `json:"name" yaml:"name" toml:"name"`          // may be schema qualified.
+	Columns []ColumnSpec `json:"columns" yaml:"columns" toml:"columns"` // expected columns.
+	Indexes []string     `json:"indexes" yaml:"indexes" toml:"indexes"` // names of expected indexes.
+}
+
+// ColumnSpec is a column expected by a TableSpec.
+type ColumnSpec struct {
+	Name string `json:"name" yaml:"name" toml:"name"`
+	Type string `json:"type" yaml:"type" toml:"type"` // such as "varchar(10)" or "timestamptz", empty to only check the column exists.
+}
+
+// SchemaDrift is a difference between the database and a SchemaSpec.
+type SchemaDrift struct {
+	Table   string // the table name from the spec.
+	Column  string // the column, empty unless the drift is about a column.
+	Index   string // the index, empty unless the drift is about an index.
+	Problem string // such as "missing table" or "type is integer, expected bigint".
+}
+
+func (d SchemaDrift) String() string {
+	name := d.Table
+	if d.Column != "" {
+		name += "." + d.Column
+	}
+	if d.Index != "" {
+		name += " index " + d.Index
+	}
+	return name + ": " + d.Problem
+}
+
+// SchemaError is returned by VerifySchema and holds every difference found.
+type SchemaError struct {
+	Drift []SchemaDrift // in the order of the spec.
+}
+
+func (e *SchemaError) Error() string {
+	msgs := make([]string, len(e.Drift))
+	for i, drift := range e.Drift {
+		msgs[i] = drift.String()
+	}
+	return "godbm: error schema has drifted in " + strconv.Itoa(len(e.Drift)) + " places: " + strings.Join(msgs, "; ")
+}
+
+// VerifySchema compares the live database with expected and reports missing tables, columns
+// and indexes and columns of the wrong type at once as a *SchemaError. Call it at startup
+// alongside ValidateStatements to catch environments which have drifted from the migrations.
+func (store *SqlStore) VerifySchema(ctx context.Context, expected SchemaSpec) error {
+	if !store.Connected {
+		return &ConnectionError{}
+	}
+
+	schemaErr := &SchemaError{}
+	for _, table := range expected.Tables {
+		drift, err := store.verifyTable(ctx, table)
+		if err != nil {
+			return err
+		}
+		schemaErr.Drift = append(schemaErr.Drift, drift...)
+	}
+	if len(schemaErr.Drift) > 0 {
+		return schemaErr
+	}
+	return nil
+}
+
+// verifyTable compares a single table with its spec.
+func (store *SqlStore) verifyTable(ctx context.Context, table TableSpec) ([]SchemaDrift, error) {
+	name := QuoteQualified(table.Name)
+	var exists bool
+	rows, err := store.QueryContext(ctx, "select to_regclass($1) is not null", name)
+	if err != nil {
+		return nil, err
+	}
+	if rows.Next() {
+		err = rows.Scan(&exists)
+	}
+	if cerr := rows.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return []SchemaDrift{{Table: table.Name, Problem: "missing table"}}, nil
+	}
+
+	columns, err := store.stringPairs(ctx, "select attname, format_type(atttypid, atttypmod) from pg_attribute "+
+		"where attrelid = to_regclass($1) and attnum > 0 and not attisdropped", name)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := store.stringPairs(ctx, "select c.relname, '' from pg_index i join pg_class c on c.oid = i.indexrelid "+
+		"where i.indrelid = to_regclass($1)", name)
+	if err != nil {
+		return nil, err
+	}
+
+	var drift []SchemaDrift
+	for _, column := range table.Columns {
+		actual, ok := columns[column.Name]
+		switch {
+		case !ok:
+			drift = append(drift, SchemaDrift{Table: table.Name, Column: column.Name, Problem: "missing column"})
+		case column.Type != "" && normalizeType(column.Type) != actual:
+			drift = append(drift, SchemaDrift{Table: table.Name, Column: column.Name, Problem: "type is " + actual + ", expected " + column.Type})
+		}
+	}
+	for _, index := range table.Indexes {
+		if _, ok := indexes[index]; !ok {
+			drift = append(drift, SchemaDrift{Table: table.Name, Index: index, Problem: "missing index"})
+		}
+	}
+	return drift, nil
+}
+
+// stringPairs runs query, which returns two text columns, and maps the first to the second.
+func (store *SqlStore) stringPairs(ctx context.Context, query string, args ...interface{}) (map[string]string, error) {
+	rows, err := store.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pairs := make(map[string]string)
+	for rows.Next() {
+		var k, v string
+		if err := rows.Scan(&k, &v); err != nil {
+			return nil, err
+		}
+		pairs[k] = v
+	}
+	return pairs, rows.Err()
+}
+
+// typeAliases maps common type names to the names format_type returns.
+var typeAliases = map[string]string{
+	"int":         "integer",
+	"int4":        "integer",
+	"int2":        "smallint",
+	"int8":        "bigint",
+	"serial":      "integer",
+	"bigserial":   "bigint",
+	"float4":      "real",
+	"float8":      "double precision",
+	"float":       "double precision",
+	"bool":        "boolean",
+	"varchar":     "character varying",
+	"char":        "character",
+	"decimal":     "numeric",
+	"timestamp":   "timestamp without time zone",
+	"timestamptz": "timestamp with time zone",
+	"time":        "time without time zone",
+	"timetz":      "time with time zone",
+}
+
+// typeModifier splits a type name such as varchar(10)[] into its name, modifier and suffix.
+var typeModifier = regexp.MustCompile(`^([a-z0-9 ]+?)\s*(\([0-9, ]+\))?((?:\[\])*)$`)
+
+// normalizeType returns the name format_type uses for typ, so "varchar(10)" matches
+// "character varying(10)". Unknown types are returned lower cased.
+func normalizeType(typ string) string {
+	typ = strings.ToLower(strings.TrimSpace(typ))
+	m := typeModifier.FindStringSubmatch(typ)
+	if m == nil {
+		return typ
+	}
+	name := m[1]
+	if alias, ok := typeAliases[name]; ok {
+		name = alias
+	}
+	modifier := strings.ReplaceAll(m[2], " ", "")
+	// format_type puts the modifier of timestamp and time types before "with/without time zone".
+	if base, zone, ok := strings.Cut(name, " with"); ok && modifier != "" && strings.HasPrefix(base, "time") {
+		return base + modifier + " with" + zone + m[3]
+	}
+	return name + modifier + m[3]
+}
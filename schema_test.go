@@ -0,0 +1,38 @@
+package godbm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeType(t *testing.T) {
+	cases := map[string]string{
+		"varchar(10)":          "character varying(10)",
+		"INT":                  "integer",
+		"int8[]":               "bigint[]",
+		"numeric(10, 2)":       "numeric(10,2)",
+		"timestamptz":          "timestamp with time zone",
+		"timestamp(3)":         "timestamp(3) without time zone",
+		"text":                 "text",
+		"character varying(5)": "character varying(5)",
+		"double precision":     "double precision",
+		"timestamptz(6)":       "timestamp(6) with time zone",
+	}
+	for typ, expected := range cases {
+		if normalized := normalizeType(typ); normalized != expected {
+			t.Fatalf("expected %s to normalize to %s, got %s\n", typ, expected, normalized)
+		}
+	}
+}
+
+func TestSchemaError(t *testing.T) {
+	err := &SchemaError{Drift: []SchemaDrift{
+		{Table: "users", Problem: "missing table"},
+		{Table: "events", Column: "created", Problem: "type is date, expected timestamptz"},
+		{Table: "events", Index: "events_created_idx", Problem: "missing index"},
+	}}
+	msg := err.Error()
+	if !strings.Contains(msg, "3 places") || !strings.Contains(msg, "events.created: type is date") || !strings.Contains(msg, "events index events_created_idx: missing index") {
+		t.Fatalf("unexpected error: %s\n", msg)
+	}
+}
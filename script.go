@@ -0,0 +1,120 @@
+package godbm
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ScriptError is returned by ExecScript when a statement in the script fails.
+type ScriptError struct {
+	Statement int    // 1 based position of the statement in the script.
+	Line      int    // line the statement starts on.
+	Query     string // the statement which failed.
+	Err       error
+}
+
+func (e *ScriptError) Error() string {
+	return "godbm: error in script statement " + strconv.Itoa(e.Statement) + " at line " + strconv.Itoa(e.Line) + ": " + e.Err.Error()
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// ExecScript reads a script of sql statements separated by semicolons, such as a schema or
+// bootstrap file, and executes them in order without preparing them, stopping at the first
+// error. Semicolons inside quotes, comments and dollar quoted function bodies don't end a
+// statement. psql meta commands such as \connect are not supported. To run the whole script in
+// one transaction call it inside WithTransaction:
+//
+//	err := store.WithTransaction(ctx, func(ctx context.Context, tx *godbm.Tx) error {
+//		return store.ExecScript(ctx, file)
+//	})
+func (store *SqlStore) ExecScript(ctx context.Context, r io.Reader) error {
+	if !store.Connected {
+		return &ConnectionError{}
+	}
+	if err := store.checkUnlocked(); err != nil {
+		return err
+	}
+	if err := store.checkWrite(); err != nil {
+		return err
+	}
+
+	script, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	for i, part := range splitScript(string(script)) {
+		st := &statement{query: part.query}
+		err := store.run(ctx, st, nil, func(ctx context.Context) error {
+			_, err := store.stmtFor(ctx, st).ExecContext(ctx)
+			return err
+		})
+		if err != nil {
+			return &ScriptError{Statement: i + 1, Line: part.line, Query: part.query, Err: err}
+		}
+	}
+	return nil
+}
+
+// scriptStatement is a statement split from a script.
+type scriptStatement struct {
+	query string
+	line  int // line the statement starts on
+}
+
+// splitScript splits script into statements on semicolons outside of string literals, quoted
+// identifiers, dollar quoted strings and comments. Statements holding only comments or
+// whitespace are dropped.
+func splitScript(script string) []scriptStatement {
+	var statements []scriptStatement
+	start, first := 0, -1 // first is the index of the statement's first non comment character
+	flush := func(end int) {
+		if first >= 0 {
+			line := 1 + strings.Count(script[:first], "\n")
+			statements = append(statements, scriptStatement{query: strings.TrimSpace(script[start:end]), line: line})
+		}
+		start, first = end+1, -1
+	}
+	mark := func(i int) {
+		if first < 0 {
+			first = i
+		}
+	}
+
+	for i := 0; i < len(script); i++ {
+		switch c := script[i]; {
+		case c == ';':
+			flush(i)
+		case c == '\'' || c == '"':
+			mark(i)
+			i = skipQuoted(script, i, c)
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			if end := strings.IndexByte(script[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(script)
+			}
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			i = skipBlockComment(script, i)
+		case c == '$':
+			mark(i)
+			if tag, ok := dollarTag(script, i); ok {
+				if end := strings.Index(script[i+len(tag):], tag); end >= 0 {
+					i += len(tag) + end + len(tag) - 1
+				} else {
+					i = len(script)
+				}
+			}
+		case c != ' ' && c != '\t' && c != '\r' && c != '\n':
+			mark(i)
+		}
+	}
+	if start < len(script) {
+		flush(len(script))
+	}
+	return statements
+}
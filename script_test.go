@@ -0,0 +1,39 @@
+package godbm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitScript(t *testing.T) {
+	script := `-- bootstrap
+create table t (a text default 'x;y');
+
+/* a function; with a body */
+create function f() returns int as $body$
+begin
+	return 1; -- inside the body
+end;
+$body$ language plpgsql;
+insert into "odd;name" values ($$;$$)
+;
+-- trailing comment only;
+`
+	statements := splitScript(script)
+	if len(statements) != 3 {
+		t.Fatalf("expected 3 statements got %d: %+v\n", len(statements), statements)
+	}
+	if statements[0].query != "-- bootstrap\ncreate table t (a text default 'x;y')" || statements[0].line != 2 {
+		t.Fatalf("unexpected first statement: %+v\n", statements[0])
+	}
+	if statements[1].line != 5 || !strings.HasSuffix(statements[1].query, "$body$ language plpgsql") {
+		t.Fatalf("unexpected second statement: %+v\n", statements[1])
+	}
+	if statements[2].query != `insert into "odd;name" values ($$;$$)` || statements[2].line != 10 {
+		t.Fatalf("unexpected third statement: %+v\n", statements[2])
+	}
+
+	if statements := splitScript("select 1"); len(statements) != 1 || statements[0].query != "select 1" {
+		t.Fatalf("expected a statement without a semicolon: %+v\n", statements)
+	}
+}
@@ -0,0 +1,121 @@
+package godbm
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// TextQueryMode is how a TextSearch turns user input into a tsquery. Every mode accepts any
+// input, unlike to_tsquery which fails on stray operators and quotes.
+type TextQueryMode int
+
+const (
+	WebSearchQuery TextQueryMode = iota // websearch_to_tsquery, accepts "quoted phrases", or and -word.
+	PlainQuery                          // plainto_tsquery, every word must match.
+	PhraseQuery                         // phraseto_tsquery, the words must match in order.
+	PrefixQuery                         // every word must match as a prefix, for search as you type, see PrefixTSQuery.
+)
+
+// function returns the sql function building the tsquery.
+func (m TextQueryMode) function() string {
+	switch m {
+	case PlainQuery:
+		return "plainto_tsquery"
+	case PhraseQuery:
+		return "phraseto_tsquery"
+	case PrefixQuery:
+		return "to_tsquery"
+	}
+	return "websearch_to_tsquery"
+}
+
+// TextSearch builds a ranked full text search. User input is only ever passed as a parameter.
+//
+//	search := godbm.TextSearch{Table: "articles", Document: "search", Config: "english",
+//		Columns: []string{"id", "title"}, Highlight: "body", Limit: 20}
+//	err := store.SearchStructs(ctx, search, input, &results)
+type TextSearch struct {
+	Table    string        // table searched, may be schema qualified.
+	Document string        // tsvector column or expression matched, written by the caller like a Where condition.
+	Config   string        // text search configuration such as english, defaults to default_text_search_config.
+	Mode     TextQueryMode // how the input is parsed, defaults to WebSearchQuery.
+	Columns  []string      // columns selected besides rank and headline, all columns if empty.
+	Limit    int           // maximum rows returned, zero for no limit.
+
+	// Highlight optionally names a column whose matching words are returned highlighted by
+	// ts_headline in a headline column, with HighlightOptions such as "StartSel=<b>, StopSel=</b>".
+	Highlight        string
+	HighlightOptions string
+
+	// Where optionally filters the rows searched, written in sql with ? parameters as for
+	// SelectBuilder.Where.
+	Where     string
+	WhereArgs []interface{}
+}
+
+// Build returns the sql and arguments searching for input. Rows are ordered by their ts_rank,
+// returned in a rank column.
+func (s TextSearch) Build(input string) (string, []interface{}) {
+	if s.Mode == PrefixQuery {
+		input = PrefixTSQuery(input)
+	}
+	args := []interface{}{input}
+	tsquery := s.Mode.function() + "($1)"
+	config := ""
+	if s.Config != "" {
+		args = append(args, s.Config)
+		config = "$2::regconfig, "
+		tsquery = s.Mode.function() + "($2::regconfig, $1)"
+	}
+
+	columns := "*"
+	if len(s.Columns) > 0 {
+		columns = quoteList(s.Columns)
+	}
+	query := "select " + columns + ", ts_rank(" + s.Document + ", godbm_q) as rank"
+	if s.Highlight != "" {
+		query += ", ts_headline(" + config + QuoteQualified(s.Highlight) + ", godbm_q"
+		if s.HighlightOptions != "" {
+			args = append(args, s.HighlightOptions)
+			query += ", $" + strconv.Itoa(len(args))
+		}
+		query += ") as headline"
+	}
+	query += " from " + QuoteQualified(s.Table) + ", " + tsquery + " godbm_q where " + s.Document + " @@ godbm_q"
+	if s.Where != "" {
+		query += " and (" + numberParams(s.Where, len(args)) + ")"
+		args = append(args, s.WhereArgs...)
+	}
+	query += " order by rank desc"
+	if s.Limit > 0 {
+		args = append(args, s.Limit)
+		query += " limit $" + strconv.Itoa(len(args))
+	}
+	return query, args
+}
+
+// SearchStructs runs search for input and appends every row to the slice dest points to, as
+// QueryPreparedStructs does. Give the struct fields tagged rank and headline to read them.
+func (store *SqlStore) SearchStructs(ctx context.Context, search TextSearch, input string, dest interface{}) error {
+	query, args := search.Build(input)
+	return store.queryStructs(dest, func() (*sql.Rows, error) {
+		return store.QueryContext(ctx, query, args...)
+	})
+}
+
+// PrefixTSQuery returns a to_tsquery expression matching every word of input as a prefix, so
+// "postgr data" matches "PostgreSQL database". Everything but letters and digits separates
+// words and each word is quoted, so input can't inject tsquery operators. Returns an empty
+// string, which matches nothing, if input has no words.
+func PrefixTSQuery(input string) string {
+	words := strings.FieldsFunc(input, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = "'" + word + "':*"
+	}
+	return strings.Join(words, " & ")
+}
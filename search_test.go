@@ -0,0 +1,32 @@
+package godbm
+
+import "testing"
+
+func TestTextSearchBuild(t *testing.T) {
+	search := TextSearch{Table: "articles", Document: "search", Config: "english", Columns: []string{"id", "title"},
+		Highlight: "body", HighlightOptions: "MaxWords=10", Where: "tenant = ?", WhereArgs: []interface{}{7}, Limit: 20}
+	query, args := search.Build("big 'data'")
+	expected := `select "id", "title", ts_rank(search, godbm_q) as rank, ts_headline($2::regconfig, "body", godbm_q, $3) as headline ` +
+		`from "articles", websearch_to_tsquery($2::regconfig, $1) godbm_q where search @@ godbm_q and (tenant = $4) order by rank desc limit $5`
+	if query != expected {
+		t.Fatalf("unexpected query: %s\n", query)
+	}
+	if len(args) != 5 || args[0] != "big 'data'" || args[1] != "english" || args[3] != 7 || args[4] != 20 {
+		t.Fatalf("unexpected args: %v\n", args)
+	}
+
+	query, args = TextSearch{Table: "articles", Document: "search", Mode: PrefixQuery}.Build("postgr da")
+	expected = `select *, ts_rank(search, godbm_q) as rank from "articles", to_tsquery($1) godbm_q where search @@ godbm_q order by rank desc`
+	if query != expected || len(args) != 1 || args[0] != "'postgr':* & 'da':*" {
+		t.Fatalf("unexpected prefix query: %s %v\n", query, args)
+	}
+}
+
+func TestPrefixTSQuery(t *testing.T) {
+	if q := PrefixTSQuery(`  it's "C++" & naïve | !x:*`); q != "'it':* & 's':* & 'C':* & 'naïve':* & 'x':*" {
+		t.Fatalf("unexpected query: %s\n", q)
+	}
+	if q := PrefixTSQuery(" &!| "); q != "" {
+		t.Fatalf("expected an empty query got %s\n", q)
+	}
+}
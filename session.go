@@ -0,0 +1,113 @@
+package godbm
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// SetApplicationName sets the application_name reported in pg_stat_activity and the server
+// logs for every connection. Must be called before Connect.
+func (store *SqlStore) SetApplicationName(name string) {
+	store.SetSessionParams(map[string]string{"application_name": name})
+}
+
+// SetSessionParams sets run-time parameters such as search_path, timezone or application_name
+// which are sent when every connection starts. Params are merged with those already set, an
+// empty value removes one. Must be called before Connect.
+func (store *SqlStore) SetSessionParams(params map[string]string) {
+	if store.sessionParams == nil {
+		store.sessionParams = make(map[string]string, len(params))
+	}
+	for name, value := range params {
+		if value == "" {
+			delete(store.sessionParams, name)
+			continue
+		}
+		store.sessionParams[name] = value
+	}
+}
+
+// sessionSettings returns the session params as connection string settings in name order.
+func (store *SqlStore) sessionSettings() string {
+	names := make([]string, 0, len(store.sessionParams))
+	for name := range store.sessionParams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var settings string
+	for _, name := range names {
+		settings += " " + name + "=" + dsnValue(store.sessionParams[name])
+	}
+	return settings
+}
+
+// ErrSessionSettingsNeedTx is returned when querying a registered statement with a context
+// carrying session settings but no transaction, as the settings could not be applied for the
+// lifetime of the rows.
+var ErrSessionSettingsNeedTx = errors.New("godbm: error session settings require a transaction for queries, use WithTransaction")
+
+// SetLocal sets the run-time parameter name to value for the remainder of the transaction,
+// the same as SET LOCAL but with the value passed as a parameter so it is safe to use with
+// untrusted input. Custom parameters such as app.current_tenant must contain a dot.
+func (tx *Tx) SetLocal(name, value string) error {
+	return tx.SetLocalContext(context.Background(), name, value)
+}
+
+// SetLocalContext is the same as SetLocal but takes a context.
+func (tx *Tx) SetLocalContext(ctx context.Context, name, value string) error {
+	_, err := tx.ExecContext(ctx, "select set_config($1, $2, true)", name, value)
+	if err == nil && name == "search_path" {
+		tx.searchPath = value
+	}
+	return err
+}
+
+type sessionSettingsKey struct{}
+
+// WithSessionSettings returns a context carrying run-time parameters, such as role or a row
+// level security tenant id, which are applied with SetLocal to transactions begun with it by
+// BeginTx and WithTransaction. ExecPreparedContext calls made with it outside of a transaction
+// run in their own transaction with the settings applied, QueryPreparedContext calls return
+// ErrSessionSettingsNeedTx. Settings are merged with any the context already carries.
+func WithSessionSettings(ctx context.Context, settings map[string]string) context.Context {
+	merged := make(map[string]string, len(settings))
+	for name, value := range sessionSettings(ctx) {
+		merged[name] = value
+	}
+	for name, value := range settings {
+		merged[name] = value
+	}
+	return context.WithValue(ctx, sessionSettingsKey{}, merged)
+}
+
+// sessionSettings returns the settings set with WithSessionSettings, if any.
+func sessionSettings(ctx context.Context) map[string]string {
+	settings, _ := ctx.Value(sessionSettingsKey{}).(map[string]string)
+	return settings
+}
+
+// needsSessionTx reports whether ctx carries session settings but no transaction to apply
+// them to.
+func needsSessionTx(ctx context.Context) bool {
+	_, inTx := TxFromContext(ctx)
+	return !inTx && len(sessionSettings(ctx)) > 0
+}
+
+// applySessionSettings applies the settings carried by ctx to tx in name order.
+func (tx *Tx) applySessionSettings(ctx context.Context) error {
+	settings := sessionSettings(ctx)
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := tx.SetLocalContext(ctx, name, settings[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
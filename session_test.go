@@ -0,0 +1,36 @@
+package godbm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSessionParams(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.SetApplicationName("billing")
+	dbm.SetSessionParams(map[string]string{"search_path": "tenant_a, public", "timezone": "UTC"})
+
+	dsn := dbm.dsn()
+	if !strings.Contains(dsn, " application_name=billing search_path='tenant_a, public' timezone=UTC") {
+		t.Fatalf("expected session params in name order, got: %s\n", dsn)
+	}
+
+	dbm.SetSessionParams(map[string]string{"timezone": ""})
+	if strings.Contains(dbm.dsn(), "timezone") {
+		t.Fatalf("expected an empty value to remove the param\n")
+	}
+}
+
+func TestWithSessionSettings(t *testing.T) {
+	ctx := WithSessionSettings(context.Background(), map[string]string{"app.current_tenant": "a", "role": "app"})
+	ctx = WithSessionSettings(ctx, map[string]string{"app.current_tenant": "b"})
+
+	settings := sessionSettings(ctx)
+	if len(settings) != 2 || settings["app.current_tenant"] != "b" || settings["role"] != "app" {
+		t.Fatalf("expected settings to be merged, got: %v\n", settings)
+	}
+	if !needsSessionTx(ctx) || needsSessionTx(context.Background()) {
+		t.Fatalf("expected only contexts with settings to need a transaction\n")
+	}
+}
@@ -0,0 +1,44 @@
+package godbm
+
+import "sync"
+
+// flightGroup deduplicates concurrent calls for the same key so that only one runs and the
+// others wait for and share its result.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// flight is an in progress or completed call.
+type flight struct {
+	wg  sync.WaitGroup
+	val interface{}
+	err error
+}
+
+// do runs fn for key unless a call for key is already in flight, in which case it waits for
+// that call and returns its result. shared reports whether the result went to other callers.
+func (g *flightGroup) do(key string, fn func() (interface{}, error)) (val interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if g.flights == nil {
+		g.flights = make(map[string]*flight)
+	}
+	if f, ok := g.flights[key]; ok {
+		g.mu.Unlock()
+		f.wg.Wait()
+		return f.val, f.err, true
+	}
+	f := &flight{}
+	f.wg.Add(1)
+	g.flights[key] = f
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.flights, key)
+		g.mu.Unlock()
+		f.wg.Done()
+	}()
+	f.val, f.err = fn()
+	return f.val, f.err, false
+}
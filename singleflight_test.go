@@ -0,0 +1,34 @@
+package godbm
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlightGroup(t *testing.T) {
+	var g flightGroup
+	var calls atomic.Int32
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, err, _ := g.do("key", func() (interface{}, error) {
+				calls.Add(1)
+				time.Sleep(20 * time.Millisecond)
+				return "prepared", nil
+			})
+			if val != "prepared" || err != nil {
+				t.Errorf("unexpected result %v %v\n", val, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("expected concurrent calls to share a single flight, got %d calls\n", n)
+	}
+}
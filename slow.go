@@ -0,0 +1,50 @@
+package godbm
+
+import (
+	"context"
+	"time"
+)
+
+// SlowQuery describes a call which exceeded the slow query threshold.
+type SlowQuery struct {
+	Key      string        // statement key, empty for ad-hoc queries.
+	Query    string        // the sql text.
+	Args     []interface{} // summary of the arguments, values are only included if LogOptions.LogArgs is set.
+	Duration time.Duration // how long the call took.
+	Plan     string        // EXPLAIN (FORMAT JSON) output when SlowQueryOptions.Explain is set.
+	PlanErr  error         // error capturing the plan, if any.
+}
+
+// SlowQueryOptions configures slow query detection.
+type SlowQueryOptions struct {
+	Threshold time.Duration         // calls taking at least this long are reported.
+	Callback  func(query SlowQuery) // called for every slow query.
+	Explain   bool                  // capture the plan in the background before calling Callback.
+}
+
+// SetSlowQueryOptions enables slow query detection, passing nil disables it. This should be
+// called before the store is in use.
+func (store *SqlStore) SetSlowQueryOptions(opts *SlowQueryOptions) {
+	store.slow = opts
+}
+
+// checkSlow reports the call to the slow query callback if it exceeded the threshold.
+func (store *SqlStore) checkSlow(st *statement, args []interface{}, duration time.Duration) {
+	opts := store.slow
+	if opts == nil || opts.Callback == nil || duration < opts.Threshold {
+		return
+	}
+
+	slow := SlowQuery{Key: st.key, Query: st.query, Args: store.logArgs(store.redact(st, args)), Duration: duration}
+	if !opts.Explain {
+		opts.Callback(slow)
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		slow.Plan, slow.PlanErr = store.explainJSON(ctx, st.query, args...)
+		opts.Callback(slow)
+	}()
+}
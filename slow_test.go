@@ -0,0 +1,32 @@
+package godbm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSlowQuery(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+
+	var reported []SlowQuery
+	dbm.SetSlowQueryOptions(&SlowQueryOptions{
+		Threshold: 10 * time.Millisecond,
+		Callback:  func(query SlowQuery) { reported = append(reported, query) },
+	})
+
+	st := &statement{key: "report", query: "select 1"}
+	dbm.run(context.Background(), st, []interface{}{"secret"}, func(ctx context.Context) error { return nil })
+	dbm.run(context.Background(), st, []interface{}{"secret"}, func(ctx context.Context) error {
+		time.Sleep(15 * time.Millisecond)
+		return nil
+	})
+
+	if len(reported) != 1 || reported[0].Key != "report" || reported[0].Duration < 10*time.Millisecond {
+		t.Fatalf("expected a single slow query to be reported, got: %+v\n", reported)
+	}
+
+	if reported[0].Args[0] == "secret" {
+		t.Fatalf("expected argument values to be summarized\n")
+	}
+}
@@ -0,0 +1,25 @@
+package godbm
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// PrepareFS registers every .sql file in fsys as a prepared statement, keyed by its slash
+// separated path without the extension, so queries/users/get.sql is registered as
+// "queries/users/get". Works with embed.FS and os.DirFS. Stops at the first file which fails
+// to prepare.
+func (store *SqlStore) PrepareFS(fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(name) != ".sql" {
+			return err
+		}
+
+		query, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		return store.PrepareAdd(strings.TrimSuffix(name, ".sql"), string(query))
+	})
+}
@@ -0,0 +1,45 @@
+package godbm
+
+import "strings"
+
+// SetStatementLabels labels the statements registered with PrepareAdd and its variants with a
+// name derived from their keys by label, such as StatementLabel, so pg_stat_activity,
+// pg_prepared_statements and the server logs attribute the sql to the statement it came from.
+// The label leads the statement's sql as a comment, where it survives truncation by
+// track_activity_query_size, and stays the same when statements are prepared again after
+// reconnecting. This doesn't name the server side prepared statements: the driver picks their
+// protocol level names, the name column of pg_prepared_statements, and database/sql prepares
+// a statement again on every connection it is used on, so neither can be controlled through
+// database/sql. Passing nil disables labels. Must be called before Connect.
+func (store *SqlStore) SetStatementLabels(label func(key string) string) {
+	store.stmtLabels = label
+}
+
+// StatementLabel is the default labeling for SetStatementLabels, it returns key prefixed with
+// godbm_.
+func StatementLabel(key string) string {
+	return "godbm_" + key
+}
+
+// labeled returns query led by the label of the statement key, if statements are labeled.
+// Ad-hoc queries have no key and are left unchanged.
+func (store *SqlStore) labeled(key, query string) string {
+	if store.stmtLabels == nil || key == "" {
+		return query
+	}
+	return "-- " + statementLabelSafe(store.stmtLabels(key)) + "\n" + query
+}
+
+// statementLabelSafe replaces anything in label besides letters, digits and _.:@- with _, so it
+// can't end the comment it is written in.
+func statementLabelSafe(label string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case strings.ContainsRune("_.:@-", r):
+			return r
+		}
+		return '_'
+	}, label)
+}
@@ -0,0 +1,24 @@
+package godbm
+
+import "testing"
+
+func TestStatementLabels(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	if query := dbm.labeled("get", "select 1"); query != "select 1" {
+		t.Fatalf("expected the query unchanged without labels got %q\n", query)
+	}
+
+	dbm.SetStatementLabels(StatementLabel)
+	if query := dbm.labeled("users.get@v2", "select 1"); query != "-- godbm_users.get@v2\nselect 1" {
+		t.Fatalf("expected the query led by its label got %q\n", query)
+	}
+	if query := dbm.labeled("", "select 1"); query != "select 1" {
+		t.Fatalf("expected ad-hoc queries unchanged got %q\n", query)
+	}
+	if query := dbm.labeled("bad\nkey */", "select 1"); query != "-- godbm_bad_key___\nselect 1" {
+		t.Fatalf("expected unsafe characters replaced got %q\n", query)
+	}
+	if fingerprint(dbm.labeled("get", "select 1")) != fingerprint("select 1") {
+		t.Fatalf("expected the label to be ignored when matching statement stats\n")
+	}
+}
@@ -0,0 +1,124 @@
+package godbm
+
+import (
+	"database/sql"
+	"expvar"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// Stats holds the connection pool stats along with godbm level counters.
+type Stats struct {
+	DB           sql.DBStats      // stats from the underlying database/sql pool.
+	Queries      map[string]int64 // calls per registered statement key.
+	AdhocQueries int64            // calls made through Exec and Query.
+	Errors       int64            // calls which returned an error.
+	Statements   int              // number of registered statements.
+	CachedStmts  int              // number of ad-hoc statements in the statement cache.
+	Reconnects   int64            // number of times Connect was called after the first.
+	Breaker      BreakerState     // circuit breaker state, BreakerClosed if none is set.
+	Coalesced    int64            // calls which shared another call's query, see Coalesced.
+	Purged       int64            // rows deleted by retention policies, see RunRetention.
+}
+
+// storeCounters holds the store wide counters reported by Stats.
+type storeCounters struct {
+	adhoc     atomic.Int64
+	errors    atomic.Int64
+	connects  atomic.Int64
+	coalesced atomic.Int64
+	purged    atomic.Int64
+}
+
+// Stats returns a snapshot of the pool and godbm counters.
+func (store *SqlStore) Stats() Stats {
+	stats := Stats{
+		AdhocQueries: store.counters.adhoc.Load(),
+		Errors:       store.counters.errors.Load(),
+		Breaker:      store.BreakerState(),
+		Coalesced:    store.counters.coalesced.Load(),
+		Purged:       store.counters.purged.Load(),
+	}
+	if connects := store.counters.connects.Load(); connects > 1 {
+		stats.Reconnects = connects - 1
+	}
+	if store.db != nil {
+		stats.DB = store.db.Stats()
+	}
+	if store.cache != nil {
+		stats.CachedStmts = store.cache.len()
+	}
+
+	store.RLock()
+	stats.Statements = len(store.queries)
+	stats.Queries = make(map[string]int64, len(store.queries))
+	for key, st := range store.queries {
+		stats.Queries[key] = st.calls.Load()
+	}
+	store.RUnlock()
+	return stats
+}
+
+// StatementStat holds the usage of a registered statement.
+type StatementStat struct {
+	Key        string    // the key the statement is registered under.
+	Calls      int64     // number of times the statement was called.
+	Errors     int64     // number of calls which returned an error.
+	LastUsed   time.Time // when the statement was last called, zero if never.
+	Registered time.Time // when the statement was registered.
+}
+
+// StatementStats returns the usage of every registered statement ordered by key, including
+// those which have never been called.
+func (store *SqlStore) StatementStats() []StatementStat {
+	store.RLock()
+	stats := make([]StatementStat, 0, len(store.queries))
+	for key, st := range store.queries {
+		stat := StatementStat{Key: key, Calls: st.calls.Load(), Errors: st.errors.Load(), Registered: st.registered}
+		if last := st.lastUsed.Load(); last != 0 {
+			stat.LastUsed = time.Unix(0, last)
+		}
+		stats = append(stats, stat)
+	}
+	store.RUnlock()
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Key < stats[j].Key })
+	return stats
+}
+
+// UnusedStatements returns the keys of registered statements which have not been called since
+// the given time, or ever if since is zero, ordered by key.
+func (store *SqlStore) UnusedStatements(since time.Time) []string {
+	var unused []string
+	for _, stat := range store.StatementStats() {
+		if stat.LastUsed.IsZero() || stat.LastUsed.Before(since) {
+			unused = append(unused, stat.Key)
+		}
+	}
+	return unused
+}
+
+// PublishExpvar publishes the store's Stats under name with the expvar package. Like
+// expvar.Publish, it panics if name is already in use.
+func (store *SqlStore) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return store.Stats()
+	}))
+}
+
+// count updates the counters for a finished call of st.
+func (store *SqlStore) count(st *statement, err error) {
+	if st.key == "" {
+		store.counters.adhoc.Add(1)
+	} else {
+		st.calls.Add(1)
+		st.lastUsed.Store(time.Now().UnixNano())
+		if err != nil {
+			st.errors.Add(1)
+		}
+	}
+	if err != nil {
+		store.counters.errors.Add(1)
+	}
+}
@@ -0,0 +1,54 @@
+package godbm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	st := &statement{key: "get", query: "select 1"}
+	dbm.queries = map[string]*statement{"get": st}
+
+	ok := func(ctx context.Context) error { return nil }
+	dbm.run(context.Background(), st, nil, ok)
+	dbm.run(context.Background(), st, nil, ok)
+	dbm.run(context.Background(), &statement{query: "select 2"}, nil, func(ctx context.Context) error {
+		return errors.New("boom")
+	})
+
+	stats := dbm.Stats()
+	if stats.Queries["get"] != 2 || stats.AdhocQueries != 1 || stats.Errors != 1 || stats.Statements != 1 {
+		t.Fatalf("unexpected stats: %+v\n", stats)
+	}
+}
+
+func TestStatementStats(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	used := &statement{key: "used", query: "select 1"}
+	dead := &statement{key: "dead", query: "select 2"}
+	dbm.queries = map[string]*statement{"used": used, "dead": dead}
+
+	start := time.Now()
+	dbm.run(context.Background(), used, nil, func(ctx context.Context) error { return errors.New("boom") })
+
+	stats := dbm.StatementStats()
+	if len(stats) != 2 || stats[0].Key != "dead" || stats[1].Key != "used" {
+		t.Fatalf("expected stats for every statement ordered by key, got %+v\n", stats)
+	}
+	if stats[0].Calls != 0 || !stats[0].LastUsed.IsZero() {
+		t.Fatalf("expected dead to be unused, got %+v\n", stats[0])
+	}
+	if stats[1].Calls != 1 || stats[1].Errors != 1 || stats[1].LastUsed.Before(start) {
+		t.Fatalf("unexpected stats for used: %+v\n", stats[1])
+	}
+
+	if unused := dbm.UnusedStatements(time.Time{}); len(unused) != 1 || unused[0] != "dead" {
+		t.Fatalf("expected only dead to be unused, got %v\n", unused)
+	}
+	if unused := dbm.UnusedStatements(time.Now().Add(time.Minute)); len(unused) != 2 {
+		t.Fatalf("expected both statements to be unused since the future, got %v\n", unused)
+	}
+}
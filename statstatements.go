@@ -0,0 +1,130 @@
+package godbm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// StatementOrder is the column TopStatements orders by, descending.
+type StatementOrder string
+
+const (
+	ByTotalTime  StatementOrder = "total_exec_time"  // time spent executing in total.
+	ByMeanTime   StatementOrder = "mean_exec_time"   // time spent executing per call.
+	ByCalls      StatementOrder = "calls"            // number of calls.
+	ByRows       StatementOrder = "rows"             // rows returned or affected.
+	ByBlocksRead StatementOrder = "shared_blks_read" // shared blocks read from outside the buffer cache.
+)
+
+// StatementStats is a row of pg_stat_statements, matched to the registered statement it
+// belongs to.
+type StatementStats struct {
+	Key        string // the registered statement key, empty if the query matched none.
+	QueryID    int64
+	Query      string
+	Calls      int64
+	Rows       int64
+	TotalTime  time.Duration
+	MeanTime   time.Duration
+	MaxTime    time.Duration
+	BlocksHit  int64 // shared blocks found in the buffer cache.
+	BlocksRead int64 // shared blocks read from outside the buffer cache.
+}
+
+// TopStatements returns up to limit entries of pg_stat_statements for the current database,
+// ordered by orderBy, with each matched back to the registered statement it came from by
+// comparing the sql without comments, case or extra whitespace, so sqlcommenter tags don't
+// prevent a match. Ad-hoc queries have their constants replaced by the server and are
+// returned without a key. Needs the pg_stat_statements extension, postgres 13 or later, and
+// a role allowed to read it, such as pg_read_all_stats.
+func (store *SqlStore) TopStatements(ctx context.Context, orderBy StatementOrder, limit int) ([]StatementStats, error) {
+	switch orderBy {
+	case ByTotalTime, ByMeanTime, ByCalls, ByRows, ByBlocksRead:
+	default:
+		return nil, errors.New("godbm: error unknown statement order " + string(orderBy))
+	}
+
+	store.RLock()
+	keys := make(map[string]string, len(store.queries))
+	for key, st := range store.queries {
+		keys[fingerprint(st.query)] = key
+	}
+	store.RUnlock()
+
+	rows, err := store.QueryContext(ctx, "select queryid, query, calls, rows, total_exec_time, mean_exec_time, max_exec_time, "+
+		"shared_blks_hit, shared_blks_read from pg_stat_statements where dbid = (select oid from pg_database where datname = current_database()) "+
+		"order by "+string(orderBy)+" desc limit $1", limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []StatementStats
+	for rows.Next() {
+		var s StatementStats
+		var total, mean, slowest float64
+		if err := rows.Scan(&s.QueryID, &s.Query, &s.Calls, &s.Rows, &total, &mean, &slowest, &s.BlocksHit, &s.BlocksRead); err != nil {
+			return nil, err
+		}
+		s.TotalTime, s.MeanTime, s.MaxTime = milliseconds(total), milliseconds(mean), milliseconds(slowest)
+		s.Key = keys[fingerprint(s.Query)]
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
+
+// milliseconds converts a duration in fractional milliseconds, as pg_stat_statements reports.
+func milliseconds(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// fingerprint returns query without comments or a trailing semicolon, with whitespace collapsed
+// and everything outside quotes in lower case, to match sql the server reports against
+// registered statements.
+func fingerprint(query string) string {
+	var b strings.Builder
+	space := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := skipQuoted(query, i, c)
+			if end >= len(query) {
+				end = len(query) - 1
+			}
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString(query[i : end+1])
+			i = end
+			space = false
+			continue
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(query)
+			}
+			space = true
+			continue
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			i = skipBlockComment(query, i)
+			space = true
+			continue
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			continue
+		}
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		b.WriteByte(c)
+	}
+	return strings.TrimSuffix(b.String(), ";")
+}
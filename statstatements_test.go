@@ -0,0 +1,24 @@
+package godbm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	registered := "SELECT id,  name\n\tFROM users -- by id\nWHERE id = $1 AND name <> 'Ann  B';"
+	reported := "select id, name from users where id = $1 and name <> 'Ann  B' /*service='api'*/"
+	if fingerprint(registered) != fingerprint(reported) {
+		t.Fatalf("expected a match:\n%s\n%s\n", fingerprint(registered), fingerprint(reported))
+	}
+	if fingerprint(reported) != "select id, name from users where id = $1 and name <> 'Ann  B'" {
+		t.Fatalf("unexpected fingerprint: %s\n", fingerprint(reported))
+	}
+}
+
+func TestTopStatementsOrder(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	if _, err := dbm.TopStatements(context.Background(), StatementOrder("calls; drop table users"), 10); err == nil {
+		t.Fatalf("expected an error for an unknown order\n")
+	}
+}
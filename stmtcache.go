@@ -0,0 +1,110 @@
+package godbm
+
+import (
+	"container/list"
+	"database/sql"
+	"sync"
+)
+
+// SetStatementCacheSize makes Exec and Query keep up to size prepared statements, keyed by
+// their sql, instead of preparing and closing a statement on every call. The least recently
+// used statement is closed once the cache is full. Zero disables the cache. Must be called
+// before Connect.
+func (store *SqlStore) SetStatementCacheSize(size int) {
+	if size <= 0 {
+		store.cache = nil
+		return
+	}
+	store.cache = &stmtCache{size: size, ll: list.New(), items: make(map[string]*list.Element), close: (*sql.Stmt).Close}
+}
+
+// stmtCache is an LRU cache of prepared statements keyed by sql.
+type stmtCache struct {
+	mu    sync.Mutex
+	size  int                      // maximum number of statements
+	ll    *list.List               // most recently used at the front
+	items map[string]*list.Element // elements by sql
+	close func(*sql.Stmt) error    // closes evicted statements
+}
+
+// cachedStmt is a statement in the cache. It is only closed once evicted and no longer in use.
+type cachedStmt struct {
+	query   string
+	stmt    *sql.Stmt
+	users   int  // callers currently using the statement
+	evicted bool // removed from the cache, close when users drops to zero
+}
+
+// acquire returns the cached statement for query, preparing it on a miss. The statement must
+// be returned with release.
+func (c *stmtCache) acquire(query string, prepare func(query string) (*sql.Stmt, error)) (*cachedStmt, error) {
+	c.mu.Lock()
+	if e, ok := c.items[query]; ok {
+		c.ll.MoveToFront(e)
+		cs := e.Value.(*cachedStmt)
+		cs.users++
+		c.mu.Unlock()
+		return cs, nil
+	}
+	c.mu.Unlock()
+
+	stmt, err := prepare(query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// another caller may have prepared the same query in the meantime.
+	if e, ok := c.items[query]; ok {
+		c.close(stmt)
+		c.ll.MoveToFront(e)
+		cs := e.Value.(*cachedStmt)
+		cs.users++
+		return cs, nil
+	}
+
+	cs := &cachedStmt{query: query, stmt: stmt, users: 1}
+	c.items[query] = c.ll.PushFront(cs)
+	for c.ll.Len() > c.size {
+		c.evict(c.ll.Back())
+	}
+	return cs, nil
+}
+
+// release returns a statement obtained from acquire.
+func (c *stmtCache) release(cs *cachedStmt) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cs.users--
+	if cs.evicted && cs.users == 0 {
+		c.close(cs.stmt)
+	}
+}
+
+// evict removes the element, closing its statement if it is not in use. Must be called with
+// mu held.
+func (c *stmtCache) evict(e *list.Element) {
+	cs := c.ll.Remove(e).(*cachedStmt)
+	delete(c.items, cs.query)
+	cs.evicted = true
+	if cs.users == 0 {
+		c.close(cs.stmt)
+	}
+}
+
+// len returns the number of cached statements.
+func (c *stmtCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// clear evicts every statement.
+func (c *stmtCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.ll.Len() > 0 {
+		c.evict(c.ll.Back())
+	}
+}
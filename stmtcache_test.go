@@ -0,0 +1,35 @@
+package godbm
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestStmtCache(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.SetStatementCacheSize(2)
+	dbm.cache.close = func(*sql.Stmt) error { return nil }
+
+	prepared := 0
+	prepare := func(query string) (*sql.Stmt, error) {
+		prepared++
+		return &sql.Stmt{}, nil
+	}
+
+	a, _ := dbm.cache.acquire("select 1", prepare)
+	dbm.cache.release(a)
+	b, _ := dbm.cache.acquire("select 2", prepare)
+	dbm.cache.release(b)
+	if again, _ := dbm.cache.acquire("select 1", prepare); again != a {
+		t.Fatalf("expected cached statement to be reused\n")
+	} else {
+		dbm.cache.release(again)
+	}
+
+	// select 2 is now the least recently used.
+	c, _ := dbm.cache.acquire("select 3", prepare)
+	dbm.cache.release(c)
+	if !b.evicted || a.evicted || prepared != 3 || dbm.cache.len() != 2 {
+		t.Fatalf("expected least recently used statement to be evicted, prepared %d\n", prepared)
+	}
+}
@@ -0,0 +1,223 @@
+package godbm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// errNotStructPointer is returned when a struct helper isn't passed a pointer to a struct.
+var errNotStructPointer = errors.New("godbm: error expected a pointer to a struct")
+
+// structField maps a struct field to a column.
+type structField struct {
+	column    string // column name from the db tag, or the lower cased field name.
+	index     []int  // index of the field for reflect.Value.FieldByIndex.
+	omitEmpty bool   // not written when zero, so the column default applies, and read back.
+	readOnly  bool   // never written, always read back, such as generated columns.
+	encrypted bool   // encrypted with the store's codec, see SetEncryption.
+}
+
+// structFields caches the fields of struct types.
+var structFields sync.Map
+
+// fieldsOf returns the column mapping of the struct type t. Fields are mapped with a db tag of
+// the form `db:"name,omitempty,readonly,encrypted"`, untagged exported fields use their lower
+// cased name and `db:"-"` skips a field. Embedded structs are flattened. Encrypted fields must
+// be strings, []byte or *string and are always written, ignoring omitempty.
+func fieldsOf(t reflect.Type) []structField {
+	if cached, ok := structFields.Load(t); ok {
+		return cached.([]structField)
+	}
+	fields := appendFields(nil, t, nil)
+	structFields.Store(t, fields)
+	return fields
+}
+
+// appendFields appends the fields of t, whose index is prefixed by index, to fields.
+func appendFields(fields []structField, t reflect.Type, index []int) []structField {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, hasTag := f.Tag.Lookup("db")
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		fieldIndex := append(append([]int(nil), index...), i)
+		if f.Anonymous && !hasTag && f.Type.Kind() == reflect.Struct {
+			fields = appendFields(fields, f.Type, fieldIndex)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+
+		opts := strings.Split(tag, ",")
+		field := structField{column: opts[0], index: fieldIndex}
+		if field.column == "" {
+			field.column = strings.ToLower(f.Name)
+		}
+		for _, opt := range opts[1:] {
+			switch opt {
+			case "omitempty":
+				field.omitEmpty = true
+			case "readonly":
+				field.readOnly = true
+			case "encrypted":
+				field.encrypted = true
+			}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// arg returns the argument for the field's value, marked for encryption if the field is
+// encrypted.
+func (f structField) arg(value reflect.Value) interface{} {
+	if f.encrypted {
+		return encryptedArg{column: f.column, value: value.Interface()}
+	}
+	return value.Interface()
+}
+
+// structValue returns the struct v points to.
+func structValue(v interface{}) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, errNotStructPointer
+	}
+	return rv.Elem(), nil
+}
+
+// InsertStruct inserts the struct v points to into table, mapping fields to columns with db
+// tags, see fieldsOf. Fields tagged readonly, or omitempty and zero, are left to the column
+// defaults and read back into the struct with RETURNING, so generated ids and timestamps are
+// filled in.
+//
+//	type User struct {
+//		ID      int64     `db:"id,omitempty"`
+//		Email   string    `db:"email"`
+//		Created time.Time `db:"created_at,readonly"`
+//	}
+func (store *SqlStore) InsertStruct(table string, v interface{}) error {
+	return store.InsertStructContext(context.Background(), table, v)
+}
+
+// InsertStructContext is the same as InsertStruct but takes a context, which may carry a
+// transaction started by WithTransaction.
+func (store *SqlStore) InsertStructContext(ctx context.Context, table string, v interface{}) error {
+	rv, err := structValue(v)
+	if err != nil {
+		return err
+	}
+
+	b := Insert(table)
+	var returning []string
+	var dest []interface{}
+	for _, f := range fieldsOf(rv.Type()) {
+		value := rv.FieldByIndex(f.index)
+		if f.readOnly || (f.omitEmpty && !f.encrypted && value.IsZero()) {
+			returning = append(returning, f.column)
+			dest = append(dest, value.Addr().Interface())
+			continue
+		}
+		b.Set(f.column, f.arg(value))
+	}
+
+	query, args := b.Returning(returning...).Build()
+	_, err = store.execReturning(ctx, query, args, dest)
+	return err
+}
+
+// UpdateStruct updates the rows of table matching the struct v points to on whereCols, setting
+// every other mapped column except those tagged readonly, or omitempty and zero. Readonly
+// columns are read back into the struct with RETURNING. Returns the number of rows updated.
+func (store *SqlStore) UpdateStruct(table string, v interface{}, whereCols ...string) (int64, error) {
+	return store.UpdateStructContext(context.Background(), table, v, whereCols...)
+}
+
+// UpdateStructContext is the same as UpdateStruct but takes a context, which may carry a
+// transaction started by WithTransaction.
+func (store *SqlStore) UpdateStructContext(ctx context.Context, table string, v interface{}, whereCols ...string) (int64, error) {
+	b, dest, err := updateStruct(table, v, whereCols)
+	if err != nil {
+		return 0, err
+	}
+	query, args := b.Build()
+	return store.execReturning(ctx, query, args, dest)
+}
+
+// updateStruct builds the update for UpdateStruct, returning the destinations for the
+// RETURNING columns.
+func updateStruct(table string, v interface{}, whereCols []string) (*UpdateBuilder, []interface{}, error) {
+	rv, err := structValue(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(whereCols) == 0 {
+		return nil, nil, errors.New("godbm: error UpdateStruct requires where columns")
+	}
+
+	where := make(map[string]bool, len(whereCols))
+	for _, col := range whereCols {
+		where[col] = true
+	}
+
+	b := Update(table)
+	var returning []string
+	var dest []interface{}
+	found := 0
+	for _, f := range fieldsOf(rv.Type()) {
+		value := rv.FieldByIndex(f.index)
+		switch {
+		case where[f.column]:
+			b.WhereEq(f.column, f.arg(value))
+			found++
+		case f.readOnly:
+			returning = append(returning, f.column)
+			dest = append(dest, value.Addr().Interface())
+		case f.omitEmpty && !f.encrypted && value.IsZero():
+		default:
+			b.Set(f.column, f.arg(value))
+		}
+	}
+	if found != len(where) {
+		return nil, nil, errors.New("godbm: error UpdateStruct where columns must be fields of the struct")
+	}
+	if len(b.columns) == 0 {
+		return nil, nil, errors.New("godbm: error UpdateStruct has no columns to set")
+	}
+	return b.Returning(returning...), dest, nil
+}
+
+// execReturning executes query, scanning the RETURNING columns into dest if there are any,
+// and returns the number of rows affected.
+func (store *SqlStore) execReturning(ctx context.Context, query string, args []interface{}, dest []interface{}) (int64, error) {
+	if len(dest) == 0 {
+		result, err := store.ExecContext(ctx, query, args...)
+		if err != nil {
+			return 0, err
+		}
+		return result.RowsAffected()
+	}
+
+	if err := store.checkWrite(); err != nil {
+		return 0, err
+	}
+	rows, err := store.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var n int64
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
@@ -0,0 +1,60 @@
+package godbm
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type audited struct {
+	Created time.Time `db:"created_at,readonly"`
+}
+
+type structUser struct {
+	ID    int64  `db:"id,omitempty"`
+	Email string `db:"email"`
+	Name  string
+	Notes string `db:"-"`
+	audited
+	secret string
+}
+
+func TestFieldsOf(t *testing.T) {
+	fields := fieldsOf(reflect.TypeOf(structUser{}))
+	var columns []string
+	for _, f := range fields {
+		columns = append(columns, f.column)
+	}
+	if !reflect.DeepEqual(columns, []string{"id", "email", "name", "created_at"}) {
+		t.Fatalf("unexpected columns: %v\n", columns)
+	}
+	if !fields[0].omitEmpty || !fields[3].readOnly || !reflect.DeepEqual(fields[3].index, []int{4, 0}) {
+		t.Fatalf("unexpected field options: %+v\n", fields)
+	}
+}
+
+func TestUpdateStruct(t *testing.T) {
+	u := &structUser{ID: 7, Email: "a@example.com"}
+	b, dest, err := updateStruct("users", u, []string{"id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, args := b.Build()
+	want := `update "users" set "email" = $1, "name" = $2 where ("id" = $3) returning "created_at"`
+	if query != want || !reflect.DeepEqual(args, []interface{}{"a@example.com", "", int64(7)}) {
+		t.Fatalf("unexpected update:\n%s\n%s\n%v\n", query, want, args)
+	}
+	if len(dest) != 1 || dest[0] != &u.Created {
+		t.Fatalf("expected created_at to be read back into the struct\n")
+	}
+
+	if _, _, err := updateStruct("users", u, nil); err == nil {
+		t.Fatalf("expected an error without where columns\n")
+	}
+	if _, _, err := updateStruct("users", u, []string{"missing"}); err == nil {
+		t.Fatalf("expected an error for unknown where columns\n")
+	}
+	if _, _, err := updateStruct("users", *u, []string{"id"}); err != errNotStructPointer {
+		t.Fatalf("expected an error for a struct value, got %v\n", err)
+	}
+}
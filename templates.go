@@ -0,0 +1,58 @@
+package godbm
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs are the functions available to templates rendered by PrepareAddTemplate.
+var templateFuncs = template.FuncMap{
+	"ident":     QuoteIdentifier,
+	"qualified": QuoteQualified,
+	"literal":   QuoteLiteral,
+	"params":    placeholders,
+}
+
+// PrepareAddTemplate renders tmpl with text/template and data, then registers the result under
+// key as PrepareAddWithOptions does. It is for sql whose static shape varies, such as an
+// optional join or an IN list of known length, which would otherwise need a near duplicate
+// statement per variant. The template is rendered once, values which change per call are still
+// passed as arguments.
+//
+// Besides the text/template builtins, templates may call ident, qualified and literal to quote
+// names and constants, and params n start which expands to n numbered placeholders from $start,
+// so "in ({{params 3 2}})" renders as "in ($2, $3, $4)". Missing map keys are an error.
+func (store *SqlStore) PrepareAddTemplate(key, tmpl string, data interface{}, opts ...StmtOption) error {
+	query, err := renderTemplate(key, tmpl, data)
+	if err != nil {
+		return err
+	}
+	return store.PrepareAddWithOptions(key, query, opts...)
+}
+
+// renderTemplate renders the sql template tmpl named key with data.
+func renderTemplate(key, tmpl string, data interface{}) (string, error) {
+	t, err := template.New(key).Funcs(templateFuncs).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// placeholders returns n comma separated placeholders numbered from start.
+func placeholders(n, start int) (string, error) {
+	if n < 1 || start < 1 {
+		return "", errors.New("godbm: error params needs a positive count and start")
+	}
+	params := make([]string, n)
+	for i := range params {
+		params[i] = "$" + strconv.Itoa(start+i)
+	}
+	return strings.Join(params, ", "), nil
+}
@@ -0,0 +1,26 @@
+package godbm
+
+import "testing"
+
+func TestRenderTemplate(t *testing.T) {
+	tmpl := `select u.id from {{qualified .Table}} u{{if .Orgs}} join orgs o on o.id = u.org_id{{end}} where u.id in ({{params .IDs 2}}) and u.kind = {{literal .Kind}} and u.name = $1`
+	data := map[string]interface{}{"Table": "app.users", "Orgs": true, "IDs": 3, "Kind": "it's"}
+	query, err := renderTemplate("users", tmpl, data)
+	if err != nil {
+		t.Fatalf("error rendering: %v\n", err)
+	}
+	expected := `select u.id from "app"."users" u join orgs o on o.id = u.org_id where u.id in ($2, $3, $4) and u.kind = 'it''s' and u.name = $1`
+	if query != expected {
+		t.Fatalf("unexpected query: %s\n", query)
+	}
+	if countParams(query) != 4 {
+		t.Fatalf("expected 4 params got %d\n", countParams(query))
+	}
+
+	if _, err := renderTemplate("users", "select {{.Missing}}", map[string]interface{}{}); err == nil {
+		t.Fatalf("expected an error for a missing key\n")
+	}
+	if _, err := renderTemplate("users", "select {{params 0 1}}", nil); err == nil {
+		t.Fatalf("expected an error for zero params\n")
+	}
+}
@@ -0,0 +1,126 @@
+package godbm
+
+import (
+	"context"
+	"database/sql"
+	"github.com/lib/pq"
+)
+
+// Tenant is a handle to the store scoped to one tenant's schema for schema-per-tenant
+// databases. Statements called through it run with search_path set to the tenant's schema,
+// which postgres applies with SET LOCAL semantics so pooled connections are never left pointing
+// at another tenant. Statements may be registered for a single tenant with its PrepareAdd,
+// otherwise the store's statements are used.
+type Tenant struct {
+	store  *SqlStore
+	name   string
+	schema string // quoted search_path value
+}
+
+// ForTenant returns a handle scoped to the schema named tenant, followed by public so shared
+// tables and extensions still resolve.
+func (store *SqlStore) ForTenant(tenant string) *Tenant {
+	return &Tenant{store: store, name: tenant, schema: pq.QuoteIdentifier(tenant) + ", public"}
+}
+
+// Name returns the tenant the handle is scoped to.
+func (t *Tenant) Name() string {
+	return t.name
+}
+
+// tenantKey returns the key a tenant's own statement is registered under.
+func (t *Tenant) tenantKey(key string) string {
+	return "tenant:" + t.name + ":" + key
+}
+
+// PrepareAdd registers a statement only callable through this tenant's handle, which takes
+// precedence over a store statement with the same key.
+func (t *Tenant) PrepareAdd(key, query string) error {
+	return t.store.PrepareAdd(t.tenantKey(key), query)
+}
+
+// PrepareAddWithOptions is the same as PrepareAdd but applies the supplied options.
+func (t *Tenant) PrepareAddWithOptions(key, query string, opts ...StmtOption) error {
+	return t.store.PrepareAddWithOptions(t.tenantKey(key), query, opts...)
+}
+
+// HasStatement reports whether key can be called through this tenant's handle.
+func (t *Tenant) HasStatement(key string) bool {
+	_, err := t.lookup(key)
+	return err == nil
+}
+
+// lookup returns the tenant's statement for key, falling back to the store's.
+func (t *Tenant) lookup(key string) (*statement, error) {
+	if st, err := t.store.lookup(t.tenantKey(key)); err == nil {
+		return st, nil
+	}
+	return t.store.lookup(key)
+}
+
+// bind scopes ctx to the tenant. If ctx carries a transaction its search_path is switched to
+// the tenant's schema, otherwise the schema is applied to transactions begun with the context.
+func (t *Tenant) bind(ctx context.Context) (context.Context, error) {
+	tx, ok := TxFromContext(ctx)
+	if !ok {
+		return WithSessionSettings(ctx, map[string]string{"search_path": t.schema}), nil
+	}
+	if tx.searchPath != t.schema {
+		if err := tx.SetLocalContext(ctx, "search_path", t.schema); err != nil {
+			return ctx, err
+		}
+	}
+	return ctx, nil
+}
+
+// WithTransaction is the same as SqlStore.WithTransaction but with the transaction scoped to
+// the tenant. Calls made through the tenant handle with the context passed to fn run inside
+// the transaction.
+func (t *Tenant) WithTransaction(ctx context.Context, fn func(ctx context.Context, tx *Tx) error) error {
+	ctx, err := t.bind(ctx)
+	if err != nil {
+		return err
+	}
+	return t.store.WithTransaction(ctx, func(ctx context.Context, tx *Tx) error {
+		ctx, err := t.bind(ctx)
+		if err != nil {
+			return err
+		}
+		return fn(ctx, tx)
+	})
+}
+
+// QueryPreparedContext queries the statement registered under key in the tenant's schema. As
+// the rows outlive the call it must be made within a transaction, see WithTransaction,
+// otherwise ErrSessionSettingsNeedTx is returned.
+func (t *Tenant) QueryPreparedContext(ctx context.Context, key string, data ...interface{}) (*sql.Rows, error) {
+	if !t.store.Connected {
+		return nil, &ConnectionError{}
+	}
+
+	st, err := t.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	if ctx, err = t.bind(ctx); err != nil {
+		return nil, err
+	}
+	return t.store.queryStatement(ctx, st, data...)
+}
+
+// ExecPreparedContext executes the statement registered under key in the tenant's schema.
+// Outside of a transaction the statement runs in its own.
+func (t *Tenant) ExecPreparedContext(ctx context.Context, key string, data ...interface{}) (sql.Result, error) {
+	if !t.store.Connected {
+		return nil, &ConnectionError{}
+	}
+
+	st, err := t.lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	if ctx, err = t.bind(ctx); err != nil {
+		return nil, err
+	}
+	return t.store.execStatement(ctx, st, data...)
+}
@@ -0,0 +1,26 @@
+package godbm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTenantBind(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	tenant := dbm.ForTenant(`acme"corp`)
+
+	if tenant.schema != `"acme""corp", public` {
+		t.Fatalf("expected the schema to be quoted, got: %s\n", tenant.schema)
+	}
+	if tenant.tenantKey("get") != `tenant:acme"corp:get` {
+		t.Fatalf("unexpected tenant key: %s\n", tenant.tenantKey("get"))
+	}
+
+	ctx, err := tenant.bind(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sessionSettings(ctx)["search_path"] != tenant.schema {
+		t.Fatalf("expected the search_path to be carried by the context\n")
+	}
+}
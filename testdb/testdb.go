@@ -0,0 +1,321 @@
+// Package testdb provides disposable postgres databases for tests. Each test gets a fresh
+// database, migrated and loaded with fixtures, which is dropped when the test ends.
+//
+// The server is either one given in Options.Server, such as a CI service container, or a
+// container started with the docker CLI and shared by every test in the package. Tests are
+// skipped when neither is available.
+package testdb
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/wirepair/godbm"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Options configures the databases created by New.
+type Options struct {
+	// Server is an existing server to create databases on, its Database is only used to
+	// connect for creating and dropping them. If nil the GODBM_TEST_HOST, GODBM_TEST_USER and
+	// GODBM_TEST_PASSWORD environment variables are used if set, otherwise a container is
+	// started.
+	Server *godbm.Config
+
+	// Migrate optionally applies the schema to each new database.
+	Migrate func(ctx context.Context, store *godbm.SqlStore) error
+
+	Image    string        // image of the container, defaults to postgres:16-alpine.
+	Fixtures fs.FS         // optional .sql scripts run in name order after Migrate.
+	Timeout  time.Duration // time allowed for setup, defaults to a minute.
+}
+
+// New creates a fresh database for the test and returns a connected store for it. The
+// statement directories of Options.Server are prepared once migrations have run. The store is
+// disconnected and the database dropped by t.Cleanup.
+func New(t testing.TB, opts *Options) *godbm.SqlStore {
+	t.Helper()
+	if opts == nil {
+		opts = &Options{}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout(opts))
+	defer cancel()
+
+	server := serverConfig(t, opts)
+	name := "godbm_test_" + randomSuffix(t)
+	if err := createDatabase(ctx, server, name, ""); err != nil {
+		t.Fatalf("testdb: error creating database: %v\n", err)
+	}
+	t.Cleanup(func() { dropDatabase(server, name) })
+
+	store := connect(ctx, t, server, name)
+	if err := setup(ctx, store, opts); err != nil {
+		t.Fatalf("testdb: error setting up database: %v\n", err)
+	}
+	prepare(t, store, server)
+	return store
+}
+
+// NewFromTemplate is like New but migrates and loads the fixtures only once, into a template
+// database shared by every call with the same opts. Each test then gets a copy made with
+// CREATE DATABASE ... TEMPLATE, which is much faster than migrating again. The template is
+// dropped by Stop.
+func NewFromTemplate(t testing.TB, opts *Options) *godbm.SqlStore {
+	t.Helper()
+	if opts == nil {
+		opts = defaultOptions
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout(opts))
+	defer cancel()
+
+	server := serverConfig(t, opts)
+	source, err := templateFor(ctx, t, server, opts)
+	if err != nil {
+		t.Fatalf("testdb: error creating template: %v\n", err)
+	}
+	name := "godbm_test_" + randomSuffix(t)
+	if err := createDatabase(ctx, server, name, source); err != nil {
+		t.Fatalf("testdb: error creating database: %v\n", err)
+	}
+	t.Cleanup(func() { dropDatabase(server, name) })
+
+	store := connect(ctx, t, server, name)
+	prepare(t, store, server)
+	return store
+}
+
+// defaultOptions is used by NewFromTemplate when opts is nil, so those calls share a template.
+var defaultOptions = &Options{}
+
+// template is a migrated database copied by NewFromTemplate.
+type template struct {
+	once   sync.Once
+	server godbm.Config
+	name   string
+	err    error
+}
+
+// templates holds a *template for each *Options passed to NewFromTemplate.
+var templates sync.Map
+
+// templateFor returns the name of the template database for opts, creating it the first time.
+func templateFor(ctx context.Context, t testing.TB, server godbm.Config, opts *Options) (string, error) {
+	v, _ := templates.LoadOrStore(opts, &template{})
+	tmpl := v.(*template)
+	tmpl.once.Do(func() {
+		tmpl.server = server
+		tmpl.name = "godbm_template_" + randomSuffix(t)
+		if tmpl.err = createDatabase(ctx, server, tmpl.name, ""); tmpl.err != nil {
+			return
+		}
+		if tmpl.err = migrateTemplate(ctx, server, tmpl.name, opts); tmpl.err != nil {
+			dropDatabase(server, tmpl.name)
+		}
+	})
+	return tmpl.name, tmpl.err
+}
+
+// migrateTemplate runs the migrations and fixtures on the template name. It disconnects when
+// done as a database can't be copied while anyone is connected to it.
+func migrateTemplate(ctx context.Context, server godbm.Config, name string, opts *Options) error {
+	cfg := server
+	cfg.Database = name
+	cfg.StatementDirs = nil
+	store := godbm.NewFromConfig(cfg)
+	if err := store.WaitUntilReady(ctx, nil); err != nil {
+		return err
+	}
+	defer store.Disconnect()
+	return setup(ctx, store, opts)
+}
+
+// timeout returns the time allowed for setup.
+func timeout(opts *Options) time.Duration {
+	if opts.Timeout > 0 {
+		return opts.Timeout
+	}
+	return time.Minute
+}
+
+// serverConfig returns the server to create databases on, starting a container if needed.
+func serverConfig(t testing.TB, opts *Options) godbm.Config {
+	t.Helper()
+	if opts.Server != nil {
+		return *opts.Server
+	}
+	if host := os.Getenv("GODBM_TEST_HOST"); host != "" {
+		return godbm.Config{Host: host, User: os.Getenv("GODBM_TEST_USER"), Password: os.Getenv("GODBM_TEST_PASSWORD"), Database: "postgres", SSLMode: "disable"}
+	}
+	c, err := startContainer(opts.Image)
+	if err != nil {
+		t.Skipf("testdb: no server configured and no container could be started: %v\n", err)
+	}
+	return c
+}
+
+// connect connects to the database name on server, waiting for it to accept connections.
+func connect(ctx context.Context, t testing.TB, server godbm.Config, name string) *godbm.SqlStore {
+	t.Helper()
+	cfg := server
+	cfg.Database = name
+	cfg.StatementDirs = nil
+	store := godbm.NewFromConfig(cfg)
+	if err := store.WaitUntilReady(ctx, nil); err != nil {
+		t.Fatalf("testdb: error connecting: %v\n", err)
+	}
+	// registered before the database is dropped so cleanups run in the right order.
+	t.Cleanup(func() { store.Disconnect() })
+	return store
+}
+
+// setup runs the migrations and fixtures.
+func setup(ctx context.Context, store *godbm.SqlStore, opts *Options) error {
+	if opts.Migrate != nil {
+		if err := opts.Migrate(ctx, store); err != nil {
+			return err
+		}
+	}
+	if opts.Fixtures == nil {
+		return nil
+	}
+
+	var scripts []string
+	err := fs.WalkDir(opts.Fixtures, ".", func(name string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && path.Ext(name) == ".sql" {
+			scripts = append(scripts, name)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(scripts)
+	for _, name := range scripts {
+		script, err := fs.ReadFile(opts.Fixtures, name)
+		if err != nil {
+			return err
+		}
+		if err := store.ExecScript(ctx, bytes.NewReader(script)); err != nil {
+			return errors.New(name + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+// prepare prepares the server's statement directories.
+func prepare(t testing.TB, store *godbm.SqlStore, server godbm.Config) {
+	t.Helper()
+	for _, dir := range server.StatementDirs {
+		if err := store.PrepareFS(os.DirFS(dir)); err != nil {
+			t.Fatalf("testdb: error preparing %s: %v\n", dir, err)
+		}
+	}
+}
+
+// createDatabase creates the database name on server, as a copy of template if given.
+func createDatabase(ctx context.Context, server godbm.Config, name, template string) error {
+	query := "create database %I"
+	args := []interface{}{name}
+	if template != "" {
+		query += " template %I"
+		args = append(args, template)
+	}
+	return admin(ctx, server, query, args...)
+}
+
+// dropDatabase drops the database name, disconnecting anyone still using it, which needs
+// postgres 13 or later.
+func dropDatabase(server godbm.Config, name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return admin(ctx, server, "drop database if exists %I with (force)", name)
+}
+
+// admin runs a statement formatted with godbm.Format on server's own database.
+func admin(ctx context.Context, server godbm.Config, template string, args ...interface{}) error {
+	query, err := godbm.Format(template, args...)
+	if err != nil {
+		return err
+	}
+	server.StatementDirs = nil
+	store := godbm.NewFromConfig(server)
+	if err := store.WaitUntilReady(ctx, nil); err != nil {
+		return err
+	}
+	defer store.Disconnect()
+	_, err = store.ExecContext(ctx, query)
+	return err
+}
+
+// randomSuffix returns a random database name suffix.
+func randomSuffix(t testing.TB) string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatalf("testdb: error generating name: %v\n", err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// container is the server started by startContainer, shared by every test in the process.
+var container struct {
+	once sync.Once
+	id   string
+	cfg  godbm.Config
+	err  error
+}
+
+// containerPassword is the superuser password of started containers.
+const containerPassword = "godbm"
+
+// startContainer starts a postgres container with the docker CLI the first time it is called.
+// It is started with --rm so stopping it, as Stop does, also removes it.
+func startContainer(image string) (godbm.Config, error) {
+	container.once.Do(func() {
+		if image == "" {
+			image = "postgres:16-alpine"
+		}
+		out, err := exec.Command("docker", "run", "-d", "--rm", "-e", "POSTGRES_PASSWORD="+containerPassword,
+			"-p", "127.0.0.1::5432", image).Output()
+		if err != nil {
+			container.err = err
+			return
+		}
+		id := strings.TrimSpace(string(out))
+		out, err = exec.Command("docker", "port", id, "5432/tcp").Output()
+		if err != nil {
+			exec.Command("docker", "rm", "-f", id).Run()
+			container.err = err
+			return
+		}
+		// the first line is the IPv4 binding, such as 127.0.0.1:49153.
+		host := strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+		container.id = id
+		container.cfg = godbm.Config{Host: host, User: "postgres", Password: containerPassword, Database: "postgres", SSLMode: "disable"}
+	})
+	return container.cfg, container.err
+}
+
+// Stop drops the templates created by NewFromTemplate and removes the container started for
+// the tests, if any. Call it from TestMain after m.Run, otherwise the container is left running
+// until the docker daemon stops.
+func Stop() {
+	templates.Range(func(_, v interface{}) bool {
+		if tmpl := v.(*template); tmpl.name != "" && tmpl.err == nil {
+			dropDatabase(tmpl.server, tmpl.name)
+		}
+		return true
+	})
+	if container.id != "" {
+		exec.Command("docker", "rm", "-f", container.id).Run()
+	}
+}
@@ -0,0 +1,66 @@
+package testdb
+
+import (
+	"context"
+	"errors"
+	"github.com/wirepair/godbm"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// testOptions returns options for the local test server, skipping the test if it isn't up.
+func testOptions(t *testing.T) *Options {
+	server := &godbm.Config{Host: "127.0.0.1", User: "postgres", Password: "testpass", Database: "postgres", SSLMode: "disable"}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := admin(ctx, *server, "select 1"); err != nil {
+		t.Skipf("no test server: %v\n", err)
+	}
+	return &Options{
+		Server: server,
+		Migrate: func(ctx context.Context, store *godbm.SqlStore) error {
+			_, err := store.ExecContext(ctx, "create table users (id int primary key, name text)")
+			return err
+		},
+		Fixtures: fstest.MapFS{"01_users.sql": {Data: []byte("insert into users values (1, 'ann');\ninsert into users values (2, 'bob');")}},
+	}
+}
+
+func countUsers(t *testing.T, store *godbm.SqlStore) int {
+	var count int
+	if err := store.Db().QueryRow("select count(*) from users").Scan(&count); err != nil {
+		t.Fatalf("error counting users: %v\n", err)
+	}
+	return count
+}
+
+func TestNew(t *testing.T) {
+	store := New(t, testOptions(t))
+	if count := countUsers(t, store); count != 2 {
+		t.Fatalf("expected 2 users from the fixtures got %d\n", count)
+	}
+}
+
+func TestNewFromTemplate(t *testing.T) {
+	opts := testOptions(t)
+	defer Stop()
+	first := NewFromTemplate(t, opts)
+	if _, err := first.Db().Exec("insert into users values (3, 'cy')"); err != nil {
+		t.Fatalf("error inserting: %v\n", err)
+	}
+	second := NewFromTemplate(t, opts)
+	if count := countUsers(t, second); count != 2 {
+		t.Fatalf("expected the copy to be isolated with 2 users got %d\n", count)
+	}
+	if count := countUsers(t, first); count != 3 {
+		t.Fatalf("expected 3 users got %d\n", count)
+	}
+}
+
+func TestSetupFixtureError(t *testing.T) {
+	opts := &Options{Migrate: func(ctx context.Context, store *godbm.SqlStore) error { return errors.New("boom") }}
+	if err := setup(context.Background(), nil, opts); err == nil || err.Error() != "boom" {
+		t.Fatalf("expected the migration error, got %v\n", err)
+	}
+}
@@ -0,0 +1,94 @@
+package godbm
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+)
+
+// Timeouts holds the postgres session timeouts which stop runaway queries and abandoned
+// transactions from holding locks indefinitely. Zero values leave the server default in place.
+type Timeouts struct {
+	Statement         time.Duration // statement_timeout
+	Lock              time.Duration // lock_timeout
+	IdleInTransaction time.Duration // idle_in_transaction_session_timeout
+}
+
+// settings returns the timeouts as postgres setting names and millisecond values. Values are
+// rounded up, as postgres takes a timeout of zero to mean none.
+func (t Timeouts) settings() [][2]string {
+	var settings [][2]string
+	add := func(name string, d time.Duration) {
+		if d > 0 {
+			ms := (d + time.Millisecond - 1) / time.Millisecond
+			settings = append(settings, [2]string{name, strconv.FormatInt(int64(ms), 10)})
+		}
+	}
+	add("statement_timeout", t.Statement)
+	add("lock_timeout", t.Lock)
+	add("idle_in_transaction_session_timeout", t.IdleInTransaction)
+	return settings
+}
+
+// SetTimeouts sets the session timeouts used by every connection the store opens. Must be
+// called before Connect.
+func (store *SqlStore) SetTimeouts(t Timeouts) {
+	store.timeouts = t
+}
+
+// ApplyTimeouts overrides the session timeouts for the remainder of the transaction using
+// SET LOCAL semantics.
+func ApplyTimeouts(ctx context.Context, txn *sql.Tx, t Timeouts) error {
+	for _, setting := range t.settings() {
+		if _, err := txn.ExecContext(ctx, "select set_config($1, $2, true)", setting[0], setting[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type callTimeoutsKey struct{}
+
+// WithCallTimeouts returns a context which overrides the session timeouts for ExecPreparedContext
+// calls made with it. The statement is executed in its own transaction so the override only
+// applies to that call, or if the context carries a transaction, for the rest of it. Queries
+// are not affected as their rows outlive the call, use ApplyTimeouts inside a transaction
+// instead.
+func WithCallTimeouts(ctx context.Context, t Timeouts) context.Context {
+	return context.WithValue(ctx, callTimeoutsKey{}, t)
+}
+
+// callTimeouts returns the timeouts set with WithCallTimeouts, if any.
+func callTimeouts(ctx context.Context) (Timeouts, bool) {
+	t, ok := ctx.Value(callTimeoutsKey{}).(Timeouts)
+	return t, ok
+}
+
+// execWithTimeouts executes st in a transaction with the timeouts applied. If the context
+// already carries a transaction the timeouts are applied to it instead.
+func (store *SqlStore) execWithTimeouts(ctx context.Context, t Timeouts, st *statement, data ...interface{}) (sql.Result, error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		if err := ApplyTimeouts(ctx, tx.Tx, t); err != nil {
+			return nil, err
+		}
+		return store.runnerOn(ctx, tx.Tx, st).ExecContext(ctx, data...)
+	}
+
+	txn, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ApplyTimeouts(ctx, txn, t); err != nil {
+		txn.Rollback()
+		return nil, err
+	}
+
+	result, err := store.runnerOn(ctx, txn, st).ExecContext(ctx, data...)
+	if err != nil {
+		txn.Rollback()
+		return nil, err
+	}
+	return result, txn.Commit()
+}
@@ -0,0 +1,28 @@
+package godbm
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeoutsDSN(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.SetTimeouts(Timeouts{Statement: 2 * time.Second, Lock: 500 * time.Millisecond})
+
+	dsn := dbm.dsn()
+	if !strings.Contains(dsn, "statement_timeout=2000") || !strings.Contains(dsn, "lock_timeout=500") {
+		t.Fatalf("expected timeouts in dsn, got: %s\n", dsn)
+	}
+
+	if strings.Contains(dsn, "idle_in_transaction_session_timeout") {
+		t.Fatalf("expected unset timeouts to be left out, got: %s\n", dsn)
+	}
+}
+
+func TestTimeoutsRoundUp(t *testing.T) {
+	settings := Timeouts{Statement: 100 * time.Microsecond, Lock: 1500 * time.Microsecond}.settings()
+	if len(settings) != 2 || settings[0][1] != "1" || settings[1][1] != "2" {
+		t.Fatalf("expected sub millisecond timeouts to round up, got: %v\n", settings)
+	}
+}
@@ -0,0 +1,79 @@
+// Package tracing creates OpenTelemetry spans for godbm queries.
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"github.com/wirepair/godbm"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+// instrumentationName identifies the tracer created by this package.
+const instrumentationName = "github.com/wirepair/godbm/tracing"
+
+// Options configures the tracing hook.
+type Options struct {
+	TracerProvider trace.TracerProvider // defaults to the global provider.
+	RecordSQL      bool                 // record the sql text as db.statement instead of the statement key.
+}
+
+// Hook is a godbm.Hook which starts a span per query as a child of the caller's context.
+type Hook struct {
+	tracer    trace.Tracer
+	recordSQL bool
+}
+
+// Use creates a tracing Hook and registers it with the store.
+func Use(store *godbm.SqlStore, opts Options) *Hook {
+	provider := opts.TracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	h := &Hook{tracer: provider.Tracer(instrumentationName), recordSQL: opts.RecordSQL}
+	store.Use(h)
+	return h
+}
+
+// BeforeQuery implements godbm.Hook and starts the span.
+func (h *Hook) BeforeQuery(ctx context.Context, key, query string, args []interface{}) context.Context {
+	name := "godbm " + key
+	statement := key
+	if key == "" {
+		name = "godbm query"
+		statement = query
+	}
+	if h.recordSQL {
+		statement = query
+	}
+
+	ctx, _ = h.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.statement", statement),
+			attribute.String("godbm.key", key),
+		),
+	)
+	return ctx
+}
+
+// AfterExec implements godbm.ResultHook and records the rows affected.
+func (h *Hook) AfterExec(ctx context.Context, key string, result sql.Result) {
+	if rows, err := result.RowsAffected(); err == nil {
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("db.rows_affected", rows))
+	}
+}
+
+// AfterQuery implements godbm.Hook and ends the span.
+func (h *Hook) AfterQuery(ctx context.Context, key string, duration time.Duration, err error) {
+	span := trace.SpanFromContext(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
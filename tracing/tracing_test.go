@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"github.com/wirepair/godbm"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+	"time"
+)
+
+// rowsResult is a sql.Result reporting a fixed number of rows affected.
+type rowsResult int64
+
+func (r rowsResult) LastInsertId() (int64, error) { return 0, errors.New("not supported") }
+func (r rowsResult) RowsAffected() (int64, error) { return int64(r), nil }
+
+// newHook returns a Hook recording its spans in memory.
+func newHook(recordSQL bool) (*Hook, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	store := godbm.New("user", "pass", "db", "localhost", "disable", "")
+	return Use(store, Options{TracerProvider: provider, RecordSQL: recordSQL}), recorder
+}
+
+// attributes returns the attributes of a span as a map.
+func attributes(kvs []attribute.KeyValue) map[string]attribute.Value {
+	attrs := make(map[string]attribute.Value, len(kvs))
+	for _, kv := range kvs {
+		attrs[string(kv.Key)] = kv.Value
+	}
+	return attrs
+}
+
+func TestHookSpan(t *testing.T) {
+	hook, recorder := newHook(false)
+	ctx := hook.BeforeQuery(context.Background(), "users.update", "update users set name = $1", nil)
+	hook.AfterExec(ctx, "users.update", rowsResult(3))
+	hook.AfterQuery(ctx, "users.update", time.Millisecond, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected one span got %d\n", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "godbm users.update" {
+		t.Fatalf("unexpected span name %q\n", span.Name())
+	}
+	attrs := attributes(span.Attributes())
+	if attrs["db.system"].AsString() != "postgresql" || attrs["db.statement"].AsString() != "users.update" || attrs["godbm.key"].AsString() != "users.update" {
+		t.Fatalf("unexpected attributes %v\n", span.Attributes())
+	}
+	if attrs["db.rows_affected"].AsInt64() != 3 {
+		t.Fatalf("expected the rows affected to be recorded got %v\n", attrs["db.rows_affected"])
+	}
+	if span.Status().Code != codes.Unset {
+		t.Fatalf("expected no error status got %v\n", span.Status())
+	}
+}
+
+func TestHookAdhocSQL(t *testing.T) {
+	hook, recorder := newHook(true)
+	ctx := hook.BeforeQuery(context.Background(), "", "select 1", nil)
+	hook.AfterQuery(ctx, "", time.Millisecond, nil)
+
+	span := recorder.Ended()[0]
+	if span.Name() != "godbm query" || attributes(span.Attributes())["db.statement"].AsString() != "select 1" {
+		t.Fatalf("expected an ad-hoc query span with its sql got %q %v\n", span.Name(), span.Attributes())
+	}
+}
+
+func TestHookError(t *testing.T) {
+	hook, recorder := newHook(false)
+	ctx := hook.BeforeQuery(context.Background(), "users.get", "select * from users", nil)
+	hook.AfterQuery(ctx, "users.get", time.Millisecond, errors.New("boom"))
+
+	span := recorder.Ended()[0]
+	if span.Status().Code != codes.Error || span.Status().Description != "boom" {
+		t.Fatalf("expected an error status got %v\n", span.Status())
+	}
+	if events := span.Events(); len(events) != 1 || events[0].Name != "exception" {
+		t.Fatalf("expected the error to be recorded got %v\n", events)
+	}
+}
@@ -0,0 +1,73 @@
+package godbm
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// PreparedTransaction is a transaction prepared for two-phase commit which has not yet been
+// committed or rolled back, as listed by pg_prepared_xacts.
+type PreparedTransaction struct {
+	ID       string    // the id passed to PrepareTransaction.
+	Prepared time.Time // when it was prepared.
+	Owner    string    // the user which prepared it.
+}
+
+// PrepareTransaction prepares the transaction for two-phase commit under id, which must be
+// unique among prepared transactions. Its work survives crashes and is held, along with its
+// locks, until CommitPrepared or RollbackPrepared is called with the id from any session. The
+// Tx can't be used afterwards. Returns an error if the server's max_prepared_transactions is
+// zero, the default.
+func (tx *Tx) PrepareTransaction(id string) error {
+	return tx.PrepareTransactionContext(context.Background(), id)
+}
+
+// PrepareTransactionContext is the same as PrepareTransaction but takes a context.
+func (tx *Tx) PrepareTransactionContext(ctx context.Context, id string) error {
+	if _, err := tx.ExecContext(ctx, "PREPARE TRANSACTION "+QuoteLiteral(id)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	// the session has left the transaction, so the commit only releases the connection. lib/pq
+	// reports the idle session as an error and discards the connection, which leaves the
+	// prepared transaction in place.
+	if err := tx.Commit(); err != nil && !strings.Contains(err.Error(), "unexpected transaction status") {
+		return err
+	}
+	return nil
+}
+
+// CommitPrepared commits the transaction prepared under id. It can't run inside a transaction.
+func (store *SqlStore) CommitPrepared(ctx context.Context, id string) error {
+	_, err := store.ExecContext(ctx, "COMMIT PREPARED "+QuoteLiteral(id))
+	return err
+}
+
+// RollbackPrepared rolls back the transaction prepared under id. It can't run inside a
+// transaction.
+func (store *SqlStore) RollbackPrepared(ctx context.Context, id string) error {
+	_, err := store.ExecContext(ctx, "ROLLBACK PREPARED "+QuoteLiteral(id))
+	return err
+}
+
+// PreparedTransactions returns the in-doubt transactions prepared in the current database,
+// oldest first, so a coordinator can resolve them after a crash. Prepared transactions left
+// behind hold their locks and block vacuum.
+func (store *SqlStore) PreparedTransactions(ctx context.Context) ([]PreparedTransaction, error) {
+	rows, err := store.QueryContext(ctx, "select gid, prepared, owner from pg_prepared_xacts where database = current_database() order by prepared")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prepared []PreparedTransaction
+	for rows.Next() {
+		var p PreparedTransaction
+		if err := rows.Scan(&p.ID, &p.Prepared, &p.Owner); err != nil {
+			return nil, err
+		}
+		prepared = append(prepared, p)
+	}
+	return prepared, rows.Err()
+}
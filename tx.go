@@ -0,0 +1,259 @@
+package godbm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"strconv"
+	"sync/atomic"
+)
+
+// ErrNotReadOnly is returned when a read only transaction is requested inside a transaction
+// which can write.
+var ErrNotReadOnly = errors.New("godbm: error read only transaction requested inside a read write transaction")
+
+// TxOptions holds the database/sql transaction options along with postgres specific ones.
+type TxOptions struct {
+	sql.TxOptions
+	Deferrable bool // with serializable isolation and ReadOnly, wait for a snapshot free of serialization failures.
+}
+
+// Tx wraps a *sql.Tx with access to the store's registered statements and savepoints. A Tx
+// is not safe for concurrent use.
+type Tx struct {
+	*sql.Tx
+	store      *SqlStore
+	readOnly   bool        // the transaction was started read only
+	savepoints int         // number of savepoints created by nested WithTransaction calls
+	searchPath string      // search_path set with SetLocal, if any
+	done       atomic.Bool // set once the transaction is committed or rolled back
+	release    func()      // releases the pool class slot held by the transaction, if any
+}
+
+type txKey struct{}
+
+// withTx returns a context carrying tx. Prepared statements called through the store with the
+// returned context run inside the transaction.
+func withTx(ctx context.Context, tx *Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext returns the transaction started by WithTransaction which the context belongs
+// to, if any.
+func TxFromContext(ctx context.Context) (*Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(*Tx)
+	return tx, ok
+}
+
+// BeginTx starts a transaction with the given options, which may be nil for the defaults.
+func (store *SqlStore) BeginTx(ctx context.Context, opts *TxOptions) (*Tx, error) {
+	if !store.Connected {
+		return nil, &ConnectionError{}
+	}
+
+	ctx, release, err := store.holdClass(ctx, &statement{})
+	if err != nil {
+		return nil, err
+	}
+	tx, err := store.beginTx(ctx, opts)
+	if err != nil {
+		if release != nil {
+			release()
+		}
+		return nil, err
+	}
+	if release != nil {
+		// the transaction is rolled back without a call to Rollback when ctx is done.
+		stop := context.AfterFunc(ctx, release)
+		tx.release = func() {
+			stop()
+			release()
+		}
+	}
+	store.trackTx(ctx, tx)
+	return tx, nil
+}
+
+// beginTx starts the transaction for BeginTx.
+func (store *SqlStore) beginTx(ctx context.Context, opts *TxOptions) (*Tx, error) {
+	var sqlOpts *sql.TxOptions
+	if opts != nil {
+		sqlOpts = &opts.TxOptions
+	}
+	txn, err := store.db.BeginTx(ctx, sqlOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts != nil && opts.Deferrable {
+		if _, err := txn.ExecContext(ctx, "SET TRANSACTION DEFERRABLE"); err != nil {
+			txn.Rollback()
+			return nil, err
+		}
+	}
+	tx := &Tx{Tx: txn, store: store, readOnly: opts != nil && opts.ReadOnly}
+	if err := tx.applySessionSettings(ctx); err != nil {
+		txn.Rollback()
+		return nil, err
+	}
+	return tx, nil
+}
+
+// Commit commits the transaction.
+func (tx *Tx) Commit() error {
+	defer tx.end()
+	return tx.Tx.Commit()
+}
+
+// Rollback aborts the transaction.
+func (tx *Tx) Rollback() error {
+	defer tx.end()
+	return tx.Tx.Rollback()
+}
+
+// end marks the transaction done and releases its pool class slot.
+func (tx *Tx) end() {
+	tx.done.Store(true)
+	if tx.release != nil {
+		tx.release()
+	}
+}
+
+// WithTransaction calls fn inside a transaction, committing if fn returns nil and rolling back
+// if it returns an error or panics. The context passed to fn carries the transaction, so
+// QueryPreparedContext and ExecPreparedContext calls made with it run inside the transaction.
+// If ctx already carries a transaction, fn runs inside a savepoint of it instead, which is
+// rolled back on error without aborting the outer transaction.
+func (store *SqlStore) WithTransaction(ctx context.Context, fn func(ctx context.Context, tx *Tx) error) error {
+	return store.WithTransactionOptions(ctx, nil, fn)
+}
+
+// WithReadOnlyTx is the same as WithTransaction but the transaction is read only, so any
+// attempt to write fails. Returns ErrNotReadOnly if ctx carries a transaction which can write.
+func (store *SqlStore) WithReadOnlyTx(ctx context.Context, fn func(ctx context.Context, tx *Tx) error) error {
+	return store.WithTransactionOptions(ctx, &TxOptions{TxOptions: sql.TxOptions{ReadOnly: true}}, fn)
+}
+
+// WithTransactionOptions is the same as WithTransaction but starts the transaction with the
+// given options. When nested inside another transaction the options of the outer transaction
+// apply, except that a read only request inside a read write transaction returns ErrNotReadOnly.
+func (store *SqlStore) WithTransactionOptions(ctx context.Context, opts *TxOptions, fn func(ctx context.Context, tx *Tx) error) (err error) {
+	if tx, ok := TxFromContext(ctx); ok {
+		if opts != nil && opts.ReadOnly && !tx.readOnly {
+			return ErrNotReadOnly
+		}
+		return tx.withSavepoint(ctx, fn)
+	}
+
+	tx, err := store.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+	return fn(withTx(ctx, tx), tx)
+}
+
+// withSavepoint calls fn inside a new savepoint, releasing it on success and rolling back to
+// it on error or panic.
+func (tx *Tx) withSavepoint(ctx context.Context, fn func(ctx context.Context, tx *Tx) error) (err error) {
+	tx.savepoints++
+	name := "godbm_sp_" + strconv.Itoa(tx.savepoints)
+	if err := tx.SavepointContext(ctx, name); err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.RollbackToContext(ctx, name)
+			panic(p)
+		}
+		if err != nil {
+			if rerr := tx.RollbackToContext(ctx, name); rerr != nil {
+				err = fmt.Errorf("%w (rolling back to savepoint: %v)", err, rerr)
+			}
+			return
+		}
+		err = tx.ReleaseSavepointContext(ctx, name)
+	}()
+	return fn(ctx, tx)
+}
+
+// Savepoint creates a savepoint with the given name.
+func (tx *Tx) Savepoint(name string) error {
+	return tx.SavepointContext(context.Background(), name)
+}
+
+// SavepointContext is the same as Savepoint but takes a context.
+func (tx *Tx) SavepointContext(ctx context.Context, name string) error {
+	_, err := tx.ExecContext(ctx, "SAVEPOINT "+pq.QuoteIdentifier(name))
+	return err
+}
+
+// RollbackTo rolls back everything done since the named savepoint was created. The savepoint
+// remains and can be rolled back to again.
+func (tx *Tx) RollbackTo(name string) error {
+	return tx.RollbackToContext(context.Background(), name)
+}
+
+// RollbackToContext is the same as RollbackTo but takes a context.
+func (tx *Tx) RollbackToContext(ctx context.Context, name string) error {
+	_, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+pq.QuoteIdentifier(name))
+	tx.searchPath = "" // a search_path set since the savepoint is undone
+	return err
+}
+
+// ReleaseSavepoint destroys the named savepoint, keeping the work done since it was created.
+func (tx *Tx) ReleaseSavepoint(name string) error {
+	return tx.ReleaseSavepointContext(context.Background(), name)
+}
+
+// ReleaseSavepointContext is the same as ReleaseSavepoint but takes a context.
+func (tx *Tx) ReleaseSavepointContext(ctx context.Context, name string) error {
+	_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+pq.QuoteIdentifier(name))
+	return err
+}
+
+// QueryPrepared executes the prepared statement registered under key inside the transaction.
+func (tx *Tx) QueryPrepared(key string, data ...interface{}) (*sql.Rows, error) {
+	return tx.QueryPreparedContext(context.Background(), key, data...)
+}
+
+// QueryPreparedContext is the same as QueryPrepared but takes a context.
+func (tx *Tx) QueryPreparedContext(ctx context.Context, key string, data ...interface{}) (*sql.Rows, error) {
+	return tx.store.QueryPreparedContext(withTx(ctx, tx), key, data...)
+}
+
+// ExecPrepared executes the prepared statement registered under key inside the transaction.
+func (tx *Tx) ExecPrepared(key string, data ...interface{}) (sql.Result, error) {
+	return tx.ExecPreparedContext(context.Background(), key, data...)
+}
+
+// ExecPreparedContext is the same as ExecPrepared but takes a context.
+func (tx *Tx) ExecPreparedContext(ctx context.Context, key string, data ...interface{}) (sql.Result, error) {
+	return tx.store.ExecPreparedContext(withTx(ctx, tx), key, data...)
+}
+
+// stmtFor returns the runner to use for st, bound to the context's transaction if it carries
+// one, or taking a connection within the call's pool wait, see WithPoolWait.
+func (store *SqlStore) stmtFor(ctx context.Context, st *statement) runner {
+	if tx, ok := TxFromContext(ctx); ok {
+		return store.runnerOn(ctx, tx.Tx, st)
+	}
+	if _, ok := poolWait(ctx); ok {
+		return waitingRunner{db: store.db, query: store.comment(ctx, store.labeled(st.key, st.query))}
+	}
+	return store.runnerOn(ctx, store.db, st)
+}
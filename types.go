@@ -0,0 +1,206 @@
+package godbm
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/lib/pq/hstore"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Hstore scans an hstore column into a map and encodes a map as an hstore parameter. NULL
+// values within the hstore are nil. A NULL column scans into a nil map.
+type Hstore map[string]*string
+
+// Scan implements sql.Scanner.
+func (h *Hstore) Scan(src interface{}) error {
+	var raw hstore.Hstore
+	if err := raw.Scan(src); err != nil {
+		return err
+	}
+	if raw.Map == nil {
+		*h = nil
+		return nil
+	}
+	m := make(Hstore, len(raw.Map))
+	for k, v := range raw.Map {
+		if v.Valid {
+			s := v.String
+			m[k] = &s
+		} else {
+			m[k] = nil
+		}
+	}
+	*h = m
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (h Hstore) Value() (driver.Value, error) {
+	if h == nil {
+		return nil, nil
+	}
+	raw := hstore.Hstore{Map: make(map[string]sql.NullString, len(h))}
+	for k, v := range h {
+		if v != nil {
+			raw.Map[k] = sql.NullString{String: *v, Valid: true}
+		} else {
+			raw.Map[k] = sql.NullString{}
+		}
+	}
+	return raw.Value()
+}
+
+// UUID is a postgres uuid, scanned from its text form or 16 raw bytes and sent as text.
+type UUID [16]byte
+
+// ParseUUID parses the canonical 8-4-4-4-12 hex form of a uuid, with or without hyphens or
+// surrounding braces.
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	clean := strings.ReplaceAll(strings.Trim(s, "{}"), "-", "")
+	if len(clean) != 32 {
+		return u, fmt.Errorf("godbm: error invalid uuid %q", s)
+	}
+	if _, err := hex.Decode(u[:], []byte(clean)); err != nil {
+		return u, fmt.Errorf("godbm: error invalid uuid %q", s)
+	}
+	return u, nil
+}
+
+// String returns the canonical form of the uuid.
+func (u UUID) String() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// Scan implements sql.Scanner.
+func (u *UUID) Scan(src interface{}) (err error) {
+	switch v := src.(type) {
+	case []byte:
+		if len(v) == 16 {
+			copy(u[:], v)
+			return nil
+		}
+		*u, err = ParseUUID(string(v))
+		return err
+	case string:
+		*u, err = ParseUUID(v)
+		return err
+	}
+	return fmt.Errorf("godbm: error cannot scan %T into a UUID", src)
+}
+
+// Value implements driver.Valuer.
+func (u UUID) Value() (driver.Value, error) {
+	return u.String(), nil
+}
+
+// errIntervalMonths is returned when an interval has a month or year part, which has no fixed
+// length.
+var errIntervalMonths = errors.New("godbm: error interval with months or years cannot be converted to a duration")
+
+// Interval scans a postgres interval into a time.Duration and sends a duration as an interval.
+// Days are taken as 24 hours. Intervals with months or years return an error as their length
+// depends on the date they are added to. Only the default postgres IntervalStyle is supported.
+type Interval time.Duration
+
+// Scan implements sql.Scanner.
+func (i *Interval) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("godbm: error cannot scan %T into an Interval", src)
+	}
+	d, err := parseInterval(s)
+	if err != nil {
+		return err
+	}
+	*i = Interval(d)
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (i Interval) Value() (driver.Value, error) {
+	return strconv.FormatInt(time.Duration(i).Microseconds(), 10) + " microseconds", nil
+}
+
+// parseInterval parses postgres style interval output such as "3 days -04:05:06.5".
+func parseInterval(s string) (time.Duration, error) {
+	var d time.Duration
+	fields := strings.Fields(s)
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if strings.Contains(field, ":") {
+			t, err := parseIntervalTime(field)
+			if err != nil {
+				return 0, fmt.Errorf("godbm: error invalid interval %q", s)
+			}
+			d += t
+			continue
+		}
+
+		if i+1 >= len(fields) {
+			return 0, fmt.Errorf("godbm: error invalid interval %q", s)
+		}
+		n, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("godbm: error invalid interval %q", s)
+		}
+		i++
+		switch strings.TrimSuffix(fields[i], "s") {
+		case "day":
+			d += time.Duration(n) * 24 * time.Hour
+		case "mon", "year":
+			if n != 0 {
+				return 0, errIntervalMonths
+			}
+		default:
+			return 0, fmt.Errorf("godbm: error invalid interval %q", s)
+		}
+	}
+	return d, nil
+}
+
+// parseIntervalTime parses the [-]hh:mm:ss[.ffffff] part of an interval.
+func parseIntervalTime(s string) (time.Duration, error) {
+	neg := strings.HasPrefix(s, "-")
+	parts := strings.Split(strings.TrimLeft(s, "+-"), ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("godbm: error invalid interval time %q", s)
+	}
+	hours, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	minutes, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	seconds, err := time.ParseDuration(parts[2] + "s")
+	if err != nil {
+		return 0, err
+	}
+	d := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + seconds
+	if neg {
+		d = -d
+	}
+	return d, nil
+}
@@ -0,0 +1,78 @@
+package godbm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHstore(t *testing.T) {
+	var h Hstore
+	if err := h.Scan([]byte(`"a"=>"1", "b"=>NULL`)); err != nil {
+		t.Fatalf("error scanning hstore: %v\n", err)
+	}
+	if len(h) != 2 || h["a"] == nil || *h["a"] != "1" || h["b"] != nil {
+		t.Fatalf("unexpected hstore: %v\n", h)
+	}
+	if err := h.Scan(nil); err != nil || h != nil {
+		t.Fatalf("expected NULL to scan into a nil map: %v %v\n", h, err)
+	}
+
+	one := "1"
+	v, err := Hstore{"a": &one}.Value()
+	if err != nil || string(v.([]byte)) != `"a"=>"1"` {
+		t.Fatalf("unexpected hstore value: %s %v\n", v, err)
+	}
+}
+
+func TestUUID(t *testing.T) {
+	const canonical = "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"
+	var u UUID
+	if err := u.Scan([]byte(canonical)); err != nil || u.String() != canonical {
+		t.Fatalf("unexpected uuid: %v %v\n", u, err)
+	}
+	if braced, err := ParseUUID("{A0EEBC999C0B4EF8BB6D6BB9BD380A11}"); err != nil || braced != u {
+		t.Fatalf("unexpected uuid: %v %v\n", braced, err)
+	}
+
+	var raw UUID
+	if err := raw.Scan(u[:]); err != nil || raw != u {
+		t.Fatalf("expected raw bytes to be copied: %v %v\n", raw, err)
+	}
+	if _, err := ParseUUID("a0eebc99"); err == nil {
+		t.Fatalf("expected an error for a short uuid\n")
+	}
+	if v, _ := u.Value(); v != canonical {
+		t.Fatalf("unexpected uuid value: %v\n", v)
+	}
+}
+
+func TestInterval(t *testing.T) {
+	tests := []struct {
+		in  string
+		out time.Duration
+	}{
+		{"00:00:01.5", 1500 * time.Millisecond},
+		{"-00:00:01", -time.Second},
+		{"3 days 04:05:06", 3*24*time.Hour + 4*time.Hour + 5*time.Minute + 6*time.Second},
+		{"1 day -01:00:00", 23 * time.Hour},
+		{"-2 days", -48 * time.Hour},
+		{"0 years 0 mons 1 day", 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		var i Interval
+		if err := i.Scan([]byte(tt.in)); err != nil || time.Duration(i) != tt.out {
+			t.Fatalf("%q: expected %v got %v %v\n", tt.in, tt.out, time.Duration(i), err)
+		}
+	}
+
+	var i Interval
+	if err := i.Scan("1 mon"); err != errIntervalMonths {
+		t.Fatalf("expected errIntervalMonths got %v\n", err)
+	}
+	if err := i.Scan("1 fortnight"); err == nil {
+		t.Fatalf("expected an error for an unknown unit\n")
+	}
+	if v, _ := Interval(1500 * time.Millisecond).Value(); v != "1500000 microseconds" {
+		t.Fatalf("unexpected interval value: %v\n", v)
+	}
+}
@@ -0,0 +1,75 @@
+package godbm
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StatementError holds the reason a registered statement failed validation.
+type StatementError struct {
+	Key   string // the statement key
+	Query string // the sql registered under the key
+	Err   error  // the error returned by the server
+}
+
+func (e *StatementError) Error() string {
+	return "godbm: error statement " + e.Key + " is invalid: " + e.Err.Error()
+}
+
+func (e *StatementError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned by ValidateStatements and holds every statement which failed.
+type ValidationError struct {
+	Failures []*StatementError // failures sorted by statement key
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Failures))
+	for i, failure := range e.Failures {
+		msgs[i] = failure.Key + ": " + failure.Err.Error()
+	}
+	return "godbm: error " + strconv.Itoa(len(e.Failures)) + " statements failed validation: " + strings.Join(msgs, "; ")
+}
+
+// ValidateStatements prepares every registered statement against the live schema and reports
+// all failures at once as a *ValidationError, so a typo or a missing column is caught at
+// startup rather than when the code path is first hit.
+func (store *SqlStore) ValidateStatements(ctx context.Context) error {
+	if !store.Connected {
+		return &ConnectionError{}
+	}
+
+	store.RLock()
+	statements := make([]*statement, 0, len(store.queries))
+	for _, st := range store.queries {
+		statements = append(statements, st)
+	}
+	store.RUnlock()
+	sort.Slice(statements, func(i, j int) bool { return statements[i].key < statements[j].key })
+
+	validation := &ValidationError{}
+	for _, st := range statements {
+		query := st.query
+		if st.in != nil {
+			query = st.in.expand(query, st.params, 1)
+		}
+		stmt, err := store.db.PrepareContext(ctx, query)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			validation.Failures = append(validation.Failures, &StatementError{Key: st.key, Query: st.query, Err: err})
+			continue
+		}
+		stmt.Close()
+	}
+
+	if len(validation.Failures) > 0 {
+		return validation
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package godbm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+)
+
+// ErrStaleRow is returned by UpdateWithVersion when the row was changed or deleted since it
+// was read.
+var ErrStaleRow = errors.New("godbm: error row was modified concurrently")
+
+// UpdateWithVersion updates the row of table matching the struct v points to on whereCols,
+// typically the primary key, using optimistic locking. The update only applies if
+// versionColumn still holds the version in the struct, and increments it, in which case the
+// struct's version is incremented too. Returns ErrStaleRow if no row was updated, meaning
+// another writer got there first and the row should be read again. versionColumn must map to
+// an integer field, see UpdateStruct for how fields map to columns.
+func (store *SqlStore) UpdateWithVersion(table string, v interface{}, versionColumn string, whereCols ...string) error {
+	return store.UpdateWithVersionContext(context.Background(), table, v, versionColumn, whereCols...)
+}
+
+// UpdateWithVersionContext is the same as UpdateWithVersion but takes a context, which may
+// carry a transaction started by WithTransaction.
+func (store *SqlStore) UpdateWithVersionContext(ctx context.Context, table string, v interface{}, versionColumn string, whereCols ...string) error {
+	b, dest, version, err := updateWithVersion(table, v, versionColumn, whereCols)
+	if err != nil {
+		return err
+	}
+
+	query, args := b.Build()
+	n, err := store.execReturning(ctx, query, args, dest)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrStaleRow
+	}
+	version.SetInt(version.Int() + 1)
+	return nil
+}
+
+// updateWithVersion builds the update for UpdateWithVersion, returning the destinations for
+// the RETURNING columns and the version field.
+func updateWithVersion(table string, v interface{}, versionColumn string, whereCols []string) (*UpdateBuilder, []interface{}, reflect.Value, error) {
+	rv, err := structValue(v)
+	if err != nil {
+		return nil, nil, reflect.Value{}, err
+	}
+
+	var version reflect.Value
+	for _, f := range fieldsOf(rv.Type()) {
+		if f.column == versionColumn {
+			version = rv.FieldByIndex(f.index)
+		}
+	}
+	switch {
+	case !version.IsValid():
+		return nil, nil, version, errors.New("godbm: error version column " + versionColumn + " is not a field of the struct")
+	case version.Kind() < reflect.Int || version.Kind() > reflect.Int64:
+		return nil, nil, version, errors.New("godbm: error version column " + versionColumn + " must be an integer")
+	case len(whereCols) == 0:
+		return nil, nil, version, errors.New("godbm: error UpdateWithVersion requires where columns")
+	}
+
+	b, dest, err := updateStruct(table, v, append(append([]string(nil), whereCols...), versionColumn))
+	if err != nil {
+		return nil, nil, version, err
+	}
+	b.Set(versionColumn, version.Int()+1)
+	return b, dest, version, nil
+}
@@ -0,0 +1,36 @@
+package godbm
+
+import (
+	"reflect"
+	"testing"
+)
+
+type versionedDoc struct {
+	ID      int64  `db:"id"`
+	Body    string `db:"body"`
+	Version int32  `db:"version"`
+}
+
+func TestUpdateWithVersion(t *testing.T) {
+	doc := &versionedDoc{ID: 1, Body: "hello", Version: 3}
+	b, _, version, err := updateWithVersion("docs", doc, "version", []string{"id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query, args := b.Build()
+	want := `update "docs" set "body" = $1, "version" = $2 where ("id" = $3) and ("version" = $4)`
+	if query != want || !reflect.DeepEqual(args, []interface{}{"hello", int64(4), int64(1), int32(3)}) {
+		t.Fatalf("unexpected update:\n%s\n%s\n%v\n", query, want, args)
+	}
+	if version.Int() != 3 {
+		t.Fatalf("expected the version to be left alone until the update succeeds\n")
+	}
+
+	if _, _, _, err := updateWithVersion("docs", doc, "body", []string{"id"}); err == nil {
+		t.Fatalf("expected an error for a non integer version column\n")
+	}
+	if _, _, _, err := updateWithVersion("docs", doc, "rev", []string{"id"}); err == nil {
+		t.Fatalf("expected an error for an unknown version column\n")
+	}
+}
@@ -0,0 +1,46 @@
+package godbm
+
+import (
+	"context"
+	"log/slog"
+)
+
+// PrepareAddVersion registers query as version of key, under the key "<key>@<version>", so
+// old and new code in a rolling deploy can each call the sql it expects. Calls naming the
+// versioned key always run that version, calls naming key itself run the default version once
+// one is set with SetDefaultVersion.
+func (store *SqlStore) PrepareAddVersion(key, version, query string, opts ...StmtOption) error {
+	return store.PrepareAddWithOptions(versionedKey(key, version), query, opts...)
+}
+
+// SetDefaultVersion points key at a version registered with PrepareAddVersion. The switch is
+// atomic, calls already running finish on the version they started with. A default version
+// takes precedence over a statement registered under key itself, and removing the version it
+// points at leaves key unknown until another default is set.
+func (store *SqlStore) SetDefaultVersion(key, version string) error {
+	versioned := versionedKey(key, version)
+	defer store.Unlock()
+
+	store.Lock()
+	if _, found := store.queries[versioned]; !found {
+		return &UnknownStmtError{StmtKey: versioned}
+	}
+	if store.versions == nil {
+		store.versions = make(map[string]string)
+	}
+	store.versions[key] = version
+	store.logEvent(context.Background(), "godbm: default version", nil, slog.String("key", key), slog.String("version", version))
+	return nil
+}
+
+// DefaultVersion returns the default version of key, or an empty string if none is set.
+func (store *SqlStore) DefaultVersion(key string) string {
+	store.RLock()
+	defer store.RUnlock()
+	return store.versions[key]
+}
+
+// versionedKey returns the key a version of key is registered under.
+func versionedKey(key, version string) string {
+	return key + "@" + version
+}
@@ -0,0 +1,33 @@
+package godbm
+
+import "testing"
+
+func TestSetDefaultVersion(t *testing.T) {
+	dbm := New(username, password, dbname, host, "disable", "")
+	dbm.queries = map[string]*statement{
+		"get":    {key: "get"},
+		"get@v1": {key: "get@v1"},
+		"get@v2": {key: "get@v2"},
+	}
+
+	if st, err := dbm.lookup("get"); err != nil || st.key != "get" {
+		t.Fatalf("expected get without a default version: %v\n", err)
+	}
+	if err := dbm.SetDefaultVersion("get", "v3"); err == nil {
+		t.Fatalf("expected an error for an unregistered version\n")
+	}
+	if err := dbm.SetDefaultVersion("get", "v2"); err != nil {
+		t.Fatalf("error setting default version: %v\n", err)
+	}
+	if st, err := dbm.lookup("get"); err != nil || st.key != "get@v2" || dbm.DefaultVersion("get") != "v2" {
+		t.Fatalf("expected get to run v2: %v\n", err)
+	}
+	if st, err := dbm.lookup("get@v1"); err != nil || st.key != "get@v1" {
+		t.Fatalf("expected get@v1 to run v1: %v\n", err)
+	}
+
+	delete(dbm.queries, "get@v2")
+	if dbm.HasStatement("get") {
+		t.Fatalf("expected get to be unknown once its default version is removed\n")
+	}
+}
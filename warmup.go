@@ -0,0 +1,89 @@
+package godbm
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// SetWarmUp makes Connect open conns connections and prepare every registered statement on each
+// of them before returning, so the first burst of traffic doesn't pay for connecting and
+// preparing. Statements from the configured StatementDirs are the ones registered at that
+// point, call WarmUp after PrepareAdd to warm others. Must be called before Connect.
+func (store *SqlStore) SetWarmUp(conns int) {
+	store.warmConns = conns
+}
+
+// WarmUp opens conns connections to the primary, or as many as MaxOpenConns allows, and
+// prepares every registered statement on each of them, then returns them to the pool.
+// MaxIdleConns is raised to conns if lower, otherwise the pool would close the connections
+// straight away. Connections may still be closed later by ConnMaxIdleTime or ConnMaxLifetime.
+func (store *SqlStore) WarmUp(ctx context.Context, conns int) error {
+	if conns <= 0 {
+		return nil
+	}
+	if max := store.pool.MaxOpenConns; max > 0 && conns > max {
+		conns = max
+	}
+	if conns > store.idleConns() {
+		store.db.SetMaxIdleConns(conns)
+	}
+
+	store.RLock()
+	stmts := make([]*sql.Stmt, 0, len(store.queries))
+	for _, st := range store.queries {
+		if st.stmt != nil {
+			stmts = append(stmts, st.stmt)
+		}
+	}
+	store.RUnlock()
+
+	// every transaction is held until all are open so each is on a connection of its own.
+	txns := make([]*sql.Tx, 0, conns)
+	defer func() {
+		for _, txn := range txns {
+			txn.Rollback()
+		}
+	}()
+	for i := 0; i < conns; i++ {
+		txn, err := store.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		txns = append(txns, txn)
+	}
+
+	var wg sync.WaitGroup
+	for _, txn := range txns {
+		wg.Add(1)
+		go func(txn *sql.Tx) {
+			defer wg.Done()
+			warmConn(ctx, txn, stmts)
+		}(txn)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// warmConn prepares stmts on the connection of txn. A statement prepared through a transaction
+// stays prepared on its connection for later calls which get that connection, one which fails
+// to prepare is simply prepared again by the next call to use it.
+func warmConn(ctx context.Context, txn *sql.Tx, stmts []*sql.Stmt) {
+	for _, stmt := range stmts {
+		txn.StmtContext(ctx, stmt).Close()
+	}
+}
+
+// warmUp warms the connections set with SetWarmUp during Connect.
+func (store *SqlStore) warmUp() error {
+	if store.warmConns <= 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	err := store.WarmUp(ctx, store.warmConns)
+	store.logEvent(ctx, "godbm: warm up", err, slog.Int("conns", store.warmConns))
+	return err
+}